@@ -7,9 +7,11 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gurkengewuerz/GitCodeJudge/internal/api"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/language"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/scoreboard"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -71,12 +73,20 @@ func serve(cmd *cobra.Command, args []string) {
 
 	// Initialize judge pool
 	scoreboardManager := scoreboard.NewScoreboardManager(db.DB)
-	docker, err := judge.NewDockerExecutor(cfg.DockerNetwork, cfg.DockerTimeout)
-	if err != nil {
-		log.WithError(err).Fatal("Failed to initialize docker executor")
+	executor, reloadLanguages := newExecutor(ctx, cfg)
+	defer executor.Close()
+	pool := judge.NewPool(executor, scoreboardManager, judge.PoolConfig{
+		MaxWorkers:        cfg.MaxParallelJudges,
+		QueueSize:         cfg.QueueSize,
+		SupersedeQueued:   cfg.SupersedeQueued,
+		CancelSuperseded:  cfg.CancelSuperseded,
+		MaxRunningPerUser: cfg.MaxRunningPerUser,
+		DeadlineWindow:    time.Duration(cfg.DeadlineWindow) * time.Hour,
+		WorkerTimeout:     time.Duration(cfg.WorkerTimeout) * time.Second,
+	})
+	if err := pool.Recover(); err != nil {
+		log.WithError(err).Error("Failed to recover queued submissions")
 	}
-	executor := judge.NewExecutor(docker, cfg.TestPath)
-	pool := judge.NewPool(executor, scoreboardManager, cfg.MaxParallelJudges)
 
 	// Setup router
 	router := api.SetupRouter(cfg, pool, scoreboardManager)
@@ -88,6 +98,8 @@ func serve(cmd *cobra.Command, args []string) {
 		}
 	}()
 
+	reloadOnSIGHUP(reloadLanguages)
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -96,7 +108,64 @@ func serve(cmd *cobra.Command, args []string) {
 	log.Info("Shutting down server...")
 }
 
+// newExecutor creates the configured runner and an executor for it. Pulling images can take a while, they are
+// prepared in the background and judges wait for the images they need. The returned function reloads the languages.
+func newExecutor(ctx context.Context, cfg *config.Config) (*judge.Executor, func()) {
+	runner, err := judge.NewRunner(cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize runner")
+	}
+	languages, err := language.Load(cfg.LanguagesFile)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load languages")
+	}
+	executor := judge.NewExecutor(runner, cfg.TestPath, languages)
+
+	docker, _ := runner.(*judge.DockerExecutor)
+	if docker != nil {
+		go func() {
+			images := languages.Images(cfg.DockerImage)
+			if err := docker.Images().Sync(ctx, images); err != nil {
+				log.WithError(err).Error("Failed to prepare images")
+			}
+			docker.WarmUp(images)
+		}()
+	}
+
+	reload := func() {
+		languages, err := language.Load(cfg.LanguagesFile)
+		if err != nil {
+			log.WithError(err).Error("Failed to reload languages")
+			return
+		}
+		if docker != nil {
+			images := languages.Images(cfg.DockerImage)
+			if err := docker.Images().Sync(ctx, images); err != nil {
+				log.WithError(err).Error("Failed to prepare images")
+			}
+			docker.WarmUp(images)
+		}
+		executor.SetLanguages(languages)
+		log.Info("Reloaded languages")
+	}
+	return executor, reload
+}
+
+// reloadOnSIGHUP reloads the languages and prepares their images on SIGHUP
+func reloadOnSIGHUP(reload func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			reload()
+		}
+	}()
+}
+
 func main() {
+	// Processes of the process runner are set up by the judge itself
+	judge.InitSandbox()
+
 	if err := rootCmd.Execute(); err != nil {
 		log.WithError(err).Fatal("Failed to execute root command")
 		os.Exit(1)
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var (
+	rejudgeCmd = &cobra.Command{
+		Use:   "rejudge",
+		Short: "Judge submissions again",
+		Long: `Judge a single commit, every recorded submission of a workshop/task or the latest commit of every
+repository of an organization again. The submissions are enqueued by the running server through its admin API.`,
+		Run: runRejudge,
+	}
+
+	// Command flags
+	rejudgeServer string
+	rejudgeToken  string
+	rejudgeCommit string
+	rejudgeTask   string
+	rejudgeAll    bool
+	rejudgeOrg    string
+)
+
+func init() {
+	rootCmd.AddCommand(rejudgeCmd)
+
+	rejudgeCmd.Flags().StringVar(&rejudgeServer, "server", "http://localhost:3000", "URL of the GitCodeJudge server")
+	rejudgeCmd.Flags().StringVar(&rejudgeToken, "token", os.Getenv("ADMIN_TOKEN"), "Admin token of the server, defaults to $ADMIN_TOKEN")
+	rejudgeCmd.Flags().StringVar(&rejudgeCommit, "commit", "", "Commit to judge again")
+	rejudgeCmd.Flags().StringVar(&rejudgeTask, "task", "", "Workshop task to judge again, e.g. workshop1/pascal_triangle")
+	rejudgeCmd.Flags().BoolVar(&rejudgeAll, "all", false, "Judge the latest commit of every repository of --org again")
+	rejudgeCmd.Flags().StringVar(&rejudgeOrg, "org", "", "Gitea organization of the repositories for --all")
+
+	rejudgeCmd.MarkFlagsMutuallyExclusive("commit", "task", "all")
+	rejudgeCmd.MarkFlagsOneRequired("commit", "task", "all")
+}
+
+func runRejudge(cmd *cobra.Command, args []string) {
+	req := models.RejudgeRequest{
+		Commit: rejudgeCommit,
+		All:    rejudgeAll,
+		Org:    rejudgeOrg,
+	}
+	if rejudgeTask != "" {
+		workshop, task, found := strings.Cut(rejudgeTask, "/")
+		if !found {
+			log.Fatal("Task must be in the format workshop/task")
+		}
+		req.Workshop, req.Task = workshop, task
+	}
+
+	if err := req.Validate(); err != nil {
+		log.WithError(err).Fatal("Invalid rejudge request")
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to encode rejudge request")
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(rejudgeServer, "/")+"/admin/rejudge", bytes.NewReader(body))
+	if err != nil {
+		log.WithError(err).Fatal("Failed to create request")
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+rejudgeToken)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to send rejudge request")
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status      string `json:"status"`
+		Error       string `json:"error"`
+		Submissions int    `json:"submissions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		log.WithError(err).WithField("StatusCode", resp.StatusCode).Fatal("Failed to decode response")
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.WithFields(log.Fields{
+			"StatusCode":  resp.StatusCode,
+			"Submissions": result.Submissions,
+		}).Fatal(fmt.Sprintf("Rejudge failed: %s", result.Error))
+	}
+
+	log.WithField("Submissions", result.Submissions).Info("Rejudge started")
+}
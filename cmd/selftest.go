@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var selfTestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Verify the security profile of the judge sandbox",
+	Long: `Start a judge container or process with the configured limits and security profile and verify that the
+profile is in force, e.g. that the root filesystem is read-only and all capabilities are dropped.`,
+	Run: runSelfTest,
+}
+
+func init() {
+	rootCmd.AddCommand(selfTestCmd)
+}
+
+func runSelfTest(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load config")
+	}
+
+	runner, err := judge.NewRunner(cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize runner")
+	}
+
+	checks, err := runner.SelfTest(context.Background())
+	if err != nil {
+		log.WithError(err).Fatal("Self-test failed")
+	}
+
+	failed := 0
+	for _, check := range checks {
+		fields := log.Fields{
+			"Expected": check.Expected,
+			"Actual":   check.Actual,
+		}
+		if check.Passed {
+			log.WithFields(fields).Info("PASS " + check.Name)
+		} else {
+			log.WithFields(fields).Error("FAIL " + check.Name)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.WithField("Failed", failed).Error("Security profile is not in force")
+		os.Exit(1)
+	}
+	log.Info("Security profile is in force")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var (
+	workerCmd = &cobra.Command{
+		Use:   "worker",
+		Short: "Judge submissions for a remote server",
+		Long: `Connect to a GitCodeJudge server and judge its queued submissions with the local runner. The worker
+needs the same configuration as the server, i.e. the Gitea credentials, the test cases and the runner settings.`,
+		Run: runWorker,
+	}
+
+	// Command flags
+	workerServer      string
+	workerToken       string
+	workerName        string
+	workerConcurrency int
+)
+
+func init() {
+	rootCmd.AddCommand(workerCmd)
+
+	hostname, _ := os.Hostname()
+	workerCmd.Flags().StringVar(&workerServer, "server", "http://localhost:3000", "URL of the GitCodeJudge server")
+	workerCmd.Flags().StringVar(&workerToken, "token", os.Getenv("WORKER_TOKEN"), "Worker token of the server, defaults to $WORKER_TOKEN")
+	workerCmd.Flags().StringVar(&workerName, "name", hostname, "Unique name of the worker, defaults to the hostname")
+	workerCmd.Flags().IntVar(&workerConcurrency, "concurrency", 1, "Number of submissions judged in parallel")
+}
+
+func runWorker(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.WithError(err).Fatal("Failed to load config")
+	}
+
+	log.SetLevel(log.Level(cfg.LogLevel))
+
+	if workerName == "" {
+		log.Fatal("Worker name must not be empty")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	executor, reloadLanguages := newExecutor(ctx, cfg)
+	defer executor.Close()
+	reloadOnSIGHUP(reloadLanguages)
+
+	// Stop claiming jobs on interrupt, running jobs are finished
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		log.Info("Shutting down worker, finishing running jobs...")
+		cancel()
+	}()
+
+	log.WithFields(log.Fields{
+		"Server":      workerServer,
+		"Name":        workerName,
+		"Concurrency": workerConcurrency,
+	}).Info("Worker started")
+
+	client := judge.NewWorkerClient(workerServer, workerToken, workerName)
+	judge.NewWorker(client, executor, workerConcurrency).Run(ctx)
+}
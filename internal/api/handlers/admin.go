@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// HandleRejudge enqueues submissions to be judged again, e.g. after an expected output was fixed
+func HandleRejudge(pool *judge.Pool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var req models.RejudgeRequest
+		if err := json.Unmarshal(c.Body(), &req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid rejudge request",
+			})
+		}
+
+		if err := req.Validate(); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		var count int
+		var err error
+		switch {
+		case req.Commit != "":
+			count, err = pool.RejudgeCommit(req.Commit)
+		case req.All:
+			count, err = pool.RejudgeHeads(req.Org)
+		default:
+			count, err = pool.RejudgeTask(req.Workshop, req.Task)
+		}
+
+		fields := log.Fields{
+			"Commit":      req.Commit,
+			"Workshop":    req.Workshop,
+			"Task":        req.Task,
+			"Org":         req.Org,
+			"Submissions": count,
+		}
+
+		if err != nil {
+			switch {
+			case errors.Is(err, judge.ErrUnknownCommit):
+				return c.Status(404).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			case errors.Is(err, judge.ErrQueueFull):
+				log.WithFields(fields).WithError(err).Warn("Rejudge stopped, queue is full")
+				return c.Status(503).JSON(fiber.Map{
+					"error":       "Judge queue is full",
+					"submissions": count,
+				})
+			}
+			log.WithFields(fields).WithError(err).Error("Failed to rejudge")
+			return c.Status(500).JSON(fiber.Map{
+				"error":       "Internal server error",
+				"submissions": count,
+			})
+		}
+
+		log.WithFields(fields).Info("Rejudge started")
+		return c.JSON(fiber.Map{
+			"status":      "rejudge started",
+			"submissions": count,
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gofiber/fiber/v3"
 	appConfig "github.com/gurkengewuerz/GitCodeJudge/internal/config"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	"github.com/johnfercher/maroto/v2"
 	"github.com/johnfercher/maroto/v2/pkg/components/col"
 	"github.com/johnfercher/maroto/v2/pkg/components/line"
@@ -168,7 +169,7 @@ func generateTaskPDF(c fiber.Ctx, appCfg *appConfig.Config, configPath string) e
 	}
 
 	// Add content
-	if err := addTaskContent(m, workshopTask); err != nil {
+	if err := addTaskContent(m, workshopTask, judge.DefaultLimits(appCfg)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString("Error adding content")
 	}
 
@@ -194,7 +195,7 @@ func addPDFFooter(m core.Maroto, appCfg *appConfig.Config) error {
 	))
 }
 
-func addTaskContent(m core.Maroto, task *judge.WorkshopTask) error {
+func addTaskContent(m core.Maroto, task *judge.WorkshopTask, defaultLimits models.Limits) error {
 	// Add title
 	m.AddRows(
 		text.NewRow(10, task.Config.Name, props.Text{
@@ -267,14 +268,52 @@ func addTaskContent(m core.Maroto, task *judge.WorkshopTask) error {
 		}))
 	}
 
-	// Example header
-	m.AddRow(7, text.NewCol(12, "Examples", props.Text{
+	// Add resource limits
+	m.AddRow(10, text.NewCol(12, "Limits:", props.Text{
 		Top:   2,
 		Size:  12,
 		Style: fontstyle.Bold,
 		Align: align.Left,
 	}))
 
+	for _, limit := range formatLimits(defaultLimits.Merge(task.Config.Limits)) {
+		m.AddRow(5, text.NewCol(12, limit, props.Text{
+			Size:  9,
+			Align: align.Left,
+		}))
+	}
+
+	// Unit test tasks list their visible tests instead of examples
+	if task.Config.Type == models.TaskTypeUnit && task.Config.Unit != nil {
+		m.AddRow(7, text.NewCol(12, "Tests", props.Text{
+			Top:   2,
+			Size:  12,
+			Style: fontstyle.Bold,
+			Align: align.Left,
+		}))
+
+		for _, test := range task.Config.Unit.Tests {
+			if test.Hidden {
+				continue
+			}
+			m.AddRow(5, text.NewCol(12, test.Name, props.Text{
+				Family: "Courier",
+				Size:   9,
+				Align:  align.Left,
+			}))
+		}
+	}
+
+	// Example header
+	if len(task.Config.Cases) > 0 {
+		m.AddRow(7, text.NewCol(12, "Examples", props.Text{
+			Top:   2,
+			Size:  12,
+			Style: fontstyle.Bold,
+			Align: align.Left,
+		}))
+	}
+
 	for i, cases := range task.Config.Cases {
 		// Test case header
 		m.AddRow(7, text.NewCol(12, fmt.Sprintf("Example %d:", i+1), props.Text{
@@ -284,8 +323,31 @@ func addTaskContent(m core.Maroto, task *judge.WorkshopTask) error {
 			Align: align.Left,
 		}))
 
-		// Input section
-		m.AddRow(7, text.NewCol(12, "Input:", props.Text{
+		if cases.Limits != nil {
+			limits := formatLimits(models.Limits{}.Merge(cases.Limits))
+			m.AddRow(5, text.NewCol(12, "Limits: "+strings.Join(limits, ", "), props.Text{
+				Size:  9,
+				Style: fontstyle.Italic,
+				Align: align.Left,
+			}))
+		}
+
+		// Input section, SQL tasks name the dataset the query runs against
+		inputLabel := "Input:"
+		inputLines := strings.Split(strings.TrimSpace(cases.Input), "\n")
+		if task.Config.Type == models.TaskTypeSQL {
+			inputLabel = "Dataset:"
+			dataset := cases.Dataset
+			if dataset == "" && task.Config.SQL != nil {
+				dataset = task.Config.SQL.Dataset
+			}
+			if dataset != "" && strings.TrimSpace(cases.Input) == "" {
+				inputLines = []string{dataset}
+			} else if dataset != "" {
+				inputLines = append([]string{dataset}, inputLines...)
+			}
+		}
+		m.AddRow(7, text.NewCol(12, inputLabel, props.Text{
 			Top:   1,
 			Size:  10,
 			Style: fontstyle.Bold,
@@ -293,7 +355,6 @@ func addTaskContent(m core.Maroto, task *judge.WorkshopTask) error {
 		}))
 
 		// Format input with monospace font
-		inputLines := strings.Split(strings.TrimSpace(cases.Input), "\n")
 		for _, s := range inputLines {
 			m.AddRow(5, text.NewCol(12, s, props.Text{
 				Family: "Courier",
@@ -328,6 +389,33 @@ func addTaskContent(m core.Maroto, task *judge.WorkshopTask) error {
 	return nil
 }
 
+// formatLimits returns a human-readable line for every limit that is set
+func formatLimits(limits models.Limits) []string {
+	lines := make([]string, 0)
+	if limits.WallTime > 0 {
+		lines = append(lines, fmt.Sprintf("Time limit: %gs", limits.WallTime))
+	}
+	if limits.CPUTime > 0 {
+		lines = append(lines, fmt.Sprintf("CPU time limit: %gs", limits.CPUTime))
+	}
+	if limits.Memory > 0 {
+		lines = append(lines, fmt.Sprintf("Memory limit: %d MB", limits.Memory))
+	}
+	if limits.CPUs > 0 {
+		lines = append(lines, fmt.Sprintf("CPUs: %g", limits.CPUs))
+	}
+	if limits.Pids > 0 {
+		lines = append(lines, fmt.Sprintf("Processes: %d", limits.Pids))
+	}
+	if limits.Output > 0 {
+		lines = append(lines, fmt.Sprintf("Output limit: %d KB", limits.Output))
+	}
+	if limits.Stderr > 0 {
+		lines = append(lines, fmt.Sprintf("Error output limit: %d KB", limits.Stderr))
+	}
+	return lines
+}
+
 func generateAndSendPDF(c fiber.Ctx, m core.Maroto) error {
 	// Generate PDF
 	document, err := m.Generate()
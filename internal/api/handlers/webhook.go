@@ -2,12 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/gitea"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	log "github.com/sirupsen/logrus"
+	"strings"
 )
 
 func HandleWebhook(cfg *config.Config, pool *judge.Pool) fiber.Handler {
@@ -20,17 +22,38 @@ func HandleWebhook(cfg *config.Config, pool *judge.Pool) fiber.Handler {
 			})
 		}
 
+		// A deleted branch has no commit to judge
+		if strings.Trim(pushEvent.After, "0") == "" {
+			return c.JSON(fiber.Map{
+				"status": "nothing to judge",
+			})
+		}
+
 		// Create submission
 		submission := models.Submission{
-			RepoName:   pushEvent.Repository.FullName,
-			CommitID:   pushEvent.After,
-			BranchName: pushEvent.Ref,
-			CloneURL:   pushEvent.Repository.CloneURL,
-			GitClient:  gitea.NewGiteaClient(cfg.GiteaURL, cfg.GiteaToken),
+			RepoName:       pushEvent.Repository.FullName,
+			CommitID:       pushEvent.After,
+			BeforeCommitID: pushEvent.Before,
+			BranchName:     pushEvent.Ref,
+			CloneURL:       pushEvent.Repository.CloneURL,
+			ChangedFiles:   pushEvent.ChangedFiles(),
+			CommitMessages: pushEvent.CommitMessages(),
+			GitClient:      gitea.NewGiteaClient(cfg.GiteaURL, cfg.GiteaToken),
 		}
 
 		// Submit to judge pool
-		pool.Submit(submission)
+		if err := pool.Submit(submission); err != nil {
+			if errors.Is(err, judge.ErrQueueFull) {
+				log.WithError(err).Warn("Rejected submission")
+				return c.Status(503).JSON(fiber.Map{
+					"error": "Judge queue is full",
+				})
+			}
+			log.WithError(err).Error("Failed to submit submission")
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Internal server error",
+			})
+		}
 
 		return c.JSON(fiber.Map{
 			"status": "submission accepted",
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v3"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// claimWait is the time a claim waits for a job before the worker has to ask again
+const claimWait = 20 * time.Second
+
+// RequireWorkerName rejects worker requests without the name of the worker
+func RequireWorkerName(c fiber.Ctx) error {
+	if c.Get(models.WorkerHeader) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Missing worker name",
+		})
+	}
+	return c.Next()
+}
+
+// HandleClaim hands the next job to a remote worker. It waits up to claimWait for a job and responds with
+// 204 No Content if there was none.
+func HandleClaim(pool *judge.Pool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var claim models.WorkerClaim
+		if err := json.Unmarshal(c.Body(), &claim); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid claim",
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), claimWait)
+		defer cancel()
+
+		job := pool.Claim(ctx, c.Get(models.WorkerHeader), c.IP(), claim.Capacity)
+		if job == nil {
+			return c.SendStatus(204)
+		}
+
+		return c.JSON(models.WorkerAssignment{
+			Job:               *job,
+			HeartbeatInterval: int(pool.HeartbeatInterval().Seconds()),
+		})
+	}
+}
+
+// HandleHeartbeat keeps a job of a remote worker alive. It responds with 410 Gone if the worker should stop the job.
+func HandleHeartbeat(pool *judge.Pool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if err := pool.Heartbeat(c.Get(models.WorkerHeader), c.Params("id")); err != nil {
+			return workerError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"status": "ok",
+		})
+	}
+}
+
+// HandleJobResult stores the result of a job judged by a remote worker
+func HandleJobResult(pool *judge.Pool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		var result models.WorkerResult
+		if err := json.Unmarshal(c.Body(), &result); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid result",
+			})
+		}
+
+		var execErr error
+		if result.Error != "" {
+			execErr = errors.New(result.Error)
+		}
+
+		if err := pool.Complete(c.Get(models.WorkerHeader), c.Params("id"), result.Result, execErr); err != nil {
+			return workerError(c, err)
+		}
+		return c.JSON(fiber.Map{
+			"status": "ok",
+		})
+	}
+}
+
+// HandleWorkers lists the in-process and remote workers of the pool and the warm containers of the server
+func HandleWorkers(pool *judge.Pool) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"queued":    pool.QueueLength(),
+			"workers":   pool.Workers(),
+			"warm_pool": pool.WarmPoolStats(),
+		})
+	}
+}
+
+func workerError(c fiber.Ctx, err error) error {
+	fields := log.Fields{
+		"Worker": c.Get(models.WorkerHeader),
+		"Job":    c.Params("id"),
+	}
+
+	switch {
+	case errors.Is(err, judge.ErrJobNotAssigned):
+		log.WithFields(fields).WithError(err).Warn("Worker request for unassigned job")
+		return c.Status(409).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, judge.ErrJobCancelled):
+		return c.Status(410).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	log.WithFields(fields).WithError(err).Error("Failed to handle worker request")
+	return c.Status(500).JSON(fiber.Map{
+		"error": "Internal server error",
+	})
+}
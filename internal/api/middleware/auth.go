@@ -3,9 +3,12 @@ package middleware
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
 	"github.com/gofiber/fiber/v3"
 	log "github.com/sirupsen/logrus"
+	"strings"
 )
 
 func ValidateGiteaWebhook(secret string) fiber.Handler {
@@ -31,3 +34,30 @@ func ValidateGiteaWebhook(secret string) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// RequireAdminToken only allows requests which send the admin token as bearer token
+func RequireAdminToken(token string) fiber.Handler {
+	return requireBearerToken(token, "admin")
+}
+
+// RequireWorkerToken only allows requests which send the worker token as bearer token
+func RequireWorkerToken(token string) fiber.Handler {
+	return requireBearerToken(token, "worker")
+}
+
+func requireBearerToken(token string, kind string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		provided, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			log.WithFields(log.Fields{
+				"ip":    c.IP(),
+				"token": kind,
+			}).Warn("Invalid token")
+			return c.Status(401).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid %s token", kind),
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -46,6 +46,20 @@ func SetupRouter(cfg *config.Config, pool *judge.Pool, scoreboardManager *scoreb
 	// Webhook route with authentication
 	app.Post("/webhook", middleware.ValidateGiteaWebhook(cfg.GiteaWebhookSecret), handlers.HandleWebhook(cfg, pool))
 
+	// Admin routes, only available if an admin token is configured
+	if cfg.AdminToken != "" {
+		app.Post("/admin/rejudge", middleware.RequireAdminToken(cfg.AdminToken), handlers.HandleRejudge(pool))
+		app.Get("/admin/workers", middleware.RequireAdminToken(cfg.AdminToken), handlers.HandleWorkers(pool))
+	}
+
+	// Remote worker routes, only available if a worker token is configured
+	if cfg.WorkerToken != "" {
+		worker := app.Group("/worker", middleware.RequireWorkerToken(cfg.WorkerToken), handlers.RequireWorkerName)
+		worker.Post("/jobs/claim", handlers.HandleClaim(pool))
+		worker.Post("/jobs/:id/heartbeat", handlers.HandleHeartbeat(pool))
+		worker.Post("/jobs/:id/result", handlers.HandleJobResult(pool))
+	}
+
 	// PDF for each problem
 	app.Get("/pdf", handlers.HandlePDF(cfg))
 
@@ -9,8 +9,16 @@ type Config struct {
 	ServerAddress     string `envconfig:"SERVER_ADDRESS" default:":3000"`
 	LogLevel          int    `envconfig:"LOG_LEVEL" default:"4"`
 	MaxParallelJudges int    `envconfig:"MAX_PARALLEL_JUDGES" default:"5"`
+	QueueSize         int    `envconfig:"QUEUE_SIZE" default:"1000"`
+	SupersedeQueued   bool   `envconfig:"SUPERSEDE_QUEUED" default:"false"`
+	CancelSuperseded  bool   `envconfig:"CANCEL_SUPERSEDED" default:"false"`
+	MaxRunningPerUser int    `envconfig:"MAX_RUNNING_PER_USER" default:"0"`
+	DeadlineWindow    int    `envconfig:"DEADLINE_PRIORITY_WINDOW" default:"24"`
 	TestPath          string `envconfig:"TESTS_PATH" default:"test_cases"`
 	BaseURL           string `envconfig:"BASE_URL" default:"http://localhost:3000"`
+	AdminToken        string `envconfig:"ADMIN_TOKEN" default:""`
+	WorkerToken       string `envconfig:"WORKER_TOKEN" default:""`
+	WorkerTimeout     int    `envconfig:"WORKER_TIMEOUT" default:"60"`
 
 	// Database
 	DatabasePath string `envconfig:"DB_PATH" default:"database/"`
@@ -26,9 +34,34 @@ type Config struct {
 	GiteaWebhookSecret string `envconfig:"GITEA_WEBHOOK_SECRET" required:"true"`
 
 	// Docker configuration
-	DockerImage   string `envconfig:"DOCKER_IMAGE" default:"ghcr.io/gurkengewuerz/gitcodejudge-judge:latest"`
-	DockerNetwork string `envconfig:"DOCKER_NETWORK" default:"none"`
-	DockerTimeout int    `envconfig:"DOCKER_TIMEOUT" default:"30"`
+	DockerImage     string `envconfig:"DOCKER_IMAGE" default:"ghcr.io/gurkengewuerz/gitcodejudge-judge:latest"`
+	DockerNetwork   string `envconfig:"DOCKER_NETWORK" default:"none"`
+	DockerTimeout   int    `envconfig:"DOCKER_TIMEOUT" default:"30"`
+	DockerWorkspace string `envconfig:"DOCKER_WORKSPACE" default:"mount"` // mount or copy
+	DockerWarmPool  int    `envconfig:"DOCKER_WARM_POOL" default:"0"`     // Idle containers per image, requires copy
+	LanguagesFile   string `envconfig:"LANGUAGES_FILE" default:""`        // Builtin languages are used if empty
+
+	// Default resource limits, can be overridden per task and per case in config.yaml
+	DockerMemory      int64   `envconfig:"DOCKER_MEMORY" default:"256"`
+	DockerCPUs        float64 `envconfig:"DOCKER_CPUS" default:"0.5"`
+	DockerPids        int64   `envconfig:"DOCKER_PIDS" default:"128"`
+	DockerCPUTime     float64 `envconfig:"DOCKER_CPU_TIME" default:"0"`
+	DockerOutputLimit int64   `envconfig:"DOCKER_OUTPUT_LIMIT" default:"1024"`
+	DockerStderrLimit int64   `envconfig:"DOCKER_STDERR_LIMIT" default:"64"`
+
+	// Security profile of judge containers, can be overridden per task in config.yaml
+	SandboxUser            string   `envconfig:"SANDBOX_USER" default:"65534:65534"`
+	SandboxReadOnlyRoot    bool     `envconfig:"SANDBOX_READ_ONLY_ROOT" default:"true"`
+	SandboxReadOnlyRepo    bool     `envconfig:"SANDBOX_READ_ONLY_REPO" default:"true"`
+	SandboxNoNewPrivileges bool     `envconfig:"SANDBOX_NO_NEW_PRIVILEGES" default:"true"`
+	SandboxCapDrop         []string `envconfig:"SANDBOX_CAP_DROP" default:"ALL"`
+	SandboxSeccomp         string   `envconfig:"SANDBOX_SECCOMP" default:"default"` // default, unconfined or the path of a profile
+	SandboxTmpfsSize       int64    `envconfig:"SANDBOX_TMPFS_SIZE" default:"64"`
+
+	// Sandbox backend, docker or process
+	Runner            string `envconfig:"RUNNER" default:"docker"`
+	ProcessCgroup     string `envconfig:"PROCESS_CGROUP" default:""` // Delegated cgroup v2 directory, no cgroup limits if empty
+	ProcessNamespaces bool   `envconfig:"PROCESS_NAMESPACES" default:"true"`
 
 	// Leaderboard & Auth configuration
 	LeaderboardEnabled bool   `envconfig:"LEADERBOARD_ENABLED" default:"true"`
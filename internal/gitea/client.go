@@ -26,7 +26,7 @@ func (c *GiteaClient) PostStarting(owner string, repo string, commit string, tar
 		state = gitea.StatusPending
 	} else if resStatus == status.StatusPassed {
 		state = gitea.StatusSuccess
-	} else if resStatus == status.StatusFailed {
+	} else if resStatus.IsFailure() {
 		state = gitea.StatusFailure
 	} else if resStatus == status.StatusError {
 		state = gitea.StatusError
@@ -35,13 +35,14 @@ func (c *GiteaClient) PostStarting(owner string, repo string, commit string, tar
 	return c.createCommitStatus(owner, repo, commit, targetURL, state, comment)
 }
 
-func (c *GiteaClient) PostResult(owner string, repo string, commit string, targetURL string, resStatus status.Status) error {
+// PostResult posts the final status of a commit. The summary is appended to the description if not empty.
+func (c *GiteaClient) PostResult(owner string, repo string, commit string, targetURL string, resStatus status.Status, summary string) error {
 	state := gitea.StatusSuccess
 	comment := "Judge successful"
 	if resStatus == status.StatusNone {
 		state = gitea.StatusSuccess
 		comment = "No Testcases found"
-	} else if resStatus == status.StatusFailed {
+	} else if resStatus.IsFailure() {
 		state = gitea.StatusFailure
 		comment = "Judge failed"
 	} else if resStatus == status.StatusError {
@@ -49,9 +50,78 @@ func (c *GiteaClient) PostResult(owner string, repo string, commit string, targe
 		comment = "Judge error"
 	}
 
+	if summary != "" {
+		comment = fmt.Sprintf("%s: %s", comment, summary)
+	}
+
 	return c.createCommitStatus(owner, repo, commit, targetURL, state, comment)
 }
 
+// PostSuperseded marks a commit which was not judged (completely) because a newer commit was pushed
+func (c *GiteaClient) PostSuperseded(owner string, repo string, commit string, targetURL string, newCommit string) error {
+	if len(newCommit) > 8 {
+		newCommit = newCommit[:8]
+	}
+	return c.createCommitStatus(owner, repo, commit, targetURL, gitea.StatusWarning, fmt.Sprintf("Superseded by %s", newCommit))
+}
+
+// RepositoryHead is the latest commit on the default branch of a repository
+type RepositoryHead struct {
+	FullName string
+	CloneURL string
+	Branch   string
+	CommitID string
+}
+
+// ListRepositoryHeads returns the head commit of every non-empty repository of the organization
+func (c *GiteaClient) ListRepositoryHeads(org string) ([]RepositoryHead, error) {
+	client, err := gitea.NewClient(c.baseURL, gitea.SetToken(c.token))
+	if err != nil {
+		return nil, err
+	}
+
+	heads := make([]RepositoryHead, 0)
+	for page := 1; ; page++ {
+		repos, _, err := client.ListOrgRepos(org, gitea.ListOrgReposOptions{
+			ListOptions: gitea.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list repositories of %s: %v", org, err)
+		}
+		if len(repos) == 0 {
+			break
+		}
+
+		for _, repo := range repos {
+			if repo.Empty {
+				continue
+			}
+
+			branch, _, err := client.GetRepoBranch(repo.Owner.UserName, repo.Name, repo.DefaultBranch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get branch %s of %s: %v", repo.DefaultBranch, repo.FullName, err)
+			}
+			if branch.Commit == nil {
+				continue
+			}
+
+			heads = append(heads, RepositoryHead{
+				FullName: repo.FullName,
+				CloneURL: repo.CloneURL,
+				Branch:   repo.DefaultBranch,
+				CommitID: branch.Commit.ID,
+			})
+		}
+	}
+
+	return heads, nil
+}
+
+// PostSkipped marks a commit which was not judged because of its commit message
+func (c *GiteaClient) PostSkipped(owner string, repo string, commit string, targetURL string) error {
+	return c.createCommitStatus(owner, repo, commit, targetURL, gitea.StatusSuccess, "Judge skipped by commit message")
+}
+
 func (c *GiteaClient) createCommitStatus(owner, repo, sha string, targetURL string, status gitea.StatusState, description string) error {
 	client, err := gitea.NewClient(c.baseURL, gitea.SetToken(c.token))
 	if err != nil {
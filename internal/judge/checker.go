@@ -0,0 +1,79 @@
+package judge
+
+import (
+	"context"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/checker"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+)
+
+// Exit codes of custom checker programs
+const (
+	checkerExitAccepted          = 0
+	checkerExitWrongAnswer       = 1
+	checkerExitPresentationError = 2
+)
+
+// customChecker runs an instructor-provided checker program from the task directory inside the sandbox. It is
+// created per case, so the checker is stopped together with the job.
+type customChecker struct {
+	ctx     context.Context
+	runner  Runner
+	program string
+	limits  models.Limits
+}
+
+func (c *customChecker) Check(input string, expected string, actual string) checker.Result {
+	res, err := c.runner.RunChecker(c.ctx, c.program, c.limits, input, expected, actual)
+	if err != nil {
+		return checker.Result{Status: status.StatusError, Message: fmt.Sprintf("checker failed: %v", err)}
+	}
+
+	message := Trim(res.Output)
+	if message == "" {
+		message = Trim(res.Stderr)
+	}
+	switch {
+	case res.Error != "":
+		return checker.Result{Status: status.StatusError, Message: fmt.Sprintf("checker failed: %s", res.Error)}
+	case res.ExceededStream != "":
+		return checker.Result{Status: status.StatusError, Message: fmt.Sprintf("checker exceeded the output limit on %s", res.ExceededStream)}
+	case res.TimedOut:
+		return checker.Result{Status: status.StatusError, Message: "checker timed out"}
+	case res.ExitCode == checkerExitAccepted:
+		return checker.Result{Status: status.StatusPassed, Message: message}
+	case res.ExitCode == checkerExitWrongAnswer:
+		return checker.Result{Status: status.StatusWrongAnswer, Message: message}
+	case res.ExitCode == checkerExitPresentationError:
+		return checker.Result{Status: status.StatusPresentationError, Message: message}
+	}
+
+	return checker.Result{Status: status.StatusError, Message: fmt.Sprintf("checker exited with code %d: %s", res.ExitCode, message)}
+}
+
+// newChecker creates the checker configured for the test case
+func (e *Executor) newChecker(ctx context.Context, tc models.TestCase) (checker.Checker, error) {
+	if tc.SQL != nil {
+		return sqlChecker(tc.SQL), nil
+	}
+	if tc.Checker.Type != checker.TypeCustom {
+		return checker.New(tc.Checker)
+	}
+
+	if tc.Checker.Program == "" {
+		return nil, fmt.Errorf("custom checker requires a program")
+	}
+
+	program, err := taskPath(tc.TaskDir, tc.Checker.Program)
+	if err != nil {
+		return nil, fmt.Errorf("checker program %v", err)
+	}
+
+	return &customChecker{
+		ctx:     ctx,
+		runner:  e.runner,
+		program: program,
+		limits:  tc.Limits,
+	}, nil
+}
@@ -0,0 +1,266 @@
+package checker
+
+import (
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	TypeExact           = "exact"
+	TypeTokens          = "tokens"
+	TypeFloat           = "float"
+	TypeCaseInsensitive = "case_insensitive"
+	TypeUnordered       = "unordered"
+	TypeRegex           = "regex"
+	TypeCustom          = "custom"
+)
+
+// Result is the verdict of a checker. Status is either passed, wrong answer, presentation error
+// or error if the checker itself failed.
+type Result struct {
+	Status  status.Status
+	Message string
+}
+
+// Checker compares the actual output of a solution with the expected output
+type Checker interface {
+	Check(input string, expected string, actual string) Result
+}
+
+// New creates the builtin checker for the config. Custom checkers need a sandbox and are created by the judge.
+func New(cfg models.CheckerConfig) (Checker, error) {
+	switch cfg.Type {
+	case "", TypeExact:
+		return &Exact{}, nil
+	case TypeTokens:
+		return &Tokens{}, nil
+	case TypeFloat:
+		return &Float{AbsEpsilon: cfg.AbsEpsilon, RelEpsilon: cfg.RelEpsilon}, nil
+	case TypeCaseInsensitive:
+		return &CaseInsensitive{}, nil
+	case TypeUnordered:
+		return &Unordered{}, nil
+	case TypeRegex:
+		r := &Regex{}
+		if cfg.Pattern != "" {
+			pattern, err := compileFullMatch(cfg.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid checker pattern: %v", err)
+			}
+			r.Pattern = pattern
+		}
+		return r, nil
+	case TypeCustom:
+		return nil, fmt.Errorf("custom checker must be created by the judge")
+	}
+	return nil, fmt.Errorf("unknown checker type: %s", cfg.Type)
+}
+
+var cutset string
+
+// Trim removes whitespace and control characters from both ends of s
+func Trim(s string) string {
+	if cutset == "" {
+		for i := 0; i < 32; i++ {
+			cutset += string(rune(i))
+		}
+		cutset += "\r\n"
+	}
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(s), cutset))
+}
+
+func lines(s string) []string {
+	l := strings.Split(Trim(s), "\n")
+	for i := range l {
+		l[i] = Trim(l[i])
+	}
+	return l
+}
+
+func passed() Result {
+	return Result{Status: status.StatusPassed}
+}
+
+func wrongAnswer(format string, a ...any) Result {
+	return Result{Status: status.StatusWrongAnswer, Message: fmt.Sprintf(format, a...)}
+}
+
+// Exact compares the output line by line. Leading and trailing whitespace of every line is ignored.
+// Outputs which only differ in other whitespace are reported as presentation error.
+type Exact struct{}
+
+func (c *Exact) Check(_ string, expected string, actual string) Result {
+	return compareLines(expected, actual, func(a, b string) bool { return a == b })
+}
+
+// CaseInsensitive works like Exact but ignores the case of letters
+type CaseInsensitive struct{}
+
+func (c *CaseInsensitive) Check(_ string, expected string, actual string) Result {
+	return compareLines(expected, actual, strings.EqualFold)
+}
+
+func compareLines(expected string, actual string, equal func(a, b string) bool) Result {
+	expectedLines := lines(expected)
+	actualLines := lines(actual)
+
+	whitespaceOnly := slices.EqualFunc(strings.Fields(expected), strings.Fields(actual), equal)
+
+	if len(expectedLines) != len(actualLines) {
+		if whitespaceOnly {
+			return Result{
+				Status:  status.StatusPresentationError,
+				Message: fmt.Sprintf("Expected %d lines, got %d", len(expectedLines), len(actualLines)),
+			}
+		}
+		return wrongAnswer("Expected %d lines, got %d", len(expectedLines), len(actualLines))
+	}
+
+	for i := range expectedLines {
+		if equal(expectedLines[i], actualLines[i]) {
+			continue
+		}
+		if whitespaceOnly {
+			return Result{
+				Status:  status.StatusPresentationError,
+				Message: fmt.Sprintf("Line %d differs in whitespace", i+1),
+			}
+		}
+		return wrongAnswer("Line %d mismatch: Expected: %s Got: %s", i+1, expectedLines[i], actualLines[i])
+	}
+
+	return passed()
+}
+
+// Tokens compares the whitespace separated tokens of the output and ignores line breaks
+type Tokens struct{}
+
+func (c *Tokens) Check(_ string, expected string, actual string) Result {
+	expectedTokens := strings.Fields(expected)
+	actualTokens := strings.Fields(actual)
+
+	for i := range expectedTokens {
+		if i >= len(actualTokens) {
+			return wrongAnswer("Expected %d tokens, got %d", len(expectedTokens), len(actualTokens))
+		}
+		if expectedTokens[i] != actualTokens[i] {
+			return wrongAnswer("Token %d mismatch: Expected: %s Got: %s", i+1, expectedTokens[i], actualTokens[i])
+		}
+	}
+
+	if len(actualTokens) != len(expectedTokens) {
+		return wrongAnswer("Expected %d tokens, got %d", len(expectedTokens), len(actualTokens))
+	}
+
+	return passed()
+}
+
+// Float compares tokens like Tokens but numbers only need to be within the absolute or relative epsilon
+type Float struct {
+	AbsEpsilon float64
+	RelEpsilon float64
+}
+
+func (c *Float) Check(_ string, expected string, actual string) Result {
+	expectedTokens := strings.Fields(expected)
+	actualTokens := strings.Fields(actual)
+
+	if len(expectedTokens) != len(actualTokens) {
+		return wrongAnswer("Expected %d tokens, got %d", len(expectedTokens), len(actualTokens))
+	}
+
+	for i := range expectedTokens {
+		expectedNumber, err := strconv.ParseFloat(expectedTokens[i], 64)
+		if err != nil {
+			if expectedTokens[i] != actualTokens[i] {
+				return wrongAnswer("Token %d mismatch: Expected: %s Got: %s", i+1, expectedTokens[i], actualTokens[i])
+			}
+			continue
+		}
+
+		actualNumber, err := strconv.ParseFloat(actualTokens[i], 64)
+		if err != nil {
+			return wrongAnswer("Token %d: Expected number %s Got: %s", i+1, expectedTokens[i], actualTokens[i])
+		}
+
+		if !c.equal(expectedNumber, actualNumber) {
+			return wrongAnswer("Token %d mismatch: Expected: %s Got: %s", i+1, expectedTokens[i], actualTokens[i])
+		}
+	}
+
+	return passed()
+}
+
+func (c *Float) equal(expected float64, actual float64) bool {
+	if math.IsNaN(expected) || math.IsNaN(actual) {
+		return math.IsNaN(expected) && math.IsNaN(actual)
+	}
+	// The difference of two equal infinities is NaN
+	if math.IsInf(expected, 0) || math.IsInf(actual, 0) {
+		return expected == actual
+	}
+	diff := math.Abs(expected - actual)
+	if diff <= c.AbsEpsilon {
+		return true
+	}
+	return diff <= c.RelEpsilon*math.Abs(expected)
+}
+
+// Unordered compares the lines of the output regardless of their order
+type Unordered struct{}
+
+func (c *Unordered) Check(_ string, expected string, actual string) Result {
+	expectedLines := lines(expected)
+	actualLines := lines(actual)
+
+	if len(expectedLines) != len(actualLines) {
+		return wrongAnswer("Expected %d lines, got %d", len(expectedLines), len(actualLines))
+	}
+
+	remaining := make(map[string]int)
+	for _, line := range expectedLines {
+		remaining[line]++
+	}
+
+	for _, line := range actualLines {
+		if remaining[line] == 0 {
+			return wrongAnswer("Unexpected line: %s", line)
+		}
+		remaining[line]--
+	}
+
+	return passed()
+}
+
+// Regex checks if the whole output matches the pattern. The expected output is used as pattern if none is set.
+type Regex struct {
+	Pattern *regexp.Regexp
+}
+
+func (c *Regex) Check(_ string, expected string, actual string) Result {
+	pattern := c.Pattern
+	if pattern == nil {
+		var err error
+		pattern, err = compileFullMatch(Trim(expected))
+		if err != nil {
+			return Result{Status: status.StatusError, Message: fmt.Sprintf("invalid expected pattern: %v", err)}
+		}
+	}
+
+	if !pattern.MatchString(Trim(actual)) {
+		return wrongAnswer("Output does not match the expected pattern")
+	}
+
+	return passed()
+}
+
+// compileFullMatch compiles a pattern which has to match the complete output
+func compileFullMatch(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`\A(?:` + pattern + `)\z`)
+}
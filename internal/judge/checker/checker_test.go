@@ -0,0 +1,99 @@
+package checker_test
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/checker"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckers(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   models.CheckerConfig
+		expected string
+		actual   string
+		status   status.Status
+	}{
+		{"exact match", models.CheckerConfig{}, "1 2\n3\n", "1 2\n3", status.StatusPassed},
+		{"exact trailing spaces", models.CheckerConfig{Type: "exact"}, "1 2\n3", "1 2  \n3\n\n", status.StatusPassed},
+		{"exact wrong answer", models.CheckerConfig{}, "1 2\n3", "1 2\n4", status.StatusWrongAnswer},
+		{"exact presentation error", models.CheckerConfig{}, "1 2\n3", "1  2\n3", status.StatusPresentationError},
+		{"exact line breaks", models.CheckerConfig{}, "1 2\n3", "1\n2 3", status.StatusPresentationError},
+		{"tokens", models.CheckerConfig{Type: "tokens"}, "1 2\n3", "1\n2   3", status.StatusPassed},
+		{"tokens missing", models.CheckerConfig{Type: "tokens"}, "1 2 3", "1 2", status.StatusWrongAnswer},
+		{"tokens extra", models.CheckerConfig{Type: "tokens"}, "1 2", "1 2 3", status.StatusWrongAnswer},
+		{"float absolute", models.CheckerConfig{Type: "float", AbsEpsilon: 1e-3}, "0.333", "0.3334", status.StatusPassed},
+		{"float relative", models.CheckerConfig{Type: "float", RelEpsilon: 1e-6}, "1000000", "1000000.5", status.StatusPassed},
+		{"float outside", models.CheckerConfig{Type: "float", AbsEpsilon: 1e-6}, "0.333", "0.334", status.StatusWrongAnswer},
+		{"float words", models.CheckerConfig{Type: "float", AbsEpsilon: 1e-6}, "mean 1.5", "mean 1.5000001", status.StatusPassed},
+		{"float not a number", models.CheckerConfig{Type: "float", AbsEpsilon: 1e-6}, "1.5", "abc", status.StatusWrongAnswer},
+		{"float infinity", models.CheckerConfig{Type: "float", AbsEpsilon: 1e-6}, "inf -inf", "+Inf -Inf", status.StatusPassed},
+		{"float infinity sign", models.CheckerConfig{Type: "float", AbsEpsilon: 1e-6}, "inf", "-inf", status.StatusWrongAnswer},
+		{"float infinity huge", models.CheckerConfig{Type: "float", RelEpsilon: 1}, "inf", "1e308", status.StatusWrongAnswer},
+		{"case insensitive", models.CheckerConfig{Type: "case_insensitive"}, "YES\nNo", "yes\nno", status.StatusPassed},
+		{"case insensitive wrong", models.CheckerConfig{Type: "case_insensitive"}, "YES", "no", status.StatusWrongAnswer},
+		{"unordered", models.CheckerConfig{Type: "unordered"}, "a\nb\nb", "b\na\nb", status.StatusPassed},
+		{"unordered duplicate", models.CheckerConfig{Type: "unordered"}, "a\nb\nb", "a\na\nb", status.StatusWrongAnswer},
+		{"regex pattern", models.CheckerConfig{Type: "regex", Pattern: `\d+ solutions?`}, "", "12 solutions", status.StatusPassed},
+		{"regex full match", models.CheckerConfig{Type: "regex", Pattern: `\d+`}, "", "12 solutions", status.StatusWrongAnswer},
+		{"regex expected", models.CheckerConfig{Type: "regex"}, "(foo|bar)", "bar\n", status.StatusPassed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := checker.New(tt.config)
+			assert.NoError(t, err)
+
+			res := c.Check("", tt.expected, tt.actual)
+			assert.Equal(t, tt.status, res.Status, res.Message)
+		})
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := checker.New(models.CheckerConfig{Type: "unknown"})
+	assert.Error(t, err)
+
+	_, err = checker.New(models.CheckerConfig{Type: "regex", Pattern: "("})
+	assert.Error(t, err)
+
+	_, err = checker.New(models.CheckerConfig{Type: "custom"})
+	assert.Error(t, err)
+}
+
+func TestResultSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		checker  checker.ResultSet
+		expected string
+		actual   string
+		status   status.Status
+		message  string
+	}{
+		{"match", checker.ResultSet{}, "name,total\nAlice,3\nBob,4", "name,total\r\nAlice,3\r\nBob,4\r\n", status.StatusPassed, ""},
+		{"quoted values", checker.ResultSet{}, "name\n\"Doe, John\"", "name\n\"Doe, John\"\n", status.StatusPassed, ""},
+		{"numbers", checker.ResultSet{}, "avg\n3\n0.3333333333", "avg\n3.0\n0.333333333333333", status.StatusPassed, ""},
+		{"column case", checker.ResultSet{}, "Name\nAlice", "name\nAlice", status.StatusPassed, ""},
+		{"column names", checker.ResultSet{}, "name,total\nAlice,3", "name,sum\nAlice,3", status.StatusWrongAnswer, "Expected columns: (name, total) Got: (name, sum)"},
+		{"ignore column names", checker.ResultSet{IgnoreColumnNames: true}, "name,total\nAlice,3", "name,sum\nAlice,3", status.StatusPassed, ""},
+		{"column count", checker.ResultSet{IgnoreColumnNames: true}, "name,total\nAlice,3", "name\nAlice", status.StatusWrongAnswer, "Expected 2 columns, got 1"},
+		{"order", checker.ResultSet{}, "name\nAlice\nBob", "name\nBob\nAlice", status.StatusWrongAnswer, "Row 1 mismatch: Expected: (Alice) Got: (Bob)"},
+		{"ignore order", checker.ResultSet{IgnoreOrder: true}, "name\nAlice\nBob\nBob", "name\nBob\nAlice\nBob", status.StatusPassed, ""},
+		{"ignore order duplicate", checker.ResultSet{IgnoreOrder: true}, "name\nAlice\nBob", "name\nBob\nBob", status.StatusWrongAnswer, "Unexpected row: (Bob)"},
+		{"empty", checker.ResultSet{}, "name,total", "", status.StatusPassed, ""},
+		{"missing rows", checker.ResultSet{}, "name\nAlice", "", status.StatusWrongAnswer, "Expected 1 rows, got 0"},
+		{"two result sets", checker.ResultSet{}, "name\nAlice", "name\nAlice\nname,total\nAlice,3", status.StatusWrongAnswer, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.checker.Check("", tt.expected, tt.actual)
+			assert.Equal(t, tt.status, res.Status, res.Message)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, res.Message)
+			}
+		})
+	}
+}
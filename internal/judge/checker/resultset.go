@@ -0,0 +1,114 @@
+package checker
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ResultSet compares the result set of an SQL query printed as CSV with a header line. Numbers are compared with
+// 10 significant digits, so 3 matches 3.0 and 0.333333333333 matches 0.3333333333.
+type ResultSet struct {
+	IgnoreOrder       bool
+	IgnoreColumnNames bool
+}
+
+func (c *ResultSet) Check(_ string, expected string, actual string) Result {
+	expectedColumns, expectedRows, err := ParseResultSet(expected)
+	if err != nil {
+		return Result{Status: status.StatusError, Message: fmt.Sprintf("invalid expected rows: %v", err)}
+	}
+	actualColumns, actualRows, err := ParseResultSet(actual)
+	if err != nil {
+		return wrongAnswer("Output is not a single result set: %v", err)
+	}
+
+	// Queries without rows print no header, so the columns of an empty result are unknown
+	if actualColumns != nil {
+		if len(expectedColumns) != len(actualColumns) {
+			return wrongAnswer("Expected %d columns, got %d", len(expectedColumns), len(actualColumns))
+		}
+		if !c.IgnoreColumnNames {
+			for i := range expectedColumns {
+				if !strings.EqualFold(expectedColumns[i], actualColumns[i]) {
+					return wrongAnswer("Expected columns: %s Got: %s", formatRow(expectedColumns), formatRow(actualColumns))
+				}
+			}
+		}
+	}
+
+	if len(expectedRows) != len(actualRows) {
+		return wrongAnswer("Expected %d rows, got %d", len(expectedRows), len(actualRows))
+	}
+
+	if c.IgnoreOrder {
+		remaining := make(map[string]int)
+		for _, row := range expectedRows {
+			remaining[rowKey(row)]++
+		}
+		for _, row := range actualRows {
+			key := rowKey(row)
+			if remaining[key] == 0 {
+				return wrongAnswer("Unexpected row: %s", formatRow(row))
+			}
+			remaining[key]--
+		}
+		return passed()
+	}
+
+	for i := range expectedRows {
+		if rowKey(expectedRows[i]) != rowKey(actualRows[i]) {
+			return wrongAnswer("Row %d mismatch: Expected: %s Got: %s", i+1, formatRow(expectedRows[i]), formatRow(actualRows[i]))
+		}
+	}
+
+	return passed()
+}
+
+// ParseResultSet parses a result set printed as CSV. The first record holds the names of the columns. Values are
+// trimmed and every row must have as many values as there are columns. An empty result set has no columns.
+func ParseResultSet(s string) ([]string, [][]string, error) {
+	r := csv.NewReader(strings.NewReader(Trim(s)))
+	r.TrimLeadingSpace = true
+
+	var columns []string
+	rows := make([][]string, 0)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		if columns == nil {
+			columns = record
+			continue
+		}
+		rows = append(rows, record)
+	}
+	return columns, rows, nil
+}
+
+// rowKey returns a comparable representation of a row with normalized numbers
+func rowKey(row []string) string {
+	values := make([]string, len(row))
+	for i, value := range row {
+		values[i] = value
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			values[i] = strconv.FormatFloat(number, 'g', 10, 64)
+		}
+	}
+	return strings.Join(values, "\x00")
+}
+
+// formatRow formats a row for a message
+func formatRow(row []string) string {
+	return "(" + strings.Join(row, ", ") + ")"
+}
@@ -19,22 +19,23 @@ var (
 	containerID    string
 )
 
-// init runs when the package is initialized
-func init() {
+// detectHostPaths checks if the judge runs in a Docker container. Bind mounts of judge containers are resolved
+// by the daemon on the host, so shared directories are created in the /repos volume whose host path is looked up.
+func detectHostPaths() error {
 	var err error
 	isDocker, containerID, err = detectDocker()
 	if err != nil {
 		log.WithError(err).Warn("Failed to check if running in Docker")
 		isDocker = false
-		return
+		return nil
 	}
 
 	if isDocker {
 		// Get the mount path using the container ID
 		dockerRepoPath, err = GetContainerInfo("/repos")
 		if err != nil {
-			log.WithError(err).Fatal("Failed to find Docker volume path for /repos")
-			panic("Docker volume /repos must be mounted")
+			isDocker = false
+			return fmt.Errorf("failed to find Docker volume path for /repos, mount it or set DOCKER_WORKSPACE=copy: %v", err)
 		}
 		log.WithFields(log.Fields{
 			"isDocker":       isDocker,
@@ -42,6 +43,7 @@ func init() {
 			"dockerRepoPath": dockerRepoPath,
 		}).Info("Docker environment detected")
 	}
+	return nil
 }
 
 // getTempDir returns the appropriate temporary directory path based on the environment
@@ -59,6 +61,16 @@ func getTempDir(prefix string) (string, error) {
 	return os.MkdirTemp("", prefix)
 }
 
+// shareWithSandbox makes a directory of the judge accessible to the sandbox, which does not run as the user of the
+// judge. Only writable directories can be written by everyone.
+func shareWithSandbox(dir string, writable bool) error {
+	mode := os.FileMode(0755)
+	if writable {
+		mode = 0777
+	}
+	return os.Chmod(dir, mode)
+}
+
 func getHostPath(localPath string) string {
 	if !isDocker {
 		return localPath
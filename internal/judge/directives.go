@@ -0,0 +1,91 @@
+package judge
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"regexp"
+	"strings"
+)
+
+var (
+	skipDirective  = regexp.MustCompile(`(?i)\[skip judge\]`)
+	judgeDirective = regexp.MustCompile(`(?im)^\s*judge:\s*(.+?)\s*$`)
+)
+
+// directives are instructions for the judge in a commit message:
+//
+//	[skip judge]                      do not judge the commit
+//	judge: workshop1/pascal_triangle  judge the task in addition to the changed ones
+//	judge: all                        judge every task
+type directives struct {
+	Skip  bool
+	All   bool
+	Tasks []models.Solution
+}
+
+// parseDirectives returns the directives of a commit message. Multiple tasks can be separated by commas
+// or given in multiple lines.
+func parseDirectives(message string) directives {
+	var d directives
+	d.Skip = skipDirective.MatchString(message)
+
+	for _, match := range judgeDirective.FindAllStringSubmatch(message, -1) {
+		for _, target := range strings.Split(match[1], ",") {
+			target = strings.TrimSpace(target)
+			if strings.EqualFold(target, "all") {
+				d.All = true
+				continue
+			}
+
+			workshop, task, found := strings.Cut(target, "/")
+			if !found || workshop == "" || task == "" || strings.Contains(task, "/") || strings.Contains(target, "..") {
+				continue
+			}
+			d.Tasks = append(d.Tasks, models.Solution{Workshop: workshop, Task: task})
+		}
+	}
+
+	return d
+}
+
+// pushDirectives combines the directives of all commits of a push. The push is only skipped if every commit asks
+// for it, the requested tasks of all commits are judged.
+func pushDirectives(messages []string) directives {
+	var d directives
+	seen := make(map[models.Solution]bool)
+	for i, message := range messages {
+		commit := parseDirectives(message)
+		d.Skip = commit.Skip && (i == 0 || d.Skip)
+		d.All = d.All || commit.All
+		for _, task := range commit.Tasks {
+			if !seen[task] {
+				seen[task] = true
+				d.Tasks = append(d.Tasks, task)
+			}
+		}
+	}
+	return d
+}
+
+// submissionDirectives returns the directives of the pushed commits. Without the commits of the webhook, only the
+// message of the judged commit is known.
+func submissionDirectives(submission models.Submission, message string) directives {
+	if len(submission.CommitMessages) > 0 {
+		return pushDirectives(submission.CommitMessages)
+	}
+	return parseDirectives(message)
+}
+
+// String returns the directives in the notation of a commit message
+func (d directives) String() string {
+	parts := make([]string, 0)
+	if d.Skip {
+		parts = append(parts, "[skip judge]")
+	}
+	if d.All {
+		parts = append(parts, "judge: all")
+	}
+	for _, task := range d.Tasks {
+		parts = append(parts, "judge: "+task.Workshop+"/"+task.Task)
+	}
+	return strings.Join(parts, ", ")
+}
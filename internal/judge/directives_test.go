@@ -0,0 +1,89 @@
+package judge
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected directives
+	}{
+		{
+			name:     "no directives",
+			message:  "Solve task1\n\nThe judge: is mentioned here but not as directive",
+			expected: directives{},
+		},
+		{
+			name:     "skip",
+			message:  "WIP [skip judge]",
+			expected: directives{Skip: true},
+		},
+		{
+			name:    "tasks",
+			message: "Fix output\n\njudge: workshop1/pascal_triangle, workshop2/fizzbuzz\nJudge: workshop1/hello",
+			expected: directives{Tasks: []models.Solution{
+				{Workshop: "workshop1", Task: "pascal_triangle"},
+				{Workshop: "workshop2", Task: "fizzbuzz"},
+				{Workshop: "workshop1", Task: "hello"},
+			}},
+		},
+		{
+			name:     "all",
+			message:  "Everything\n\njudge: all",
+			expected: directives{All: true},
+		},
+		{
+			name:     "invalid tasks are ignored",
+			message:  "judge: workshop1\njudge: ../etc/passwd\njudge: a/b/c",
+			expected: directives{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseDirectives(tt.message))
+		})
+	}
+}
+
+func TestPushDirectives(t *testing.T) {
+	task1 := models.Solution{Workshop: "workshop1", Task: "task1"}
+	task2 := models.Solution{Workshop: "workshop1", Task: "task2"}
+
+	tests := []struct {
+		name     string
+		messages []string
+		expected directives
+	}{
+		{
+			name:     "every commit skips",
+			messages: []string{"WIP [skip judge]", "More WIP [skip judge]"},
+			expected: directives{Skip: true},
+		},
+		{
+			name:     "one commit is not skipped",
+			messages: []string{"WIP [skip judge]", "Done"},
+			expected: directives{},
+		},
+		{
+			name:     "tasks of all commits",
+			messages: []string{"judge: workshop1/task1", "judge: workshop1/task2, workshop1/task1", "Done"},
+			expected: directives{Tasks: []models.Solution{task1, task2}},
+		},
+		{
+			name:     "all",
+			messages: []string{"judge: all", "judge: workshop1/task1"},
+			expected: directives{All: true, Tasks: []models.Solution{task1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, pushDirectives(tt.messages))
+		})
+	}
+}
@@ -1,17 +1,16 @@
 package judge
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
 
@@ -20,27 +19,253 @@ import (
 )
 
 type DockerExecutor struct {
-	cli     *client.Client
-	network string
-	timeout time.Duration
+	cli       *client.Client
+	images    *ImageManager
+	network   string
+	workspace string // How workspaces get into containers, WorkspaceMount or WorkspaceCopy
+	warm      *warmPool
+	limits    models.Limits
+	security  models.Security
 }
 
-func NewDockerExecutor(network string, timeoutSeconds int) (*DockerExecutor, error) {
+// containerRun describes a single container execution
+type containerRun struct {
+	Image      string // DOCKER_IMAGE if empty
+	Entrypoint []string
+	Env        []string
+	WorkingDir string
+	Workspaces []workspace
+	Limits     models.Limits
+	Security   models.Security
+	Labels     map[string]string
+	Stdin      io.Reader // Attached as stdin if set, e.g. the output of the interactor of the case
+	Stdout     io.Writer // Gets the output instead of the result if set
+}
+
+// Program is a solution prepared for execution
+type Program struct {
+	Language *models.Language
+	File     string          // Path of the solution file relative to the repository
+	BuildDir string          // Directory for compiled output, available as /build
+	Security models.Security // Security profile of the task, overrides the server defaults
+}
+
+// programPaths returns the paths of the solution file and its directory inside the container
+func (e *DockerExecutor) programPaths(p Program) (string, string) {
+	file := path.Join(e.containerPath("repo"), filepath.ToSlash(p.File))
+	return file, path.Dir(file)
+}
+
+// programWorkspaces returns the workspaces of the repository and the build directory. The build directory is
+// read back after a compilation.
+func (e *DockerExecutor) programWorkspaces(p Program, repositoryDir string, security models.Security) []workspace {
+	return []workspace{
+		{
+			Dir:      repositoryDir,
+			Name:     "repo",
+			ReadOnly: models.Enabled(security.ReadOnlyRepo),
+		},
+		{
+			Dir:    p.BuildDir,
+			Name:   "build",
+			Output: true,
+		},
+	}
+}
+
+// NewDockerExecutor creates a executor which uses limits and security for every value not set by the test case.
+// With a warm pool size above 0, that many started containers are kept ready per image and security profile.
+func NewDockerExecutor(network string, workspace string, warmPoolSize int, limits models.Limits, security models.Security) (*DockerExecutor, error) {
 	log.Info("New Docker executer created")
 
+	switch workspace {
+	case WorkspaceMount:
+		if warmPoolSize > 0 {
+			return nil, fmt.Errorf("the warm pool requires the %s workspace mode", WorkspaceCopy)
+		}
+		if err := detectHostPaths(); err != nil {
+			return nil, err
+		}
+	case WorkspaceCopy:
+	default:
+		return nil, fmt.Errorf("unknown workspace mode %s", workspace)
+	}
+
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create docker client: %v", err)
 	}
 
-	return &DockerExecutor{
-		cli:     cli,
-		network: network,
-		timeout: time.Duration(timeoutSeconds) * time.Second,
-	}, nil
+	e := &DockerExecutor{
+		cli:       cli,
+		images:    NewImageManager(cli),
+		network:   network,
+		workspace: workspace,
+		limits:    limits,
+		security:  security,
+	}
+	if warmPoolSize > 0 {
+		e.warm = newWarmPool(e, warmPoolSize)
+		e.warm.removeStale(context.Background())
+	}
+	return e, nil
+}
+
+// WarmPoolStats returns the statistics of the warm pool, nil if it is disabled
+func (e *DockerExecutor) WarmPoolStats() *WarmPoolStats {
+	if e.warm == nil {
+		return nil
+	}
+	stats := e.warm.stats()
+	return &stats
+}
+
+// WarmUp starts the idle containers of the warm pool for the images, if it is enabled. Tasks with their own
+// security profile get idle containers once they were judged the first time.
+func (e *DockerExecutor) WarmUp(images []string) {
+	if e.warm != nil {
+		e.warm.warmUp(images)
+	}
+}
+
+// Close removes the idle containers of the warm pool
+func (e *DockerExecutor) Close() {
+	if e.warm != nil {
+		e.warm.close()
+	}
+}
+
+// Images returns the manager of the images containers are created from
+func (e *DockerExecutor) Images() *ImageManager {
+	return e.images
+}
+
+// imageName returns the image of a language, DOCKER_IMAGE is used if it has none
+func imageName(image string) string {
+	if image == "" {
+		return config.CFG.DockerImage
+	}
+	return image
+}
+
+// EffectiveLimits returns the limits a test case is executed with. The limits of the language override the
+// defaults and are overridden by the limits of the test case.
+func (e *DockerExecutor) EffectiveLimits(language *models.Language, limits models.Limits) models.Limits {
+	return effectiveLimits(e.limits, language, limits)
+}
+
+// Environment pulls the image of the language if needed and returns its name and digest
+func (e *DockerExecutor) Environment(ctx context.Context, language *models.Language) (string, string, error) {
+	image := imageName(language.Image)
+	digest, err := e.images.Ensure(ctx, image)
+	return image, digest, err
+}
+
+// DefaultLimits returns the limits configured through the environment
+func DefaultLimits(cfg *config.Config) models.Limits {
+	return models.Limits{
+		Memory:   cfg.DockerMemory,
+		CPUs:     cfg.DockerCPUs,
+		Pids:     cfg.DockerPids,
+		WallTime: float64(cfg.DockerTimeout),
+		CPUTime:  cfg.DockerCPUTime,
+		Output:   cfg.DockerOutputLimit,
+		Stderr:   cfg.DockerStderrLimit,
+	}
+}
+
+// containerResources converts limits to the resources of a docker container
+func containerResources(limits models.Limits) container.Resources {
+	resources := container.Resources{
+		Memory:     limits.Memory * 1024 * 1024,
+		MemorySwap: limits.Memory * 1024 * 1024, // disable swap
+		CPUPeriod:  100000,
+		CPUQuota:   int64(limits.CPUs * 100000),
+	}
+	if limits.Pids > 0 {
+		resources.PidsLimit = &limits.Pids
+	}
+	return resources
+}
+
+// Compile runs the compile command of the language. The compiled program is written to the build directory.
+// Interpreted languages are not compiled.
+func (e *DockerExecutor) Compile(ctx context.Context, repositoryDir string, program Program) (*models.ExecutionResult, error) {
+	if program.Language.Compile == "" {
+		return &models.ExecutionResult{}, nil
+	}
+
+	limits := compileLimits(e.EffectiveLimits(program.Language, models.Limits{}))
+
+	security := e.EffectiveSecurity(program.Security)
+	file, dir := e.programPaths(program)
+	result, err := e.run(ctx, containerRun{
+		Image:      program.Language.Image,
+		Entrypoint: []string{"/bin/sh", "-c", program.Language.Command(program.Language.Compile, file, dir, e.containerPath("build"))},
+		WorkingDir: dir,
+		Workspaces: e.programWorkspaces(program, repositoryDir, security),
+		Limits:     limits,
+		Security:   security,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return compileResult(result, limits), nil
 }
 
-func (e *DockerExecutor) RunCode(ctx context.Context, testCase models.TestCase) (*models.ExecutionResult, error) {
+// RunCode runs a prepared program with the input of the test case on stdin
+func (e *DockerExecutor) RunCode(ctx context.Context, testCase models.TestCase, program Program) (*models.ExecutionResult, error) {
+	return e.runCode(ctx, testCase, program, nil, nil)
+}
+
+// RunInteractive runs a prepared program and the interactor of the test case in two containers. The interactor
+// runs in the judge image with the security profile of the server and only its container holds the input of the
+// case.
+func (e *DockerExecutor) RunInteractive(ctx context.Context, testCase models.TestCase, program Program) (*models.ExecutionResult, error) {
+	tmpDir, err := getTempDir("interactor-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	command, err := writeInteractor(tmpDir, e.containerPath("judge"), testCase.Interactor, testCase.Input)
+	if err != nil {
+		return nil, err
+	}
+	if err := shareWithSandbox(tmpDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	return interact(func(stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error) {
+		return e.runCode(ctx, testCase, program, stdin, stdout)
+	}, func(stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error) {
+		return e.run(ctx, containerRun{
+			Entrypoint: []string{"/bin/sh", "-c", command},
+			WorkingDir: e.containerPath("judge"),
+			Workspaces: []workspace{
+				{
+					Dir:      tmpDir,
+					Name:     "judge",
+					ReadOnly: true,
+				},
+			},
+			Limits:   testCase.Limits,
+			Security: e.security,
+			Stdin:    stdin,
+			Stdout:   stdout,
+		})
+	})
+}
+
+// runCode runs a prepared program. Without stdin, the program reads the input of the test case and its output is
+// stored in the result. Otherwise stdin is attached and the output is written to stdout.
+func (e *DockerExecutor) runCode(ctx context.Context, testCase models.TestCase, program Program, stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error) {
+	limits := e.EffectiveLimits(program.Language, testCase.Limits)
+	if e.warm != nil && !testCase.HasFiles() && stdin == nil {
+		return e.runInSession(ctx, testCase, program, limits)
+	}
+
 	// Create temp directory for code and test files
 	tmpDir, err := getTempDir("judge-*")
 	if err != nil {
@@ -49,116 +274,284 @@ func (e *DockerExecutor) RunCode(ctx context.Context, testCase models.TestCase)
 	defer os.RemoveAll(tmpDir)
 
 	// Write files
-	if err := os.WriteFile(filepath.Join(tmpDir, "input.txt"), []byte(testCase.Input), 0644); err != nil {
-		return nil, fmt.Errorf("failed to write input: %v", err)
+	if stdin == nil {
+		if err := os.WriteFile(filepath.Join(tmpDir, "input.txt"), []byte(testCase.Input), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write input: %v", err)
+		}
 	}
 	if err := os.WriteFile(filepath.Join(tmpDir, "expected.txt"), []byte(testCase.Expected), 0644); err != nil {
 		return nil, fmt.Errorf("failed to write expected output: %v", err)
 	}
 
-	// Check if image exists locally
-	images, err := e.cli.ImageList(ctx, image.ListOptions{})
+	if err := shareWithSandbox(tmpDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	security := e.EffectiveSecurity(program.Security)
+	file, dir := e.programPaths(program)
+	input := path.Join(e.containerPath("judge"), "input.txt")
+	command := program.Language.Command(program.Language.Run, file, dir, e.containerPath("build"))
+	command = caseCommand(command, testCase.Args)
+	if stdin == nil {
+		command += " < " + input
+	}
+	command = measureCommand(command, limits)
+
+	workspaces := append(e.programWorkspaces(program, testCase.RepositoryDir, security), workspace{
+		Dir:      tmpDir,
+		Name:     "judge",
+		ReadOnly: true,
+	})
+
+	// Cases with files run in a working directory of their own, which is read back after the run
+	workDir := ""
+	if testCase.HasFiles() {
+		if workDir, err = prepareWorkDir(testCase); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(workDir)
+		dir = e.containerPath(workDirName)
+		workspaces = append(workspaces, workspace{
+			Dir:    workDir,
+			Name:   workDirName,
+			Output: true,
+		})
+	}
+
+	result, err := e.run(ctx, containerRun{
+		Image:      program.Language.Image,
+		Entrypoint: []string{"/bin/sh", "-c", command},
+		Env:        caseEnv(testCase),
+		WorkingDir: dir,
+		Workspaces: workspaces,
+		Limits:     measureLimits(limits),
+		Security:   security,
+		Stdin:      stdin,
+		Stdout:     stdout,
+	})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	imageExists := false
-	for _, img := range images {
-		for _, tag := range img.RepoTags {
-			if tag == config.CFG.DockerImage {
-				imageExists = true
-				break
-			}
+	if workDir != "" {
+		if err := readOutputFiles(workDir, testCase, limits, result); err != nil {
+			return nil, err
 		}
 	}
 
-	imageFields := log.Fields{
-		"Image": config.CFG.DockerImage,
+	return runResult(result, limits), nil
+}
+
+// runInSession runs a test case in a session of its own, which takes a container from the warm pool
+func (e *DockerExecutor) runInSession(ctx context.Context, testCase models.TestCase, program Program, limits models.Limits) (*models.ExecutionResult, error) {
+	session, err := e.NewSession(ctx, testCase.RepositoryDir, program, limits)
+	var imageErr *ImageError
+	if errors.As(err, &imageErr) {
+		return &models.ExecutionResult{Error: imageErr.Error()}, nil
 	}
-	// Pull image if it doesn't exist
-	if !imageExists {
-		log.WithFields(imageFields).Warn("Image not found locally, pulling...")
-		reader, err := e.cli.ImagePull(ctx, config.CFG.DockerImage, image.PullOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("failed to pull image: %v", err)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	testCase.Limits = limits
+	return session.Run(ctx, testCase)
+}
+
+// runResult reads the measurements of a program and sets its signal
+func runResult(result *models.ExecutionResult, limits models.Limits) *models.ExecutionResult {
+	parseStats(result, limits)
+
+	if result.ExitCode > 128 {
+		// The shell reports processes killed by a signal as 128 + signal number
+		result.Signal = result.ExitCode - 128
+	}
+	return result
+}
+
+// RunChecker runs a custom checker program in a container. The checker is called with the paths of the
+// input, the actual output and the expected output as arguments.
+func (e *DockerExecutor) RunChecker(ctx context.Context, program string, limits models.Limits, input, expected, actual string) (*models.ExecutionResult, error) {
+	tmpDir, err := getTempDir("checker-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	command, err := writeChecker(tmpDir, e.containerPath("judge"), program, input, expected, actual)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := shareWithSandbox(tmpDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	return e.run(ctx, containerRun{
+		Entrypoint: []string{"/bin/sh", "-c", command},
+		WorkingDir: e.containerPath("judge"),
+		Workspaces: []workspace{
+			{
+				Dir:      tmpDir,
+				Name:     "judge",
+				ReadOnly: true,
+			},
+		},
+		Limits:   limits,
+		Security: e.security,
+	})
+}
+
+// createContainer pulls the image if needed and creates a container. The returned function removes it.
+func (e *DockerExecutor) createContainer(ctx context.Context, spec containerRun) (string, func(), error) {
+	name := imageName(spec.Image)
+	if _, err := e.images.Ensure(ctx, name); err != nil {
+		return "", nil, err
+	}
+
+	hostConfig := &container.HostConfig{
+		NetworkMode: container.NetworkMode(e.network),
+		// The output is attached, the daemon does not need to store it
+		LogConfig: container.LogConfig{
+			Type: "none",
+		},
+		RestartPolicy: container.RestartPolicy{
+			Name: container.RestartPolicyDisabled,
+		},
+		Resources: containerResources(spec.Limits),
+		Mounts:    e.workspaceMounts(spec.Workspaces),
+	}
+	if err := applySecurity(hostConfig, spec.Security); err != nil {
+		return "", nil, err
+	}
+
+	create := func() (container.CreateResponse, error) {
+		return e.cli.ContainerCreate(ctx,
+			&container.Config{
+				Image:        name,
+				Entrypoint:   spec.Entrypoint,
+				Env:          spec.Env,
+				WorkingDir:   spec.WorkingDir,
+				User:         spec.Security.User,
+				Labels:       spec.Labels,
+				AttachStdin:  spec.Stdin != nil,
+				AttachStdout: true,
+				AttachStderr: true,
+				OpenStdin:    spec.Stdin != nil,
+				StdinOnce:    spec.Stdin != nil,
+			}, hostConfig, nil, nil, "")
+	}
+
+	resp, err := create()
+	if client.IsErrNotFound(err) {
+		// The image was removed from the daemon since it was cached
+		e.images.Forget(name)
+		if _, err := e.images.Ensure(ctx, name); err != nil {
+			return "", nil, err
 		}
-		// Wait for the image pull to complete
-		_, err = io.Copy(io.Discard, reader)
+		resp, err = create()
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create container: %v", err)
+	}
+
+	// The container is removed with a fresh context, so it is also cleaned up if the judge was cancelled
+	remove := func() {
+		err := e.cli.ContainerRemove(context.Background(), resp.ID, container.RemoveOptions{
+			Force:         true,
+			RemoveVolumes: true,
+		})
 		if err != nil {
-			panic(err)
+			log.WithField("ContainerID", resp.ID).WithError(err).Error("Failed to remove container")
 		}
-		reader.Close()
-		log.WithFields(imageFields).Info("Successfully pulled")
-	} else {
-		log.WithFields(imageFields).Debug("Image found locally")
-	}
-
-	// Create container
-	resp, err := e.cli.ContainerCreate(ctx,
-		&container.Config{
-			Image: config.CFG.DockerImage,
-			Env: []string{
-				fmt.Sprintf("JUDGE_WORKSHOP=%s", testCase.Solution.Workshop),
-				fmt.Sprintf("JUDGE_TASK=%s", testCase.Solution.Task),
-			},
-			WorkingDir: "/judge",
-		},
-		&container.HostConfig{
-			NetworkMode: container.NetworkMode(e.network),
-			RestartPolicy: container.RestartPolicy{
-				Name: container.RestartPolicyDisabled,
-			},
-			Resources: container.Resources{
-				Memory:    256 * 1024 * 1024, // 256MB memory limit
-				CPUPeriod: 100000,
-				CPUQuota:  50000, // 0.5 CPU
-			},
-			Mounts: []mount.Mount{
-				{
-					Type:   mount.TypeBind,
-					Source: fmt.Sprintf("%s", getHostPath(tmpDir)),
-					Target: "/judge",
-				},
-				{
-					Type:   mount.TypeBind,
-					Source: fmt.Sprintf("%s", getHostPath(testCase.RepositoryDir)),
-					Target: "/repo",
-				},
-			},
-		}, nil, nil, "")
+	}
+
+	if err := e.copyWorkspaces(ctx, resp.ID, spec.Workspaces); err != nil {
+		remove()
+		return "", nil, err
+	}
+
+	return resp.ID, remove, nil
+}
+
+// run creates a container, waits until it exited or the wall time is exceeded and collects its output
+func (e *DockerExecutor) run(ctx context.Context, spec containerRun) (*models.ExecutionResult, error) {
+	containerID, remove, err := e.createContainer(ctx, spec)
+	var imageErr *ImageError
+	if errors.As(err, &imageErr) {
+		return &models.ExecutionResult{Error: imageErr.Error()}, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create container: %v", err)
+		return nil, err
 	}
+	defer remove()
+
 	containerFields := log.Fields{
-		"ContainerID": resp.ID,
+		"ContainerID": containerID,
 	}
-	defer func(cli *client.Client, ctx context.Context, containerID string, options container.RemoveOptions) {
-		err := cli.ContainerRemove(ctx, containerID, options)
-		if err != nil {
-			log.WithFields(containerFields).WithError(err).Error("Failed to remove container")
-		}
-	}(e.cli, ctx, resp.ID, container.RemoveOptions{
-		Force: true,
+
+	// The output is read while the container runs, so a program which prints endlessly is stopped at the limit
+	attach, err := e.cli.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdin:  spec.Stdin != nil,
+		Stdout: true,
+		Stderr: true,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach container: %v", err)
+	}
+	defer attach.Close()
+
+	output := newStreams(spec.Limits)
+	var stdout io.Writer = output.stdout
+	if spec.Stdout != nil {
+		stdout = spec.Stdout
+	}
+	copied := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, output.stderr, attach.Reader)
+		copied <- err
+	}()
 
 	// Start container with timeout
 	start := time.Now()
-	if err := e.cli.ContainerStart(ctx, resp.ID, container.StartOptions{}); err != nil {
+	if err := e.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
 		return nil, fmt.Errorf("failed to start container: %v", err)
 	}
+	if spec.Stdin != nil {
+		go func() {
+			// The program reads the end of its input once stdin is closed
+			io.Copy(attach.Conn, spec.Stdin)
+			attach.CloseWrite()
+		}()
+	}
 
-	log.WithFields(containerFields).WithError(err).Debug("Starting container")
+	log.WithFields(containerFields).WithFields(log.Fields{
+		"Memory":   spec.Limits.Memory,
+		"CPUs":     spec.Limits.CPUs,
+		"Pids":     spec.Limits.Pids,
+		"WallTime": spec.Limits.WallTime,
+	}).Debug("Starting container")
 
 	// Wait for container with timeout
-	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	ctx, cancel := context.WithTimeout(ctx, spec.Limits.WallTimeDuration())
 	defer cancel()
 
-	statusCh, errCh := e.cli.ContainerWait(ctx, resp.ID, container.WaitConditionNotRunning)
+	statusCh, errCh := e.cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
 	var result models.ExecutionResult
-	result.ExecutionTime = time.Since(start)
+	timedOut := func() (*models.ExecutionResult, error) {
+		result.ExecutionTime = time.Since(start)
+		result.TimedOut = true
+		log.WithFields(containerFields).Warn("execution timeout")
+		return &result, nil
+	}
 
 	select {
 	case err := <-errCh:
+		// The wait also fails with the error of the context once the wall time is over
+		if ctx.Err() != nil {
+			return timedOut()
+		}
 		if err != nil {
 			str := fmt.Sprintf("execution error: %v", err)
 			result.Error = str
@@ -166,9 +559,15 @@ func (e *DockerExecutor) RunCode(ctx context.Context, testCase models.TestCase)
 			return &result, nil
 		}
 	case <-ctx.Done():
-		str := "execution timeout"
-		result.Error = str
-		log.WithFields(containerFields).Warn(str)
+		return timedOut()
+	case <-output.exceeded:
+		result.ExecutionTime = time.Since(start)
+		log.WithFields(containerFields).Warn("output limit exceeded")
+		if err := e.cli.ContainerKill(context.Background(), containerID, "KILL"); err != nil {
+			log.WithFields(containerFields).WithError(err).Error("Failed to kill container")
+		}
+		<-copied
+		output.collect(&result)
 		return &result, nil
 	case status := <-statusCh:
 		if status.Error != nil {
@@ -179,25 +578,25 @@ func (e *DockerExecutor) RunCode(ctx context.Context, testCase models.TestCase)
 		}
 		result.ExitCode = status.StatusCode
 	}
+	result.ExecutionTime = time.Since(start)
 
-	// Get logs
-	logs, err := e.cli.ContainerLogs(context.Background(), resp.ID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-	})
+	inspect, err := e.cli.ContainerInspect(context.Background(), containerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get logs: %v", err)
+		return nil, fmt.Errorf("failed to inspect container: %v", err)
+	}
+	if inspect.State != nil {
+		result.OOMKilled = inspect.State.OOMKilled
 	}
-	defer logs.Close()
 
-	// Read all output
-	var output bytes.Buffer
+	// The stream ends once the container exited
+	if err := <-copied; err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read output: %v", err)
+	}
 
-	_, err = stdcopy.StdCopy(&output, &output, logs)
-	if err != nil && err != io.EOF {
-		return nil, fmt.Errorf("failed to read logs: %v", err)
+	if err := e.copyOutputs(context.Background(), containerID, spec.Workspaces); err != nil {
+		return nil, err
 	}
 
-	result.Output = output.String()
+	output.collect(&result)
 	return &result, nil
 }
@@ -5,52 +5,117 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-git/go-git/v5"
+	gitConfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/checker"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/language"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
 	log "github.com/sirupsen/logrus"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"syscall"
 )
 
 type Executor struct {
-	docker      *DockerExecutor
+	runner      Runner
 	testCaseDir string
+	languages   atomic.Pointer[language.Registry]
 }
 
-func NewExecutor(docker *DockerExecutor, testCaseDir string) *Executor {
+func NewExecutor(runner Runner, testCaseDir string, languages *language.Registry) *Executor {
 	log.Info("New executer created")
 
-	return &Executor{
-		docker:      docker,
+	e := &Executor{
+		runner:      runner,
 		testCaseDir: testCaseDir,
 	}
+	e.languages.Store(languages)
+	return e
 }
 
-var (
-	ExtraCutset = ""
-)
+// SetLanguages replaces the language registry. Running judges keep the languages they started with.
+func (e *Executor) SetLanguages(languages *language.Registry) {
+	e.languages.Store(languages)
+}
 
-func Trim(s string) string {
-	if ExtraCutset == "" {
-		for i := 0; i < 32; i++ {
-			ExtraCutset += string(rune(i))
-		}
-		ExtraCutset += "\r\n"
+// WarmPoolStats returns the statistics of the warm container pool of the runner, nil if it has none
+func (e *Executor) WarmPoolStats() *WarmPoolStats {
+	if docker, ok := e.runner.(*DockerExecutor); ok {
+		return docker.WarmPoolStats()
+	}
+	return nil
+}
+
+// Close releases the sandboxes the runner keeps ready
+func (e *Executor) Close() {
+	if docker, ok := e.runner.(*DockerExecutor); ok {
+		docker.Close()
 	}
-	return strings.TrimSpace(strings.Trim(strings.TrimSpace(s), ExtraCutset))
 }
 
-func (e *Executor) Execute(submission models.Submission) (*models.TestResult, error) {
+// preparedProgram is the detected and compiled solution of a task
+type preparedProgram struct {
+	program *Program
+	session Session                 // Sandbox the cases run in, nil if every case is isolated
+	image   string                  // Environment the program runs in, e.g. the image of its container
+	digest  string                  // Digest of the environment
+	compile *models.ExecutionResult // Result of a failed compilation or a missing environment
+	invalid string                  // Reason why the solution cannot be judged
+
+	repositoryDir string // Copy of the repository with the files of the instructor, empty if the checkout is used
+}
+
+// run runs a test case in the session of the program or in its own sandbox. Cases with files always get their
+// own sandbox, so their working directory is not shared. Interactive cases get a sandbox next to the interactor.
+func (p *preparedProgram) run(ctx context.Context, runner Runner, tc models.TestCase) (*models.ExecutionResult, error) {
+	if p.repositoryDir != "" {
+		tc.RepositoryDir = p.repositoryDir
+	}
+	if tc.Interactor != "" {
+		return runner.RunInteractive(ctx, tc, *p.program)
+	}
+	if p.session != nil && p.session.Accepts(tc.Limits) && !tc.HasFiles() {
+		return p.session.Run(ctx, tc)
+	}
+	return runner.RunCode(ctx, tc, *p.program)
+}
+
+// close removes the session, the build directory and the copied repository of the program
+func (p *preparedProgram) close() {
+	if p == nil {
+		return
+	}
+	if p.session != nil {
+		p.session.Close()
+	}
+	if p.program != nil {
+		os.RemoveAll(p.program.BuildDir)
+	}
+	if p.repositoryDir != "" {
+		os.RemoveAll(p.repositoryDir)
+	}
+}
+
+func Trim(s string) string {
+	return checker.Trim(s)
+}
+
+func (e *Executor) Execute(ctx context.Context, submission models.Submission) (*models.TestResult, error) {
 	repoTmpDir, err := getTempDir("jrepo-*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp dir: %v", err)
 	}
 	defer os.RemoveAll(repoTmpDir)
+	if err := shareWithSandbox(repoTmpDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
 
 	field := log.Fields{
 		"Repo":   submission.RepoName,
@@ -58,20 +123,35 @@ func (e *Executor) Execute(submission models.Submission) (*models.TestResult, er
 	}
 	log.WithFields(field).Debug("Worker executes")
 
-	r, err := git.PlainClone(repoTmpDir, false, &git.CloneOptions{
-		URL: submission.CloneURL,
-		Auth: &http.BasicAuth{
-			Username: "git-judge-system", // yes, this can be anything except an empty string
-			Password: config.CFG.GiteaToken,
-		},
+	// Rejudged commits can be older than the latest two commits of the branch
+	depth := 2 // need to get the parent commit
+	if submission.Rejudge {
+		depth = 0
+	}
+
+	auth := &http.BasicAuth{
+		Username: "git-judge-system", // yes, this can be anything except an empty string
+		Password: config.CFG.GiteaToken,
+	}
+	r, err := git.PlainCloneContext(ctx, repoTmpDir, false, &git.CloneOptions{
+		URL:               submission.CloneURL,
+		Auth:              auth,
 		ReferenceName:     plumbing.ReferenceName(submission.BranchName),
-		Depth:             2, // need to get the parent commit
+		Depth:             depth,
 		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to clone repo %s: %v", submission.CloneURL, err)
 	}
 
+	// A push can contain any number of commits. Only the tree of the previous head is needed to find the
+	// changed files, so it is fetched on its own instead of the history in between.
+	if hasBefore(submission) {
+		if err := fetchCommit(ctx, r, auth, submission.BeforeCommitID); err != nil {
+			log.WithFields(field).WithField("Before", submission.BeforeCommitID).WithError(err).Debug("Failed to fetch previous head")
+		}
+	}
+
 	w, err := r.Worktree()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get worktree %s: %v", submission.CloneURL, err)
@@ -103,55 +183,50 @@ func (e *Executor) Execute(submission models.Submission) (*models.TestResult, er
 		return nil, fmt.Errorf("failed to get commit: %v", err)
 	}
 
-	// Get the parent commit to compare changes
-	parentCommit, err := commit.Parent(0)
-	if err != nil {
-		if errors.Is(err, object.ErrParentNotFound) {
-			// This is the first commit
+	var taskPaths []string
+	var applied string
+	if len(submission.Solutions) > 0 {
+		taskPaths = solutionPaths(repoTmpDir, submission.Solutions)
+		log.WithFields(field).WithField("Tasks", taskPaths).Debug("judging requested tasks")
+	} else {
+		commitDirectives := submissionDirectives(submission, commit.Message)
+		applied = commitDirectives.String()
+		if commitDirectives.Skip {
+			log.WithFields(field).Info("Judging skipped by commit message")
 			return &models.TestResult{
-				Status: status.StatusNone,
+				Status:     status.StatusNone,
+				Skipped:    true,
+				Directives: applied,
 			}, nil
 		}
-		return nil, fmt.Errorf("failed to get parent commit: %v", err)
-	}
 
-	// Get changes between commits
-	patch, err := commit.Patch(parentCommit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get patch: %v", err)
-	}
+		changedFiles, err := changedFiles(r, commit, submission)
+		if err != nil {
+			return nil, err
+		}
 
-	changedFiles := make([]string, 0)
-	for _, filePatch := range patch.FilePatches() {
-		from, to := filePatch.Files()
+		log.WithFields(field).WithField("ChangedFiles", changedFiles).Debug("files changed by the push")
 
-		// Handle added files
-		if from == nil && to != nil {
-			changedFiles = append(changedFiles, to.Path())
-			continue
+		for _, file := range changedFiles {
+			taskPaths = append(taskPaths, filepath.Dir(file))
 		}
 
-		/*
-			// Handle deleted files
-			if from != nil && to == nil {
-				changedFiles = append(changedFiles, from.Path())
-				continue
+		requested := commitDirectives.Tasks
+		if commitDirectives.All {
+			tasks, err := FindAllTasks(e.testCaseDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load tasks: %v", err)
+			}
+			for _, task := range tasks {
+				requested = append(requested, models.Solution{Workshop: task.Workshop, Task: task.Task})
 			}
-		*/
-
-		// Handle modified files
-		if from != nil && to != nil {
-			changedFiles = append(changedFiles, to.Path())
 		}
+		taskPaths = append(taskPaths, solutionPaths(repoTmpDir, requested)...)
 	}
 
-	log.WithFields(field).WithField("ChangedFiles", changedFiles).Debug("files in latest commit")
-
 	testCases := make([]models.TestCase, 0)
 
-	for _, file := range changedFiles {
-		path := filepath.Dir(file)
-
+	for _, path := range uniquePaths(taskPaths) {
 		// Get test cases for the task
 		newTestCases, err := LoadTestCases(filepath.Join(e.testCaseDir, path))
 
@@ -183,12 +258,45 @@ func (e *Executor) Execute(submission models.Submission) (*models.TestResult, er
 		}
 	}
 
-	log.WithFields(field).WithField("ChangedFiles", changedFiles).Debugf("Found %d test cases in %d changed files", len(testCases), len(changedFiles))
+	log.WithFields(field).WithField("Tasks", taskPaths).Debugf("Found %d test cases", len(testCases))
+
+	testCaseResults, err := e.judge(ctx, testCases, field)
+	if err != nil {
+		return nil, err
+	}
 
 	result := &models.TestResult{
-		TestCases: make([]models.TestCaseResult, len(testCases)),
+		TestCases:  testCaseResults,
+		Directives: applied,
+	}
+	result.Scores = models.ComputeScores(result.TestCases)
+
+	// Calculate overall result
+	result.Status = status.StatusPassed
+	for _, tc := range result.TestCases {
+		if tc.Status != status.StatusPassed {
+			result.Status = status.StatusFailed
+			break
+		}
 	}
 
+	log.WithFields(field).Debug("Worker finished")
+
+	return result, nil
+}
+
+// judge runs the test cases and determines their verdicts. The cases of a task must follow each other.
+func (e *Executor) judge(ctx context.Context, testCases []models.TestCase, field log.Fields) ([]models.TestCaseResult, error) {
+	results := make([]models.TestCaseResult, 0, len(testCases))
+
+	// The cases of a task are loaded one after another, every task is compiled once
+	var prepared *preparedProgram
+	var preparedTask string
+	var err error
+	defer func() {
+		prepared.close()
+	}()
+
 	// Run each test case
 	for i, tc := range testCases {
 		tcField := log.Fields{
@@ -197,66 +305,366 @@ func (e *Executor) Execute(submission models.Submission) (*models.TestResult, er
 		}
 		log.WithFields(field).Info("Executing test case")
 
-		execResult, err := e.docker.RunCode(context.Background(), tc)
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute test case %d: %v", i+1, err)
+		task := filepath.Join(tc.Solution.Workshop, tc.Solution.Task)
+		if prepared == nil || preparedTask != task {
+			prepared.close()
+			prepared, err = e.prepare(ctx, tc)
+			preparedTask = task
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare %s: %v", task, err)
+			}
 		}
 
 		caseResult := models.TestCaseResult{
-			TestNumber:    i + 1,
-			ExecutionTime: execResult.ExecutionTime,
-			Error:         execResult.Error,
 			Solution:      *tc.Solution,
 			IsHidden:      tc.IsHidden,
+			Points:        tc.Points,
+			Subtask:       tc.Subtask,
+			SubtaskPoints: tc.SubtaskPoints,
 		}
 
-		log.WithFields(field).WithFields(tcField).WithField("output", execResult.Output).Trace()
-		if execResult.Error != "" {
-			caseResult.Status = status.StatusError
-			log.WithFields(field).WithFields(tcField).Error(caseResult.Error)
-		} else if execResult.ExitCode != 0 {
-			caseResult.Status = status.StatusError
-			caseResult.Error = fmt.Sprintf("Program exited with code %d", execResult.ExitCode)
-			caseResult.Status = status.StatusError
-			log.WithFields(field).WithFields(tcField).Error(caseResult.Error)
-		} else {
-			// Compare output
-			expectedLines := strings.Split(Trim(tc.Expected), "\n")
-			actualLines := strings.Split(Trim(execResult.Output), "\n")
-
-			if len(expectedLines) != len(actualLines) {
-				caseResult.Status = status.StatusFailed
-				caseResult.Error = fmt.Sprintf("Expected %d lines, got %d", len(expectedLines), len(actualLines))
-			} else {
-				caseResult.Status = status.StatusPassed
-				for j := range expectedLines {
-					expected := Trim(expectedLines[j])
-					actual := Trim(actualLines[j])
-
-					log.WithFields(field).WithFields(tcField).Trace(fmt.Sprintf("Testing %s/%s/%s  \"%v\" - \"%v\"", submission.RepoName, tc.Solution.Workshop, tc.Solution.Task, []rune(expected), []rune(actual)))
-
-					if expected != actual {
-						caseResult.Status = status.StatusFailed
-						caseResult.Error = fmt.Sprintf("Line %d mismatch: Expected: %s Got: %s", j+1, expected, actual)
-						break
-					}
+		var lang *models.Language
+		if prepared.program != nil {
+			lang = prepared.program.Language
+			caseResult.Language = lang.Name
+			caseResult.Image = prepared.image
+			caseResult.ImageDigest = prepared.digest
+		}
+		tc.Limits = e.runner.EffectiveLimits(lang, tc.Limits)
+
+		var execResult *models.ExecutionResult
+		switch {
+		case prepared.invalid != "":
+			caseResult.Status, caseResult.Error = status.StatusCompileError, prepared.invalid
+		case prepared.compile != nil:
+			caseResult.Status, caseResult.Error = e.verdict(ctx, tc, prepared.compile)
+		default:
+			execResult, err = prepared.run(ctx, e.runner, tc)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to execute test case %d: %v", i+1, err)
+			}
+
+			log.WithFields(field).WithFields(tcField).WithField("output", execResult.Output).Trace()
+			caseResult.ExecutionTime = execResult.ExecutionTime
+			caseResult.CPUTime = execResult.CPUTime
+			caseResult.MemoryPeak = execResult.MemoryPeak
+			if tc.Unit != nil {
+				// The report is split into a result per test below
+				break
+			}
+			if !tc.IsHidden && execResult.Stderr != "" {
+				caseResult.Stderr = stderrExcerpt(execResult.Stderr)
+			}
+			caseResult.Status, caseResult.Error = e.verdict(ctx, tc, execResult)
+			// A cancelled custom checker is no verdict of the solution
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if tc.SQL != nil && !tc.IsHidden && caseResult.Status == status.StatusWrongAnswer {
+				caseResult.ResultSet = sqlResultSet(execResult.Output)
+			}
+			if tc.Interactor != "" && !tc.IsHidden {
+				if interaction, finished := parseInteraction(execResult.Output); finished && interaction.Transcript != "" {
+					caseResult.Transcript = stderrExcerpt(interaction.Transcript)
 				}
 			}
 		}
 
-		result.TestCases[i] = caseResult
+		caseResults := []models.TestCaseResult{caseResult}
+		if tc.Unit != nil {
+			caseResults = unitResults(tc, caseResult, execResult)
+		}
+
+		for _, caseResult := range caseResults {
+			caseResult.TestNumber = len(results) + 1
+			if caseResult.Status == status.StatusError {
+				log.WithFields(field).WithFields(tcField).Error(caseResult.Error)
+			} else if caseResult.Status != status.StatusPassed {
+				log.WithFields(field).WithFields(tcField).WithField("Verdict", caseResult.Status).Debug(caseResult.Error)
+			}
+			results = append(results, caseResult)
+		}
 	}
 
-	// Calculate overall result
-	result.Status = status.StatusPassed
-	for _, tc := range result.TestCases {
-		if tc.Status != status.StatusPassed {
-			result.Status = status.StatusFailed
-			break
+	return results, nil
+}
+
+// prepare detects the language of the solution of a task and compiles it. A solution which cannot be
+// judged or compiled is reported in the returned program instead of an error.
+func (e *Executor) prepare(ctx context.Context, tc models.TestCase) (*preparedProgram, error) {
+	task := filepath.Join(tc.Solution.Workshop, tc.Solution.Task)
+	lang, file, err := e.languages.Load().Detect(filepath.Join(tc.RepositoryDir, task), tc.Languages)
+	if err != nil {
+		return &preparedProgram{invalid: err.Error()}, nil
+	}
+
+	// Unit test tasks add the test files of the instructor to a copy of the solution, the checkout is not
+	// changed. A copy which fails is a judge error of the task.
+	prepared := &preparedProgram{}
+	repositoryDir := tc.RepositoryDir
+	if tc.Unit != nil {
+		prepared.repositoryDir, err = copySolution(tc, task)
+		if err != nil {
+			log.WithField("Task", task).WithError(err).Error("Failed to add the files of the instructor")
+			prepared.compile = &models.ExecutionResult{Error: err.Error()}
+			return prepared, nil
 		}
+		repositoryDir = prepared.repositoryDir
+		lang = unitLanguage(lang, tc.Unit)
 	}
 
-	log.WithFields(field).Debug("Worker finished")
+	buildDir, err := getTempDir("build-*")
+	if err != nil {
+		return prepared, fmt.Errorf("failed to create build dir: %v", err)
+	}
+	if err := shareWithSandbox(buildDir, true); err != nil {
+		os.RemoveAll(buildDir)
+		return prepared, fmt.Errorf("failed to prepare build dir: %v", err)
+	}
 
-	return result, nil
+	prepared.program = &Program{
+		Language: lang,
+		File:     filepath.Join(task, file),
+		BuildDir: buildDir,
+		Security: tc.Security,
+	}
+
+	// A missing environment fails every case of the task with a judge error
+	prepared.image, prepared.digest, err = e.runner.Environment(ctx, lang)
+	if err != nil {
+		log.WithField("Task", task).WithError(err).Error("Environment not available")
+		prepared.compile = &models.ExecutionResult{Error: err.Error()}
+		return prepared, nil
+	}
+
+	log.WithFields(log.Fields{
+		"Task":     task,
+		"Language": lang.Name,
+		"File":     file,
+	}).Debug("Compiling solution")
+
+	var compileResult *models.ExecutionResult
+	if tc.Isolated {
+		compileResult, err = e.runner.Compile(ctx, repositoryDir, *prepared.program)
+	} else {
+		// The sandbox of the session gets the resources of the first case, cases with other
+		// resources run in their own sandbox
+		prepared.session, err = e.runner.NewSession(ctx, repositoryDir, *prepared.program, e.runner.EffectiveLimits(lang, tc.Limits))
+		if err != nil {
+			return prepared, fmt.Errorf("failed to start session: %v", err)
+		}
+		compileResult, err = prepared.session.Compile(ctx)
+	}
+	if err != nil {
+		return prepared, fmt.Errorf("failed to compile: %v", err)
+	}
+	if compileResult.CompileError || compileResult.Error != "" {
+		prepared.compile = compileResult
+	}
+
+	return prepared, nil
+}
+
+// copySolution copies the solution of a unit test task to a repository of its own and adds the test files of the
+// instructor to it. Files of the solution with the same name are replaced.
+func copySolution(tc models.TestCase, task string) (string, error) {
+	repositoryDir, err := getTempDir("solution-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create solution dir: %v", err)
+	}
+	dir := filepath.Join(repositoryDir, task)
+	if err := copyDir(filepath.Join(tc.RepositoryDir, task), dir); err != nil {
+		os.RemoveAll(repositoryDir)
+		return "", fmt.Errorf("failed to copy solution: %v", err)
+	}
+
+	err = copyUnitFiles(dir, tc.Unit.Files)
+	if err == nil {
+		err = shareWithSandbox(repositoryDir, false)
+	}
+	if err != nil {
+		os.RemoveAll(repositoryDir)
+		return "", err
+	}
+	return repositoryDir, nil
+}
+
+// verdict determines the status of a single test case from its execution result
+func (e *Executor) verdict(ctx context.Context, tc models.TestCase, execResult *models.ExecutionResult) (status.Status, string) {
+	if tc.Interactor != "" {
+		return interactiveVerdict(tc, execResult)
+	}
+	if s, message, failed := runVerdict(tc, execResult); failed {
+		return s, message
+	}
+
+	c, err := e.newChecker(ctx, tc)
+	if err != nil {
+		return status.StatusError, fmt.Sprintf("invalid checker: %v", err)
+	}
+
+	// Cases which only expect output files do not compare the standard output
+	if tc.Expected != "" || len(tc.ExpectedFiles) == 0 {
+		res := c.Check(tc.Input, tc.Expected, execResult.Output)
+		if res.Status != status.StatusPassed {
+			return res.Status, res.Message
+		}
+	}
+
+	for _, file := range tc.ExpectedFiles {
+		actual, written := execResult.Files[file.Name]
+		if !written {
+			return status.StatusWrongAnswer, fmt.Sprintf("Output file %s was not written", file.Name)
+		}
+		res := c.Check(tc.Input, file.Expected, actual)
+		if res.Status != status.StatusPassed {
+			return res.Status, fmt.Sprintf("%s: %s", file.Name, res.Message)
+		}
+	}
+	return status.StatusPassed, ""
+}
+
+// runVerdict determines the status of a run which failed before its output could be checked
+func runVerdict(tc models.TestCase, execResult *models.ExecutionResult) (status.Status, string, bool) {
+	switch {
+	case execResult.Error != "":
+		return status.StatusError, execResult.Error, true
+	case execResult.ExceededStream != "":
+		limit := tc.Limits.Output
+		if execResult.ExceededStream == "stderr" {
+			limit = tc.Limits.Stderr
+		}
+		return status.StatusOutputLimitExceeded, fmt.Sprintf("Output limit of %d KB exceeded on %s", limit, execResult.ExceededStream), true
+	case execResult.TimedOut:
+		return status.StatusTimeLimitExceeded, fmt.Sprintf("Time limit of %gs exceeded", tc.Limits.WallTime), true
+	case tc.Limits.CPUTime > 0 && execResult.CPUTime.Seconds() > tc.Limits.CPUTime:
+		return status.StatusTimeLimitExceeded, fmt.Sprintf("CPU time limit of %gs exceeded", tc.Limits.CPUTime), true
+	case execResult.OOMKilled:
+		return status.StatusMemoryLimitExceeded, fmt.Sprintf("Memory limit of %d MB exceeded", tc.Limits.Memory), true
+	case execResult.CompileError:
+		return status.StatusCompileError, fmt.Sprintf("Compilation failed: %s", Trim(execResult.Output)), true
+	case execResult.Signal != 0:
+		return status.StatusRuntimeError, fmt.Sprintf("Program was killed by signal %d (%s)", execResult.Signal, syscall.Signal(execResult.Signal)), true
+	case execResult.ExitCode != 0:
+		return status.StatusRuntimeError, fmt.Sprintf("Program exited with code %d", execResult.ExitCode), true
+	}
+	return "", "", false
+}
+
+// solutionPaths returns the directories of the solutions which exist in the repository
+func solutionPaths(repoDir string, solutions []models.Solution) []string {
+	paths := make([]string, 0, len(solutions))
+	for _, solution := range solutions {
+		path := filepath.Join(solution.Workshop, solution.Task)
+		if info, err := os.Stat(filepath.Join(repoDir, path)); err == nil && info.IsDir() {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// uniquePaths removes duplicates from paths while keeping their order
+func uniquePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			unique = append(unique, path)
+		}
+	}
+	return unique
+}
+
+// hasBefore reports whether the submission knows the head of the branch before the push.
+// New branches have no previous head.
+func hasBefore(submission models.Submission) bool {
+	return submission.BeforeCommitID != "" && plumbing.NewHash(submission.BeforeCommitID) != plumbing.ZeroHash
+}
+
+// fetchCommit fetches a single commit without its history if it is not part of the repository yet
+func fetchCommit(ctx context.Context, r *git.Repository, auth transport.AuthMethod, hash string) error {
+	if _, err := r.CommitObject(plumbing.NewHash(hash)); err == nil {
+		return nil
+	}
+
+	err := r.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []gitConfig.RefSpec{gitConfig.RefSpec(hash + ":refs/judge/" + hash)},
+		Depth:    1,
+		Auth:     auth,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+// changedFiles returns the files added or modified by a push. The trees of the previous and the new head are
+// compared, so merges and force pushes are handled as well. If the previous head is unknown or could not be
+// fetched, e.g. after a force push, the files reported by the webhook and the changes of the commit itself are used.
+func changedFiles(r *git.Repository, commit *object.Commit, submission models.Submission) ([]string, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %v", err)
+	}
+
+	if hasBefore(submission) {
+		before, err := r.CommitObject(plumbing.NewHash(submission.BeforeCommitID))
+		if err == nil {
+			beforeTree, err := before.Tree()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get tree of %s: %v", submission.BeforeCommitID, err)
+			}
+			return diffTrees(beforeTree, tree)
+		}
+		log.WithFields(log.Fields{
+			"Repo":   submission.RepoName,
+			"Before": submission.BeforeCommitID,
+		}).WithError(err).Debug("Previous head not found, probably a force push")
+	}
+
+	files := append([]string{}, submission.ChangedFiles...)
+
+	parent, err := commit.Parent(0)
+	if err != nil {
+		if errors.Is(err, object.ErrParentNotFound) {
+			// This is the first commit
+			return files, nil
+		}
+		return nil, fmt.Errorf("failed to get parent commit: %v", err)
+	}
+
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent tree: %v", err)
+	}
+
+	changed, err := diffTrees(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+	return append(files, changed...), nil
+}
+
+// diffTrees returns the files added or modified between two trees. Deleted files are ignored.
+func diffTrees(from, to *object.Tree) ([]string, error) {
+	changes, err := from.Diff(to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changes: %v", err)
+	}
+
+	files := make([]string, 0, len(changes))
+	for _, change := range changes {
+		if change.To.Name == "" {
+			continue
+		}
+		files = append(files, change.To.Name)
+	}
+	return files, nil
 }
@@ -0,0 +1,117 @@
+package judge
+
+import (
+	"context"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// commitFile writes a file to the worktree and commits it
+func commitFile(t *testing.T, r *git.Repository, dir string, file string) plumbing.Hash {
+	w, err := r.Worktree()
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, file)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(file), 0644))
+
+	_, err = w.Add(file)
+	require.NoError(t, err)
+
+	hash, err := w.Commit("add "+file, &git.CommitOptions{
+		Author: &object.Signature{Name: "student", Email: "student@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return hash
+}
+
+func TestChangedFilesOfPush(t *testing.T) {
+	dir := t.TempDir()
+	r, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+
+	before := commitFile(t, r, dir, "workshop1/task1/solution.py")
+	commitFile(t, r, dir, "workshop1/task2/solution.py")
+	commitFile(t, r, dir, "workshop1/task3/solution.py")
+	after := commitFile(t, r, dir, "workshop1/task4/solution.py")
+
+	commit, err := r.CommitObject(after)
+	require.NoError(t, err)
+
+	t.Run("range since previous head", func(t *testing.T) {
+		files, err := changedFiles(r, commit, models.Submission{BeforeCommitID: before.String()})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			"workshop1/task2/solution.py",
+			"workshop1/task3/solution.py",
+			"workshop1/task4/solution.py",
+		}, files)
+	})
+
+	t.Run("unknown previous head", func(t *testing.T) {
+		// e.g. after a force push the previous head is not part of the repository anymore
+		files, err := changedFiles(r, commit, models.Submission{
+			BeforeCommitID: "1111111111111111111111111111111111111111",
+			ChangedFiles:   []string{"workshop1/task3/solution.py"},
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{
+			"workshop1/task3/solution.py",
+			"workshop1/task4/solution.py",
+		}, files)
+	})
+
+	t.Run("new branch", func(t *testing.T) {
+		files, err := changedFiles(r, commit, models.Submission{BeforeCommitID: plumbing.ZeroHash.String()})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"workshop1/task4/solution.py"}, files)
+	})
+}
+
+func TestFetchPreviousHead(t *testing.T) {
+	origin := t.TempDir()
+	r, err := git.PlainInit(origin, false)
+	require.NoError(t, err)
+	cfg, err := r.Config()
+	require.NoError(t, err)
+	cfg.Raw.Section("uploadpack").SetOption("allowReachableSHA1InWant", "true")
+	require.NoError(t, r.SetConfig(cfg))
+
+	before := commitFile(t, r, origin, "workshop1/task1/solution.py")
+	between := commitFile(t, r, origin, "workshop1/task2/solution.py")
+	commitFile(t, r, origin, "workshop1/task3/solution.py")
+	after := commitFile(t, r, origin, "workshop1/task4/solution.py")
+
+	clone, err := git.PlainClone(t.TempDir(), false, &git.CloneOptions{URL: "file://" + origin, Depth: 2})
+	require.NoError(t, err)
+	_, err = clone.CommitObject(before)
+	require.Error(t, err, "the shallow clone does not contain the previous head")
+
+	require.NoError(t, fetchCommit(context.Background(), clone, nil, before.String()))
+	require.NoError(t, fetchCommit(context.Background(), clone, nil, before.String()))
+
+	commit, err := clone.CommitObject(after)
+	require.NoError(t, err)
+	files, err := changedFiles(clone, commit, models.Submission{BeforeCommitID: before.String()})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"workshop1/task2/solution.py",
+		"workshop1/task3/solution.py",
+		"workshop1/task4/solution.py",
+	}, files)
+
+	// The history in between is not fetched
+	_, err = clone.CommitObject(between)
+	assert.Error(t, err)
+
+	// e.g. after a force push the previous head is gone
+	assert.Error(t, fetchCommit(context.Background(), clone, nil, "1111111111111111111111111111111111111111"))
+}
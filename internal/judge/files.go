@@ -0,0 +1,151 @@
+package judge
+
+import (
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// workDirName is the name of the working directory of cases with fixtures or expected output files
+const workDirName = "work"
+
+// caseEnv returns the environment variables of the program of a test case
+func caseEnv(testCase models.TestCase) []string {
+	env := []string{
+		fmt.Sprintf("JUDGE_WORKSHOP=%s", testCase.Solution.Workshop),
+		fmt.Sprintf("JUDGE_TASK=%s", testCase.Solution.Task),
+	}
+
+	names := make([]string, 0, len(testCase.Env))
+	for name := range testCase.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+testCase.Env[name])
+	}
+	return env
+}
+
+// caseCommand appends the command-line arguments of a test case to the run command of a program
+func caseCommand(command string, args []string) string {
+	for _, arg := range args {
+		command += " " + shellQuote(arg)
+	}
+	return command
+}
+
+// shellQuote quotes a string as a single word for /bin/sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// copyDir copies a directory of a solution. Symbolic links are copied as links, other special files are skipped.
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// prepareWorkDir creates the working directory of a test case, which the sandbox can write, and copies its fixtures
+// into it.
+func prepareWorkDir(testCase models.TestCase) (string, error) {
+	workDir, err := getTempDir("work-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create work dir: %v", err)
+	}
+	if err := shareWithSandbox(workDir, true); err != nil {
+		os.RemoveAll(workDir)
+		return "", fmt.Errorf("failed to prepare work dir: %v", err)
+	}
+
+	for _, file := range testCase.Files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			os.RemoveAll(workDir)
+			return "", fmt.Errorf("failed to read fixture: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(workDir, filepath.Base(file)), data, 0644); err != nil {
+			os.RemoveAll(workDir)
+			return "", fmt.Errorf("failed to write fixture: %v", err)
+		}
+	}
+	return workDir, nil
+}
+
+// readOutputFiles reads the expected output files of a test case from the working directory once the program
+// exited. Missing files and files which are not regular, e.g. symlinks created by the program, are left out.
+// A file larger than the output limit is reported as exceeded stream.
+func readOutputFiles(workDir string, testCase models.TestCase, limits models.Limits, result *models.ExecutionResult) error {
+	if len(testCase.ExpectedFiles) == 0 || result.TimedOut || result.ExceededStream != "" {
+		return nil
+	}
+
+	result.Files = make(map[string]string)
+	for _, file := range testCase.ExpectedFiles {
+		path := filepath.Join(workDir, file.Name)
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open output file %s: %v", file.Name, err)
+		}
+		var r io.Reader = f
+		if limits.Output > 0 {
+			r = io.LimitReader(f, limits.Output*1024+1)
+		}
+		data, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read output file %s: %v", file.Name, err)
+		}
+		if limits.Output > 0 && int64(len(data)) > limits.Output*1024 {
+			result.ExceededStream = file.Name
+			return nil
+		}
+		result.Files[file.Name] = string(data)
+	}
+	return nil
+}
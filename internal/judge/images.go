@@ -0,0 +1,140 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ImageError is returned if an image can neither be found nor pulled
+type ImageError struct {
+	Image string
+	Err   error
+}
+
+func (e *ImageError) Error() string {
+	return fmt.Sprintf("image %s is not available: %v", e.Image, e.Err)
+}
+
+func (e *ImageError) Unwrap() error {
+	return e.Err
+}
+
+// ImageManager makes sure the images of the sandbox exist and caches their digests, so the docker daemon is
+// only asked once per image
+type ImageManager struct {
+	cli    *client.Client
+	mu     sync.Mutex
+	images map[string]*imageState
+}
+
+// imageState is the cached state of a single image
+type imageState struct {
+	mu     sync.Mutex // Held while the image is checked or pulled
+	digest string
+}
+
+func NewImageManager(cli *client.Client) *ImageManager {
+	return &ImageManager{
+		cli:    cli,
+		images: make(map[string]*imageState),
+	}
+}
+
+// Ensure returns the digest of the image and pulls it if it does not exist locally
+func (m *ImageManager) Ensure(ctx context.Context, name string) (string, error) {
+	m.mu.Lock()
+	state, exists := m.images[name]
+	if !exists {
+		state = &imageState{}
+		m.images[name] = state
+	}
+	m.mu.Unlock()
+
+	// Concurrent judges wait for a running pull instead of pulling the image again
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.digest != "" {
+		return state.digest, nil
+	}
+
+	imageFields := log.Fields{
+		"Image": name,
+	}
+
+	digest, err := m.inspect(ctx, name)
+	if client.IsErrNotFound(err) {
+		log.WithFields(imageFields).Warn("Image not found locally, pulling...")
+		if err := m.pull(ctx, name); err != nil {
+			return "", &ImageError{Image: name, Err: err}
+		}
+		log.WithFields(imageFields).Info("Successfully pulled")
+
+		digest, err = m.inspect(ctx, name)
+	}
+	if err != nil {
+		return "", &ImageError{Image: name, Err: err}
+	}
+
+	log.WithFields(imageFields).WithField("Digest", digest).Debug("Image available")
+	state.digest = digest
+	return digest, nil
+}
+
+// Forget removes an image from the cache, it is checked again on next use
+func (m *ImageManager) Forget(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.images, name)
+}
+
+// Sync checks and pulls all images. Every image is tried, the failures are returned together.
+func (m *ImageManager) Sync(ctx context.Context, names []string) error {
+	var errs []error
+	for _, name := range names {
+		m.Forget(name)
+		if _, err := m.Ensure(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// inspect returns the digest of a local image. Images without registry digest, e.g. built locally, are
+// identified by their ID.
+func (m *ImageManager) inspect(ctx context.Context, name string) (string, error) {
+	inspect, _, err := m.cli.ImageInspectWithRaw(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	for _, repoDigest := range inspect.RepoDigests {
+		if _, digest, found := strings.Cut(repoDigest, "@"); found {
+			return digest, nil
+		}
+	}
+	return inspect.ID, nil
+}
+
+// pull pulls an image and waits until the pull completed
+func (m *ImageManager) pull(ctx context.Context, name string) error {
+	reader, err := m.cli.ImagePull(ctx, name, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %v", err)
+	}
+	defer reader.Close()
+
+	// Errors of the pull are reported in the progress stream
+	if err := jsonmessage.DisplayJSONMessagesStream(reader, io.Discard, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to pull image: %v", err)
+	}
+	return nil
+}
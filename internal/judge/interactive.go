@@ -0,0 +1,248 @@
+package judge
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// interactorMarker starts the verdict of the interactor which is appended to the transcript
+const interactorMarker = "\n@@judge-interactor@@ "
+
+// Exit codes of an interactor, every other code is a failure of the interactor
+const (
+	interactorAccepted          = 0
+	interactorWrongAnswer       = 1
+	interactorPresentationError = 2
+)
+
+// transcriptBytes limits the transcript kept for a case
+const transcriptBytes = 16 * 1024
+
+// interactorMessageBytes limits the message of the interactor
+const interactorMessageBytes = 4096
+
+// writeInteractor writes the interactor of a case and its input to a directory and returns the command running it.
+// The directory is visible to the interactor as dir and must not be visible to the solution.
+func writeInteractor(tmpDir string, dir string, interactor string, input string) (string, error) {
+	if err := os.WriteFile(filepath.Join(tmpDir, "input.txt"), []byte(input), 0644); err != nil {
+		return "", fmt.Errorf("failed to write input: %v", err)
+	}
+
+	data, err := os.ReadFile(interactor)
+	if err != nil {
+		return "", fmt.Errorf("failed to read interactor: %v", err)
+	}
+	name := "interactor" + filepath.Ext(interactor)
+	if err := os.WriteFile(filepath.Join(tmpDir, name), data, 0755); err != nil {
+		return "", fmt.Errorf("failed to write interactor: %v", err)
+	}
+
+	command := path.Join(dir, name) + " " + path.Join(dir, "input.txt")
+	switch filepath.Ext(interactor) {
+	case ".py":
+		return "python3 " + command, nil
+	case ".sh":
+		return "sh " + command, nil
+	}
+	return command, nil
+}
+
+// interactiveRun runs the solution or the interactor of a case with the given stdin and stdout
+type interactiveRun func(stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error)
+
+// interact runs the solution and the interactor of a case at the same time, each in a sandbox of its own. The
+// stdout of one is the stdin of the other. The judge passes their output on and records every line in the
+// transcript, so neither can change the transcript or the verdict of the other. The returned result is the one of
+// the solution, its output is the transcript followed by the exit code and the error output of the interactor.
+func interact(solution interactiveRun, interactor interactiveRun) (*models.ExecutionResult, error) {
+	interactorIn, solutionOut, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe: %v", err)
+	}
+	solutionIn, interactorOut, err := os.Pipe()
+	if err != nil {
+		interactorIn.Close()
+		solutionOut.Close()
+		return nil, fmt.Errorf("failed to create pipe: %v", err)
+	}
+
+	t := &transcript{}
+	var interactorResult *models.ExecutionResult
+	var interactorErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		out := t.relay("<", interactorOut)
+		interactorResult, interactorErr = interactor(interactorIn, out)
+		out.flush()
+		// The solution reads the end of its input once the interactor exited
+		interactorIn.Close()
+		interactorOut.Close()
+	}()
+
+	out := t.relay(">", solutionOut)
+	result, err := solution(solutionIn, out)
+	out.flush()
+	// The interactor only reads the end of its input below, so it exited on its own if it is done already
+	interactorFirst := false
+	select {
+	case <-done:
+		interactorFirst = true
+	default:
+	}
+	solutionIn.Close()
+	solutionOut.Close()
+	<-done
+
+	if err != nil {
+		return nil, err
+	}
+	if interactorErr != nil {
+		return nil, fmt.Errorf("failed to run interactor: %v", interactorErr)
+	}
+
+	result.Output = t.String()
+	switch {
+	case interactorResult.Error != "":
+		result.Error = fmt.Sprintf("interactor failed: %s", interactorResult.Error)
+	case result.TimedOut && !interactorFirst:
+		// The interactor only stopped because the solution was killed, its verdict is not the cause
+	case !interactorResult.TimedOut && interactorResult.ExceededStream == "":
+		message := interactorResult.Stderr
+		if len(message) > interactorMessageBytes {
+			message = message[:interactorMessageBytes]
+		}
+		result.Output += fmt.Sprintf("%s%d\n%s", interactorMarker, interactorResult.ExitCode, message)
+	}
+	return result, nil
+}
+
+// transcript records the lines passed between the solution and the interactor up to transcriptBytes
+type transcript struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (t *transcript) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.buf.String()
+}
+
+// add records a line with the prefix of its side
+func (t *transcript) add(prefix string, line []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := prefix + " " + string(line) + "\n"
+	if remaining := transcriptBytes - t.buf.Len(); len(entry) > remaining {
+		entry = entry[:max(remaining, 0)]
+	}
+	t.buf.WriteString(entry)
+}
+
+// relay returns a writer which passes the output of one side to the other and records it in the transcript
+func (t *transcript) relay(prefix string, dst io.Writer) *relayWriter {
+	return &relayWriter{transcript: t, prefix: prefix, dst: dst}
+}
+
+// relayWriter passes output on to the other side. Once the other side exited, further output is discarded, so the
+// writing side does not block on a full pipe.
+type relayWriter struct {
+	transcript *transcript
+	prefix     string
+	dst        io.Writer
+	line       []byte // Incomplete last line
+	closed     bool
+}
+
+func (w *relayWriter) Write(p []byte) (int, error) {
+	for data := p; len(data) > 0; {
+		line, rest, complete := bytes.Cut(data, []byte("\n"))
+		if len(w.line) < transcriptBytes {
+			w.line = append(w.line, line[:min(len(line), transcriptBytes-len(w.line))]...)
+		}
+		if !complete {
+			break
+		}
+		w.transcript.add(w.prefix, w.line)
+		w.line = w.line[:0]
+		data = rest
+	}
+
+	if !w.closed {
+		if _, err := w.dst.Write(p); err != nil {
+			w.closed = true
+		}
+	}
+	return len(p), nil
+}
+
+// flush records an incomplete last line
+func (w *relayWriter) flush() {
+	if len(w.line) > 0 {
+		w.transcript.add(w.prefix, w.line)
+		w.line = nil
+	}
+}
+
+// interaction is the outcome of an interactive run as reported by the wrapper
+type interaction struct {
+	Transcript string
+	ExitCode   int
+	Message    string
+}
+
+// parseInteraction reads the transcript and the verdict of the interactor from the output of an interactive run.
+// It returns false if the interactor did not finish, e.g. because it exceeded its time limit.
+func parseInteraction(output string) (interaction, bool) {
+	i := strings.LastIndex(output, interactorMarker)
+	if i < 0 {
+		return interaction{}, false
+	}
+
+	code, message, _ := strings.Cut(output[i+len(interactorMarker):], "\n")
+	exitCode, err := strconv.Atoi(code)
+	if err != nil {
+		return interaction{}, false
+	}
+	return interaction{
+		Transcript: strings.TrimRight(output[:i], "\n"),
+		ExitCode:   exitCode,
+		Message:    Trim(message),
+	}, true
+}
+
+// interactiveVerdict determines the status of an interactive case. A wrong answer of the interactor takes
+// precedence, as the solution usually fails once the interactor stopped talking to it.
+func interactiveVerdict(tc models.TestCase, execResult *models.ExecutionResult) (status.Status, string) {
+	result, finished := parseInteraction(execResult.Output)
+	if execResult.Error == "" && finished {
+		switch result.ExitCode {
+		case interactorWrongAnswer:
+			return status.StatusWrongAnswer, result.Message
+		case interactorPresentationError:
+			return status.StatusPresentationError, result.Message
+		}
+	}
+
+	if s, message, failed := runVerdict(tc, execResult); failed {
+		return s, message
+	}
+
+	switch {
+	case !finished:
+		return status.StatusError, "Interactor did not report a verdict"
+	case result.ExitCode != interactorAccepted:
+		return status.StatusError, fmt.Sprintf("Interactor failed with exit code %d: %s", result.ExitCode, result.Message)
+	}
+	return status.StatusPassed, ""
+}
@@ -0,0 +1,163 @@
+package language
+
+import (
+	_ "embed"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+//go:embed languages.yaml
+var defaultLanguages []byte
+
+// Registry holds all languages solutions can be written in
+type Registry struct {
+	Languages []models.Language `yaml:"languages"`
+}
+
+// Load reads the registry from a YAML file. The builtin languages are used if path is empty.
+func Load(path string) (*Registry, error) {
+	data := defaultLanguages
+	if path != "" {
+		var err error
+		data, err = os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read languages: %v", err)
+		}
+	}
+
+	return Parse(data)
+}
+
+// Parse parses and validates a registry
+func Parse(data []byte) (*Registry, error) {
+	var r Registry
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse languages: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, l := range r.Languages {
+		switch {
+		case l.Name == "":
+			return nil, fmt.Errorf("language without name")
+		case names[l.Name]:
+			return nil, fmt.Errorf("language %s defined twice", l.Name)
+		case len(l.Patterns) == 0:
+			return nil, fmt.Errorf("language %s has no patterns", l.Name)
+		case l.Run == "":
+			return nil, fmt.Errorf("language %s has no run command", l.Name)
+		}
+		for _, pattern := range l.Patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("language %s has invalid pattern %s: %v", l.Name, pattern, err)
+			}
+		}
+		names[l.Name] = true
+	}
+
+	return &r, nil
+}
+
+// Get returns the language with the name or nil
+func (r *Registry) Get(name string) *models.Language {
+	for i := range r.Languages {
+		if r.Languages[i].Name == name {
+			return &r.Languages[i]
+		}
+	}
+	return nil
+}
+
+// Images returns the images of all languages. Languages without image use defaultImage.
+func (r *Registry) Images(defaultImage string) []string {
+	images := make([]string, 0)
+	for _, l := range r.Languages {
+		image := l.Image
+		if image == "" {
+			image = defaultImage
+		}
+		if !slices.Contains(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// Detect finds the solution file in dir and returns its language and file name. A task accepts every
+// language if allowed is empty. Exactly one solution file must exist.
+func (r *Registry) Detect(dir string, allowed []string) (*models.Language, string, error) {
+	for _, name := range allowed {
+		if r.Get(name) == nil {
+			return nil, "", fmt.Errorf("task accepts unknown language %s", name)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read solution directory: %v", err)
+	}
+
+	type match struct {
+		language *models.Language
+		file     string
+	}
+	matches := make([]match, 0)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if l := r.match(entry.Name()); l != nil {
+			matches = append(matches, match{language: l, file: entry.Name()})
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, "", fmt.Errorf("no solution file found, expected one of: %s", strings.Join(r.patterns(allowed), ", "))
+	}
+
+	if len(matches) > 1 {
+		files := make([]string, len(matches))
+		for i, m := range matches {
+			files[i] = m.file
+		}
+		sort.Strings(files)
+		return nil, "", fmt.Errorf("multiple solution files found (%s), only one solution per task is allowed", strings.Join(files, ", "))
+	}
+
+	found := matches[0]
+	if len(allowed) > 0 && !slices.Contains(allowed, found.language.Name) {
+		return nil, "", fmt.Errorf("%s is not accepted for this task, use one of: %s", found.language.Name, strings.Join(allowed, ", "))
+	}
+
+	return found.language, found.file, nil
+}
+
+// match returns the language of a solution file or nil
+func (r *Registry) match(file string) *models.Language {
+	for i, l := range r.Languages {
+		for _, pattern := range l.Patterns {
+			if ok, _ := filepath.Match(pattern, file); ok {
+				return &r.Languages[i]
+			}
+		}
+	}
+	return nil
+}
+
+// patterns returns the solution file patterns of the allowed languages
+func (r *Registry) patterns(allowed []string) []string {
+	patterns := make([]string, 0)
+	for _, l := range r.Languages {
+		if len(allowed) == 0 || slices.Contains(allowed, l.Name) {
+			patterns = append(patterns, l.Patterns...)
+		}
+	}
+	return patterns
+}
@@ -0,0 +1,93 @@
+package language_test
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/language"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func solutionDir(t *testing.T, files ...string) string {
+	dir := t.TempDir()
+	for _, file := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte{}, 0644))
+	}
+	return dir
+}
+
+func TestLoadDefault(t *testing.T) {
+	registry, err := language.Load("")
+	require.NoError(t, err)
+
+	for _, name := range []string{"python", "go", "c", "cpp", "java", "rust", "javascript", "sql"} {
+		assert.NotNil(t, registry.Get(name), name)
+	}
+}
+
+func TestImages(t *testing.T) {
+	registry, err := language.Parse([]byte(`
+languages:
+  - name: python
+    patterns: ["solution.py"]
+    run: python3 {file}
+  - name: go
+    patterns: ["solution.go"]
+    run: go run {file}
+  - name: c
+    patterns: ["solution.c"]
+    image: gcc:14
+    run: "{build}/solution"
+  - name: cpp
+    patterns: ["solution.cpp"]
+    image: gcc:14
+    run: "{build}/solution"
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"judge:latest", "gcc:14"}, registry.Images("judge:latest"))
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := language.Parse([]byte("languages:\n  - name: python\n    patterns: [\"solution.py\"]\n"))
+	assert.ErrorContains(t, err, "no run command")
+
+	_, err = language.Parse([]byte("languages:\n  - name: python\n    run: python3 {file}\n"))
+	assert.ErrorContains(t, err, "no patterns")
+}
+
+func TestDetect(t *testing.T) {
+	registry, err := language.Load("")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		files    []string
+		allowed  []string
+		language string
+		file     string
+		err      string
+	}{
+		{name: "python", files: []string{"solution.py", "README.md"}, language: "python", file: "solution.py"},
+		{name: "java", files: []string{"Solution.java"}, language: "java", file: "Solution.java"},
+		{name: "allowed", files: []string{"solution.go"}, allowed: []string{"go", "c"}, language: "go", file: "solution.go"},
+		{name: "not allowed", files: []string{"solution.py"}, allowed: []string{"go"}, err: "python is not accepted"},
+		{name: "multiple", files: []string{"solution.py", "solution.go"}, err: "multiple solution files found (solution.go, solution.py)"},
+		{name: "none", files: []string{"main.py"}, allowed: []string{"python"}, err: "expected one of: solution.py"},
+		{name: "unknown language", files: []string{"solution.py"}, allowed: []string{"cobol"}, err: "unknown language cobol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, file, err := registry.Detect(solutionDir(t, tt.files...), tt.allowed)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.language, l.Name)
+			assert.Equal(t, tt.file, file)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package judge
+
+import (
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsMarker starts the line the measurement wrapper appends to stderr
+const statsMarker = "\n@@judge-stats@@ "
+
+// statsReserve is the stderr in KB reserved for the measurements, so they do not count towards the stderr limit
+const statsReserve = 1
+
+// timesPattern matches a duration printed by the times builtin of the shell, e.g. 0m1.250000s
+var timesPattern = regexp.MustCompile(`(\d+)m([\d.]+)s`)
+
+// measureCommand wraps the run command of a program. The wrapper measures the wall and CPU time of the program
+// inside the sandbox and appends them together with the peak memory of the container cgroup before and after the
+// program to stderr. A CPU time limit is enforced with ulimit, the verdict is based on the measured time.
+func measureCommand(command string, limits models.Limits) string {
+	ulimit := cpuTimeUlimit(limits)
+
+	// The peak of a cgroup v1 is reset if the cgroup is writable. Otherwise the peak also covers the compilation and
+	// earlier cases of a session. Builtins read the peak, so the wrapper adds no child processes to the CPU time.
+	return fmt.Sprintf(`peak=/sys/fs/cgroup/memory.peak
+if [ ! -r $peak ]; then
+	peak=/sys/fs/cgroup/memory/memory.max_usage_in_bytes
+	{ echo 0 > $peak; } 2>/dev/null
+fi
+{ read before < $peak; } 2>/dev/null
+read start _ < /proc/uptime
+(%s%s)
+status=$?
+read end _ < /proc/uptime
+{ read after < $peak; } 2>/dev/null
+printf '%s%%s %%s %%s %%s\n' "$start" "$end" "${before:-0}" "${after:-0}" >&2
+times >&2
+exit $status`, ulimit, command, strings.ReplaceAll(statsMarker, "\n", `\n`))
+}
+
+// cpuTimeUlimit returns the ulimit builtin enforcing the CPU time limit, empty without a limit
+func cpuTimeUlimit(limits models.Limits) string {
+	if limits.CPUTime <= 0 {
+		return ""
+	}
+	// One extra second, so the program is killed only after it exceeded the limit
+	return fmt.Sprintf("ulimit -t %d; ", int64(math.Ceil(limits.CPUTime))+1)
+}
+
+// measureLimits returns the limits of a measured program with the stderr reserved for the measurements
+func measureLimits(limits models.Limits) models.Limits {
+	if limits.Stderr > 0 {
+		limits.Stderr += statsReserve
+	}
+	return limits
+}
+
+// parseStats removes the measurements of the wrapper from stderr and stores them in the result. The last
+// marker is used, so a program cannot fake its measurements by printing a marker itself. The stderr of the program
+// is checked against the limit without the reserve of the measurements.
+func parseStats(result *models.ExecutionResult, limits models.Limits) {
+	defer func() {
+		if limit := int(limits.Stderr * 1024); limit > 0 && len(result.Stderr) > limit {
+			result.Stderr = result.Stderr[:limit]
+			if result.ExceededStream == "" {
+				result.ExceededStream = "stderr"
+			}
+		}
+	}()
+
+	i := strings.LastIndex(result.Stderr, statsMarker)
+	if i < 0 {
+		return
+	}
+	lines := strings.Split(result.Stderr[i+len(statsMarker):], "\n")
+	result.Stderr = result.Stderr[:i]
+
+	var start, end float64
+	var before, after int64
+	if _, err := fmt.Sscanf(lines[0], "%g %g %d %d", &start, &end, &before, &after); err != nil {
+		return
+	}
+	result.ExecutionTime = time.Duration((end - start) * float64(time.Second))
+	// The peak is only known to belong to the program if the program raised it. Otherwise it may be the peak of the
+	// compilation or an earlier case, which would hide how much memory the program used.
+	result.MemoryPeak = models.MemoryUnknown
+	if after > before {
+		result.MemoryPeak = after
+	}
+
+	// times prints the times of the shell followed by the user and system time of its children
+	if len(lines) < 3 {
+		return
+	}
+	for _, match := range timesPattern.FindAllStringSubmatch(lines[2], 2) {
+		minutes, _ := strconv.ParseFloat(match[1], 64)
+		seconds, _ := strconv.ParseFloat(match[2], 64)
+		result.CPUTime += time.Duration((minutes*60 + seconds) * float64(time.Second))
+	}
+}
@@ -0,0 +1,66 @@
+package judge
+
+import (
+	"bytes"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStats(t *testing.T) {
+	result := models.ExecutionResult{
+		Stderr: "debug\n@@judge-stats@@ 1.00 1.00 0 0\n" + statsMarker + "100.25 101.75 1048576 10485760\n0m0.01s 0m0.00s\n1m2.500000s 0m0.250000s\n",
+	}
+	parseStats(&result, models.Limits{})
+
+	assert.Equal(t, "debug\n@@judge-stats@@ 1.00 1.00 0 0\n", result.Stderr)
+	assert.Equal(t, 1500*time.Millisecond, result.ExecutionTime)
+	assert.Equal(t, 62750*time.Millisecond, result.CPUTime)
+	assert.Equal(t, int64(10485760), result.MemoryPeak)
+
+	t.Run("peak of an earlier command", func(t *testing.T) {
+		result := models.ExecutionResult{Stderr: statsMarker + "1.00 2.00 10485760 10485760\n"}
+		parseStats(&result, models.Limits{})
+		assert.Equal(t, models.MemoryUnknown, result.MemoryPeak)
+	})
+
+	t.Run("stderr limit", func(t *testing.T) {
+		stats := statsMarker + "1.00 2.00 0 0\n0m0.00s 0m0.00s\n0m0.00s 0m0.00s\n"
+
+		result := models.ExecutionResult{Stderr: strings.Repeat("x", 1024) + stats}
+		parseStats(&result, models.Limits{Stderr: 1})
+		assert.Len(t, result.Stderr, 1024)
+		assert.Empty(t, result.ExceededStream, "the measurements do not count towards the limit")
+
+		result = models.ExecutionResult{Stderr: strings.Repeat("x", 1025) + stats}
+		parseStats(&result, models.Limits{Stderr: 1})
+		assert.Len(t, result.Stderr, 1024)
+		assert.Equal(t, "stderr", result.ExceededStream)
+	})
+}
+
+func TestMeasureCommand(t *testing.T) {
+	if _, err := os.Stat("/proc/uptime"); err != nil {
+		t.Skip("requires /proc/uptime")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("/bin/sh", "-c", measureCommand("echo 42; echo oops >&2; exit 3", models.Limits{CPUTime: 1}))
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+
+	result := models.ExecutionResult{Output: stdout.String(), Stderr: stderr.String()}
+	parseStats(&result, models.Limits{})
+	assert.Equal(t, "42\n", result.Output)
+	assert.Equal(t, "oops\n", result.Stderr)
+	assert.GreaterOrEqual(t, result.ExecutionTime, time.Duration(0))
+}
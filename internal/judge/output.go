@@ -0,0 +1,91 @@
+package judge
+
+import (
+	"bytes"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"strings"
+	"sync"
+)
+
+// Size of the stderr excerpt shown for public test cases
+const (
+	stderrExcerptBytes = 2048
+	stderrExcerptLines = 20
+)
+
+// cappedBuffer stores a stream up to its limit. Further output is discarded, so the program does not block
+// on a full pipe before it is killed.
+type cappedBuffer struct {
+	name     string
+	buf      bytes.Buffer
+	limit    int64 // Limit in bytes, 0 means no limit
+	exceeded bool
+	onExceed func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && int64(b.buf.Len()+len(p)) > b.limit {
+		b.buf.Write(p[:b.limit-int64(b.buf.Len())])
+		if !b.exceeded {
+			b.exceeded = true
+			b.onExceed()
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// streams captures stdout and stderr of a program separately, each up to its own limit
+type streams struct {
+	stdout   *cappedBuffer
+	stderr   *cappedBuffer
+	exceeded chan struct{} // Closed once a stream exceeded its limit
+	once     sync.Once
+}
+
+func newStreams(limits models.Limits) *streams {
+	s := &streams{
+		exceeded: make(chan struct{}),
+	}
+	s.stdout = &cappedBuffer{name: "stdout", limit: limits.Output * 1024, onExceed: s.exceed}
+	s.stderr = &cappedBuffer{name: "stderr", limit: limits.Stderr * 1024, onExceed: s.exceed}
+	return s
+}
+
+func (s *streams) exceed() {
+	s.once.Do(func() {
+		close(s.exceeded)
+	})
+}
+
+// collect stores the captured output in the result. It must only be called after the streams were copied.
+func (s *streams) collect(result *models.ExecutionResult) {
+	result.Output = s.stdout.buf.String()
+	result.Stderr = s.stderr.buf.String()
+	for _, b := range []*cappedBuffer{s.stdout, s.stderr} {
+		if b.exceeded {
+			result.ExceededStream = b.name
+			break
+		}
+	}
+}
+
+// stderrExcerpt returns the beginning of the error output of a program
+func stderrExcerpt(stderr string) string {
+	excerpt := strings.TrimRight(stderr, "\n")
+	truncated := false
+
+	if len(excerpt) > stderrExcerptBytes {
+		excerpt = strings.ToValidUTF8(excerpt[:stderrExcerptBytes], "")
+		truncated = true
+	}
+	if lines := strings.SplitN(excerpt, "\n", stderrExcerptLines+1); len(lines) > stderrExcerptLines {
+		excerpt = strings.Join(lines[:stderrExcerptLines], "\n")
+		truncated = true
+	}
+
+	if truncated {
+		excerpt += "\n..."
+	}
+	return excerpt
+}
@@ -0,0 +1,62 @@
+package judge
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestStreamsSeparate(t *testing.T) {
+	s := newStreams(models.Limits{})
+	s.stdout.Write([]byte("42\n"))
+	s.stderr.Write([]byte("debug\n"))
+
+	var result models.ExecutionResult
+	s.collect(&result)
+
+	assert.Equal(t, "42\n", result.Output)
+	assert.Equal(t, "debug\n", result.Stderr)
+	assert.Empty(t, result.ExceededStream)
+}
+
+func TestStreamsLimit(t *testing.T) {
+	s := newStreams(models.Limits{Output: 1, Stderr: 1})
+
+	n, err := s.stderr.Write([]byte(strings.Repeat("x", 1000)))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, n)
+
+	select {
+	case <-s.exceeded:
+		t.Fatal("Limit exceeded too early")
+	default:
+	}
+
+	// Output beyond the limit is discarded without blocking the program
+	n, err = s.stderr.Write([]byte(strings.Repeat("x", 1000)))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, n)
+	s.stderr.Write([]byte("more"))
+
+	select {
+	case <-s.exceeded:
+	default:
+		t.Fatal("Expected exceeded limit")
+	}
+
+	var result models.ExecutionResult
+	s.collect(&result)
+	assert.Equal(t, "stderr", result.ExceededStream)
+	assert.Len(t, result.Stderr, 1024)
+}
+
+func TestStderrExcerpt(t *testing.T) {
+	assert.Equal(t, "Traceback", stderrExcerpt("Traceback\n"))
+
+	lines := strings.Repeat("line\n", 30)
+	assert.Equal(t, strings.Repeat("line\n", stderrExcerptLines)+"...", stderrExcerpt(lines))
+
+	long := strings.Repeat("x", stderrExcerptBytes+10)
+	assert.Equal(t, strings.Repeat("x", stderrExcerptBytes)+"\n...", stderrExcerpt(long))
+}
@@ -1,46 +1,106 @@
 package judge
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/dgraph-io/badger/v4"
+	"github.com/google/uuid"
 	appConfig "github.com/gurkengewuerz/GitCodeJudge/internal/config"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/db"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/gitea"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/scoreboard"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
 	log "github.com/sirupsen/logrus"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
+// maxJobAttempts is the number of times a job interrupted by a restart is started before it is given up
+const maxJobAttempts = 2
+
+// PoolConfig configures the workers and the queue of a Pool
+type PoolConfig struct {
+	MaxWorkers int
+	QueueSize  int
+
+	// MaxRunningPerUser limits the submissions of a single user which are judged at the same time. 0 means unlimited.
+	MaxRunningPerUser int
+	// DeadlineWindow gives submissions a high priority if a task ends within the window. 0 disables it.
+	DeadlineWindow time.Duration
+
+	// SupersedeQueued drops queued submissions of a repository branch when a newer push arrives
+	SupersedeQueued bool
+	// CancelSuperseded also cancels the running submission of the repository branch
+	CancelSuperseded bool
+
+	// WorkerTimeout is the time after which a remote worker which did not send a heartbeat for its job is
+	// considered dead and the job is requeued. 0 disables the watchdog.
+	WorkerTimeout time.Duration
+}
+
+// runningJob is a job currently processed by a worker
+type runningJob struct {
+	job       models.Job
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	worker    string    // Name of the remote worker, empty for in-process workers
+	heartbeat time.Time // Last sign of life of the remote worker
+}
+
+// errSuperseded is the cancel cause of running jobs which were superseded by a newer push
+type errSuperseded struct {
+	commitID string
+}
+
+func (e *errSuperseded) Error() string {
+	return fmt.Sprintf("superseded by %s", e.commitID)
+}
+
 type Pool struct {
 	executor          *Executor
-	maxWorkers        int
-	workers           chan struct{}
-	submissions       chan models.Submission
+	config            PoolConfig
+	queue             *jobQueue
+	store             *queueStore
 	wg                sync.WaitGroup
 	scoreboardManager *scoreboard.ScoreboardManager
+	configs           *taskConfigCache // Configurations of the tasks, used to find their deadlines
+
+	runningMu sync.Mutex
+	running   map[string]runningJob
+	workers   map[string]*models.WorkerStatus // Remote workers seen since the start
+
+	stop chan struct{}
 }
 
-func NewPool(executor *Executor, scoreboardManager *scoreboard.ScoreboardManager, maxWorkers int) *Pool {
+func NewPool(executor *Executor, scoreboardManager *scoreboard.ScoreboardManager, config PoolConfig) *Pool {
 	log.Info("New pool created")
 
 	p := &Pool{
 		executor:          executor,
-		maxWorkers:        maxWorkers,
-		workers:           make(chan struct{}, maxWorkers),
-		submissions:       make(chan models.Submission, 1000), // Buffer for pending submissions
+		config:            config,
+		queue:             newJobQueue(config.QueueSize, config.MaxRunningPerUser),
+		store:             &queueStore{db: db.DB},
 		scoreboardManager: scoreboardManager,
+		configs:           newTaskConfigCache(executor.testCaseDir),
+		running:           make(map[string]runningJob),
+		workers:           make(map[string]*models.WorkerStatus),
+		stop:              make(chan struct{}),
 	}
 
 	// Start worker pool
 	go p.start()
+	if config.WorkerTimeout > 0 {
+		go p.watchWorkers()
+	}
 	return p
 }
 
 func (p *Pool) start() {
-	for i := 0; i < p.maxWorkers; i++ {
+	for i := 0; i < p.config.MaxWorkers; i++ {
 		p.wg.Add(1)
 		go p.worker()
 	}
@@ -49,82 +109,450 @@ func (p *Pool) start() {
 func (p *Pool) worker() {
 	defer p.wg.Done()
 
-	for submission := range p.submissions {
-		fields := log.Fields{
-			"Repo":   submission.RepoName,
-			"Commit": submission.CommitID,
+	for {
+		job, ok := p.queue.pop()
+		if !ok {
+			return
 		}
 
-		// Extract owner and repo from full repository name
-		parts := strings.Split(submission.RepoName, "/")
-		if len(parts) != 2 {
-			log.WithFields(fields).Error("invalid repository name format")
-			continue
+		job, ctx := p.markRunning(job, "")
+		if p.begin(job.Submission) {
+			result, err := p.executor.Execute(ctx, job.Submission)
+			p.finish(ctx, job.Submission, result, err)
+		}
+
+		if running, ok := p.takeRunning(job.ID, ""); ok {
+			p.finished(running)
+		}
+	}
+}
+
+// markRunning persists a job taken from the queue as running and registers it for the worker
+func (p *Pool) markRunning(job models.Job, worker string) (models.Job, context.Context) {
+	now := time.Now()
+	job.State = models.JobRunning
+	job.Attempts++
+	job.StartedAt = &now
+	job.Worker = worker
+	if err := p.store.save(job); err != nil {
+		log.WithField("Job", job.ID).WithError(err).Error("Failed to persist running job")
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	p.runningMu.Lock()
+	p.running[job.ID] = runningJob{job: job, ctx: ctx, cancel: cancel, worker: worker, heartbeat: now}
+	p.runningMu.Unlock()
+
+	return job, ctx
+}
+
+// takeRunning unregisters a running job of the worker. It returns false if the worker does not process the job
+// (anymore), e.g. because it was requeued in between.
+func (p *Pool) takeRunning(id string, worker string) (runningJob, bool) {
+	p.runningMu.Lock()
+	defer p.runningMu.Unlock()
+
+	running, exists := p.running[id]
+	if !exists || running.worker != worker {
+		return runningJob{}, false
+	}
+	delete(p.running, id)
+	return running, true
+}
+
+// finished releases a job taken from the running jobs
+func (p *Pool) finished(running runningJob) {
+	running.cancel(nil)
+	p.queue.done(running.job)
+
+	if err := p.store.delete(running.job.ID); err != nil {
+		log.WithField("Job", running.job.ID).WithError(err).Error("Failed to delete finished job")
+	}
+}
+
+// commitTarget returns the owner and name of the repository of a submission and the URL of its results
+func commitTarget(submission models.Submission) (owner string, repo string, targetURL string, err error) {
+	parts := strings.Split(submission.RepoName, "/")
+	if len(parts) != 2 {
+		return "", "", "", fmt.Errorf("invalid repository name format")
+	}
+	return parts[0], parts[1], fmt.Sprintf("%s/results/%s", appConfig.CFG.BaseURL, submission.CommitID), nil
+}
+
+// begin reports the start of judging a submission. It returns false if the submission can not be judged.
+func (p *Pool) begin(submission models.Submission) bool {
+	fields := log.Fields{
+		"Repo":   submission.RepoName,
+		"Commit": submission.CommitID,
+	}
+
+	// Extract owner and repo from full repository name
+	owner, repo, targetURL, err := commitTarget(submission)
+	if err != nil {
+		log.WithFields(fields).Error("invalid repository name format")
+		return false
+	}
+
+	if err := submission.GitClient.PostStarting(owner, repo, submission.CommitID, targetURL, status.StatusNone, "Judge started"); err != nil {
+		log.WithFields(fields).WithError(err).Error("Failed to post starting")
+	} else {
+		log.WithFields(fields).Info("Posting starting")
+	}
+	return true
+}
+
+// finish stores and reports the result of a submission, whether it was judged by an in-process or a remote worker
+func (p *Pool) finish(ctx context.Context, submission models.Submission, result *models.TestResult, err error) {
+	fields := log.Fields{
+		"Repo":   submission.RepoName,
+		"Commit": submission.CommitID,
+	}
+
+	owner, repo, targetURL, targetErr := commitTarget(submission)
+	if targetErr != nil {
+		log.WithFields(fields).Error("invalid repository name format")
+		return
+	}
+
+	var superseded *errSuperseded
+	if errors.As(context.Cause(ctx), &superseded) {
+		log.WithFields(fields).Info("Cancelled superseded submission")
+		if err := submission.GitClient.PostSuperseded(owner, repo, submission.CommitID, targetURL, superseded.commitID); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to post superseded")
+		}
+		return
+	}
+	if err != nil {
+		log.WithFields(fields).WithError(err).Error("Failed to execute submission")
+		if err := submission.GitClient.PostStarting(owner, repo, submission.CommitID, targetURL, status.StatusError, "Internal Server error"); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to post internal server error")
+		} else {
+			log.WithFields(fields).Info("Posting internal server error")
+		}
+		return
+	}
+
+	result.Markdown = models.FormatTestResult(result)
+
+	log.WithFields(fields).Debug("Inserting results of commit to datbase")
+	err = db.DB.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(submission.CommitID), []byte(result.Markdown))
+		if appConfig.CFG.DatabaseTTL != 0 {
+			e = e.WithTTL(time.Hour * time.Duration(appConfig.CFG.DatabaseTTL))
+		}
+		err := txn.SetEntry(e)
+		return err
+	})
+	if err != nil {
+		log.WithFields(fields).WithError(err).Error("Failed to create database entry")
+	} else {
+		log.WithFields(fields).Debug("Created Results in database")
+	}
+
+	if result.Skipped {
+		if err := submission.GitClient.PostSkipped(owner, repo, submission.CommitID, targetURL); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to post skipped")
 		}
+		return
+	}
 
-		owner, repo := parts[0], parts[1]
-		targetURL := fmt.Sprintf("%s/results/%s", appConfig.CFG.BaseURL, submission.CommitID)
+	if len(result.Scores) > 0 {
+		if err := saveSubmission(submission, result.Scores); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to record submission")
+		}
+	}
 
-		if err := submission.GitClient.PostStarting(owner, repo, submission.CommitID, targetURL, status.StatusNone, "Judge started"); err != nil {
-			log.WithFields(fields).WithError(err).Error("Failed to post starting")
+	if len(result.TestCases) == 0 {
+		log.WithFields(fields).WithError(err).Warn("No solutions found in submission")
+		result.Status = status.StatusNone
+	} else {
+		if err := p.scoreboardManager.ProcessTestResults(submission, result.TestCases); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to process test results for scoreboard")
 		} else {
-			log.WithFields(fields).Info("Posting starting")
+			log.WithFields(fields).Debug("Processed scoreboard results in database")
 		}
+	}
 
-		result, err := p.executor.Execute(submission)
-		if err != nil {
-			log.WithFields(fields).WithError(err).Error("Failed to execute submission")
-			if err := submission.GitClient.PostStarting(owner, repo, submission.CommitID, targetURL, status.StatusError, "Internal Server error"); err != nil {
-				log.WithFields(fields).WithError(err).Error("Failed to post internal server error")
-			} else {
-				log.WithFields(fields).Info("Posting internal server error")
+	if err := submission.GitClient.PostResult(owner, repo, submission.CommitID, targetURL, result.Status, result.Summary()); err != nil {
+		log.WithFields(fields).WithError(err).Error("Failed to post result")
+	} else {
+		log.WithFields(fields).Info("Posting results")
+	}
+}
+
+// Submit persists the submission and adds it to the queue. It returns ErrQueueFull instead of blocking
+// if the queue has no capacity left. Submissions for a task close to its deadline get a high priority.
+// Pushes skipped by their commit messages are reported without being queued.
+func (p *Pool) Submit(submission models.Submission) error {
+	if len(submission.Solutions) == 0 && len(submission.CommitMessages) > 0 {
+		if d := pushDirectives(submission.CommitMessages); d.Skip {
+			p.skip(submission, d)
+			return nil
+		}
+	}
+	return p.SubmitWithPriority(submission, p.priority(submission))
+}
+
+// skip reports a push which is not judged because of its commit messages
+func (p *Pool) skip(submission models.Submission, d directives) {
+	log.WithFields(log.Fields{
+		"Repo":   submission.RepoName,
+		"Commit": submission.CommitID,
+	}).Info("Judging skipped by commit message")
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.finish(context.Background(), submission, &models.TestResult{
+			Status:     status.StatusNone,
+			Skipped:    true,
+			Directives: d.String(),
+		}, nil)
+	}()
+}
+
+// SubmitWithPriority works like Submit but uses the given priority class
+func (p *Pool) SubmitWithPriority(submission models.Submission, priority models.JobPriority) error {
+	job := models.Job{
+		ID:         uuid.NewString(),
+		Submission: submission,
+		State:      models.JobQueued,
+		Priority:   priority,
+		EnqueuedAt: time.Now(),
+	}
+
+	// Rejudges neither supersede nor are superseded, older commits are judged again on purpose.
+	// The new job takes over the changes of the jobs it supersedes, so their tasks are still judged.
+	var superseded, cancelled []models.Job
+	if p.config.SupersedeQueued && !submission.Rejudge {
+		superseded = p.queue.removeWhere(sameBranch(job))
+		if p.config.CancelSuperseded {
+			cancelled = p.runningWhere(sameBranch(job))
+		}
+		job.Submission = mergePushes(job.Submission, append(superseded, cancelled...))
+	}
+
+	if err := p.enqueue(job); err != nil {
+		for _, old := range superseded {
+			if err := p.queue.push(old); err != nil {
+				log.WithField("Job", old.ID).WithError(err).Error("Failed to restore superseded job")
 			}
+		}
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"Job":      job.ID,
+		"User":     submission.User(),
+		"Priority": priority,
+	}).Info("Submission added")
+
+	p.supersede(job, superseded, cancelled)
+	return nil
+}
+
+// priority returns the priority class of a new submission. It is high if a task of the submission ends within
+// the deadline window.
+func (p *Pool) priority(submission models.Submission) models.JobPriority {
+	if p.config.DeadlineWindow <= 0 {
+		return models.PriorityNormal
+	}
+
+	now := time.Now()
+	for _, task := range submissionTasks(submission) {
+		config, err := p.configs.get(task)
+		if err != nil {
+			// Changed files outside of tasks have no configuration
+			continue
+		}
+		if config.Disabled || config.EndDate == nil {
 			continue
 		}
+		if config.EndDate.After(now) && config.EndDate.Before(now.Add(p.config.DeadlineWindow)) {
+			return models.PriorityHigh
+		}
+	}
 
-		result.Markdown = models.FormatTestResult(result)
+	return models.PriorityNormal
+}
 
-		log.WithFields(fields).Debug("Inserting results of commit to datbase")
-		err = db.DB.Update(func(txn *badger.Txn) error {
-			e := badger.NewEntry([]byte(submission.CommitID), []byte(result.Markdown))
-			if appConfig.CFG.DatabaseTTL != 0 {
-				e = e.WithTTL(time.Hour * time.Duration(appConfig.CFG.DatabaseTTL))
-			}
-			err := txn.SetEntry(e)
-			return err
-		})
+// sameBranch matches the pushes to the repository branch of job except job itself
+func sameBranch(newer models.Job) func(job models.Job) bool {
+	return func(job models.Job) bool {
+		return job.ID != newer.ID && !job.Submission.Rejudge &&
+			job.Submission.RepoName == newer.Submission.RepoName &&
+			job.Submission.BranchName == newer.Submission.BranchName
+	}
+}
+
+// mergePushes extends the submission by the pushes of older jobs. The range starts at the head before
+// the oldest push and all files changed and commit messages according to the webhooks are kept.
+func mergePushes(submission models.Submission, older []models.Job) models.Submission {
+	if len(older) == 0 {
+		return submission
+	}
+
+	sort.Slice(older, func(i, j int) bool {
+		return older[i].EnqueuedAt.Before(older[j].EnqueuedAt)
+	})
+	submission.BeforeCommitID = older[0].Submission.BeforeCommitID
+
+	changed := make([]string, 0)
+	for _, job := range older {
+		changed = append(changed, job.Submission.ChangedFiles...)
+	}
+	submission.ChangedFiles = append(changed, submission.ChangedFiles...)
+
+	messages := make([]string, 0)
+	for _, job := range older {
+		messages = append(messages, job.Submission.CommitMessages...)
+	}
+	submission.CommitMessages = append(messages, submission.CommitMessages...)
+
+	return submission
+}
+
+// runningWhere returns the running jobs matching the predicate
+func (p *Pool) runningWhere(match func(job models.Job) bool) []models.Job {
+	p.runningMu.Lock()
+	defer p.runningMu.Unlock()
+
+	jobs := make([]models.Job, 0)
+	for _, running := range p.running {
+		if match(running.job) {
+			jobs = append(jobs, running.job)
+		}
+	}
+	return jobs
+}
+
+// supersede drops the older queued jobs of the same repository branch and cancels the running ones. The commit
+// statuses of the dropped jobs are posted in the background, so the webhook does not wait for Gitea.
+func (p *Pool) supersede(newer models.Job, superseded []models.Job, cancelled []models.Job) {
+	for _, job := range superseded {
+		fields := log.Fields{
+			"Job":    job.ID,
+			"Repo":   job.Submission.RepoName,
+			"Commit": job.Submission.CommitID,
+		}
+		log.WithFields(fields).Info("Dropped superseded submission")
+
+		if err := p.store.delete(job.ID); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to delete superseded job")
+		}
+	}
+	if len(superseded) > 0 {
+		p.wg.Add(1)
+		go p.postSuperseded(newer, superseded)
+	}
+
+	p.runningMu.Lock()
+	defer p.runningMu.Unlock()
+	for _, job := range cancelled {
+		if running, exists := p.running[job.ID]; exists {
+			log.WithField("Job", job.ID).Info("Cancelling superseded submission")
+			running.cancel(&errSuperseded{commitID: newer.Submission.CommitID})
+		}
+	}
+}
+
+// postSuperseded marks the commits of dropped jobs as superseded by the commit of the newer job
+func (p *Pool) postSuperseded(newer models.Job, superseded []models.Job) {
+	defer p.wg.Done()
+
+	for _, job := range superseded {
+		if job.Submission.GitClient == nil {
+			continue
+		}
+		owner, repo, targetURL, err := commitTarget(job.Submission)
 		if err != nil {
-			log.WithFields(fields).WithError(err).Error("Failed to create database entry")
-		} else {
-			log.WithFields(fields).Debug("Created Results in database")
+			continue
 		}
+		if err := job.Submission.GitClient.PostSuperseded(owner, repo, job.Submission.CommitID, targetURL, newer.Submission.CommitID); err != nil {
+			log.WithField("Job", job.ID).WithError(err).Error("Failed to post superseded")
+		}
+	}
+}
 
-		if len(result.TestCases) == 0 {
-			log.WithFields(fields).WithError(err).Warn("No solutions found in submission")
-			result.Status = status.StatusNone
-		} else {
-			if err := p.scoreboardManager.ProcessTestResults(submission, result.TestCases); err != nil {
-				log.WithFields(fields).WithError(err).Error("Failed to process test results for scoreboard")
-			} else {
-				log.WithFields(fields).Debug("Processed scoreboard results in database")
-			}
+func (p *Pool) enqueue(job models.Job) error {
+	if err := p.store.save(job); err != nil {
+		return fmt.Errorf("failed to persist job: %v", err)
+	}
+
+	if err := p.queue.push(job); err != nil {
+		if err := p.store.delete(job.ID); err != nil {
+			log.WithField("Job", job.ID).WithError(err).Error("Failed to delete rejected job")
 		}
+		return err
+	}
 
-		if err := submission.GitClient.PostResult(owner, repo, submission.CommitID, targetURL, result.Status); err != nil {
-			log.WithFields(fields).WithError(err).Error("Failed to post result")
-		} else {
-			log.WithFields(fields).Info("Posting results")
+	return nil
+}
+
+// Recover re-enqueues all persisted jobs of a previous run. Jobs which were interrupted while running are
+// retried until they reached maxJobAttempts, afterward their commit status is set to error.
+func (p *Pool) Recover() error {
+	jobs, err := p.store.list()
+	if err != nil {
+		return fmt.Errorf("failed to list persisted jobs: %v", err)
+	}
+
+	for _, job := range jobs {
+		fields := log.Fields{
+			"Job":    job.ID,
+			"Repo":   job.Submission.RepoName,
+			"Commit": job.Submission.CommitID,
+			"State":  job.State,
+		}
+
+		job.Submission.GitClient = gitea.NewGiteaClient(appConfig.CFG.GiteaURL, appConfig.CFG.GiteaToken)
+
+		if job.State == models.JobRunning && job.Attempts >= maxJobAttempts {
+			log.WithFields(fields).Warn("Giving up job interrupted too often")
+			p.abandon(job)
+			continue
+		}
+
+		job.State = models.JobQueued
+		job.StartedAt = nil
+		if err := p.queue.push(job); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to re-enqueue job")
+			continue
+		}
+		if err := p.store.save(job); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to persist re-enqueued job")
 		}
+		log.WithFields(fields).Info("Re-enqueued job")
+	}
+
+	return nil
+}
+
+// abandon removes a job and marks its commit as failed by the judge
+func (p *Pool) abandon(job models.Job) {
+	if err := p.store.delete(job.ID); err != nil {
+		log.WithField("Job", job.ID).WithError(err).Error("Failed to delete abandoned job")
+	}
+
+	parts := strings.Split(job.Submission.RepoName, "/")
+	if len(parts) != 2 {
+		return
+	}
+
+	targetURL := fmt.Sprintf("%s/results/%s", appConfig.CFG.BaseURL, job.Submission.CommitID)
+	if err := job.Submission.GitClient.PostStarting(parts[0], parts[1], job.Submission.CommitID, targetURL, status.StatusError, "Judge interrupted"); err != nil {
+		log.WithField("Job", job.ID).WithError(err).Error("Failed to post interrupted status")
 	}
 }
 
-func (p *Pool) Submit(submission models.Submission) {
-	p.submissions <- submission
-	log.Info("Submission added")
+// QueueLength returns the number of submissions waiting for a worker
+func (p *Pool) QueueLength() int {
+	return p.queue.len()
 }
 
 func (p *Pool) Stop() {
 	log.Info("Stopping pool")
-	close(p.submissions)
+	close(p.stop)
+	p.queue.close()
 	p.wg.Wait()
 }
@@ -1,16 +1,27 @@
 package judge_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/gitea"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/scoreboard"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewPool(t *testing.T) {
 	executor := &judge.Executor{}
 	scoreboardManager := &scoreboard.ScoreboardManager{}
-	pool := judge.NewPool(executor, scoreboardManager, 5)
+	pool := judge.NewPool(executor, scoreboardManager, judge.PoolConfig{MaxWorkers: 5, QueueSize: 1000})
 
 	if pool == nil {
 		t.Fatal("Expected pool to be created")
@@ -20,7 +31,7 @@ func TestNewPool(t *testing.T) {
 func TestSubmitMultiple(t *testing.T) {
 	executor := &judge.Executor{}
 	scoreboardManager := &scoreboard.ScoreboardManager{}
-	pool := judge.NewPool(executor, scoreboardManager, 5)
+	pool := judge.NewPool(executor, scoreboardManager, judge.PoolConfig{MaxWorkers: 5, QueueSize: 1000})
 
 	submissions := []models.Submission{
 		{RepoName: "repo1", CommitID: "commit1"},
@@ -29,6 +40,217 @@ func TestSubmitMultiple(t *testing.T) {
 	}
 
 	for _, submission := range submissions {
-		pool.Submit(submission)
+		if err := pool.Submit(submission); err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	}
+}
+
+func TestSubmitQueueFull(t *testing.T) {
+	executor := &judge.Executor{}
+	scoreboardManager := &scoreboard.ScoreboardManager{}
+	// No workers, so nothing is taken from the queue
+	pool := judge.NewPool(executor, scoreboardManager, judge.PoolConfig{MaxWorkers: 0, QueueSize: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := pool.Submit(models.Submission{RepoName: "owner/repo", CommitID: "commit"}); err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	}
+
+	err := pool.Submit(models.Submission{RepoName: "owner/repo", CommitID: "commit"})
+	if !errors.Is(err, judge.ErrQueueFull) {
+		t.Fatalf("Expected ErrQueueFull, got %v", err)
+	}
+
+	if pool.QueueLength() != 2 {
+		t.Errorf("Expected 2 queued submissions, got %d", pool.QueueLength())
+	}
+}
+
+func TestSubmitSupersedeQueued(t *testing.T) {
+	config.CFG = &config.Config{BaseURL: "http://judge"}
+	executor := &judge.Executor{}
+	scoreboardManager := &scoreboard.ScoreboardManager{}
+	pool := judge.NewPool(executor, scoreboardManager, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10, SupersedeQueued: true})
+	defer pool.Stop()
+
+	submissions := []models.Submission{
+		{RepoName: "owner/repo", BranchName: "main", CommitID: "commit1"},
+		{RepoName: "owner/repo", BranchName: "feature", CommitID: "commit2"},
+		{RepoName: "owner/repo", BranchName: "main", CommitID: "commit3"},
+		{RepoName: "other/repo", BranchName: "main", CommitID: "commit4"},
+	}
+
+	for _, submission := range submissions {
+		if err := pool.Submit(submission); err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	}
+
+	if pool.QueueLength() != 3 {
+		t.Errorf("Expected 3 queued submissions, got %d", pool.QueueLength())
+	}
+}
+
+func TestSubmitSupersedeDoesNotWaitForGitea(t *testing.T) {
+	release := make(chan struct{})
+	posted := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/version") {
+			w.Write([]byte(`{"version":"1.22.0"}`))
+			return
+		}
+		<-release
+		posted <- r.URL.Path
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	config.CFG = &config.Config{BaseURL: "http://judge"}
+	client := gitea.NewGiteaClient(server.URL, "token")
+
+	pool := judge.NewPool(&judge.Executor{}, &scoreboard.ScoreboardManager{}, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10, SupersedeQueued: true})
+	defer pool.Stop()
+
+	done := make(chan error)
+	go func() {
+		for _, commit := range []string{"commit1", "commit2"} {
+			if err := pool.Submit(models.Submission{RepoName: "owner/repo", BranchName: "main", CommitID: commit, GitClient: client}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	// The newer push is accepted while Gitea does not respond
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Submit waited for the superseded status")
+	}
+
+	close(release)
+	select {
+	case path := <-posted:
+		if !strings.Contains(path, "commit1") {
+			t.Errorf("Expected the status of commit1, got %s", path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Superseded status was not posted")
+	}
+}
+
+func TestSubmitDeadlinePriority(t *testing.T) {
+	_, client := newFakeGitea(t)
+	testCaseDir := t.TempDir()
+	deadlines := map[string]time.Time{
+		"urgent": time.Now().Add(time.Hour),
+		"later":  time.Now().Add(30 * 24 * time.Hour),
+	}
+	for task, end := range deadlines {
+		dir := filepath.Join(testCaseDir, "workshop1", task)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+		config := fmt.Sprintf("name: %s\nend_date: %s\n", task, end.Format(time.RFC3339))
+		if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	executor := judge.NewExecutor(nil, testCaseDir, nil)
+	pool := judge.NewPool(executor, &scoreboard.ScoreboardManager{}, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10, DeadlineWindow: 24 * time.Hour})
+	defer pool.Stop()
+
+	// Only the push changing the task which ends soon is urgent
+	submissions := []models.Submission{
+		{RepoName: "owner/alice", CommitID: "later", ChangedFiles: []string{"workshop1/later/solution.py"}, GitClient: client},
+		{RepoName: "owner/bob", CommitID: "readme", ChangedFiles: []string{"README.md"}, GitClient: client},
+		{RepoName: "owner/carol", CommitID: "urgent", ChangedFiles: []string{"workshop1/urgent/solution.py"}, GitClient: client},
+	}
+	for _, submission := range submissions {
+		if err := pool.Submit(submission); err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	}
+
+	expected := []struct {
+		commit   string
+		priority models.JobPriority
+	}{
+		{"urgent", models.PriorityHigh},
+		{"later", models.PriorityNormal},
+		{"readme", models.PriorityNormal},
+	}
+	for _, e := range expected {
+		job := pool.Claim(context.Background(), "worker1", "10.0.0.1", 3)
+		if job == nil || job.Submission.CommitID != e.commit || job.Priority != e.priority {
+			t.Fatalf("Expected %s with priority %v, got %+v", e.commit, e.priority, job)
+		}
+	}
+}
+
+func TestSubmitSupersedeMergesPushes(t *testing.T) {
+	_, client := newFakeGitea(t)
+	pool := judge.NewPool(&judge.Executor{}, &scoreboard.ScoreboardManager{}, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10, SupersedeQueued: true})
+	defer pool.Stop()
+
+	// The superseded pushes are removed before the new one is queued, so it takes over their changes
+	pushes := []models.Submission{
+		{RepoName: "owner/repo", BranchName: "main", BeforeCommitID: "commit0", CommitID: "commit1", ChangedFiles: []string{"workshop1/task1/solution.py"}},
+		{RepoName: "owner/repo", BranchName: "main", BeforeCommitID: "commit1", CommitID: "commit2", ChangedFiles: []string{"workshop1/task2/solution.py"}},
+	}
+	for _, push := range pushes {
+		push.GitClient = client
+		if err := pool.Submit(push); err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	}
+
+	if pool.QueueLength() != 1 {
+		t.Fatalf("Expected 1 queued submission, got %d", pool.QueueLength())
+	}
+	job := pool.Claim(context.Background(), "worker1", "10.0.0.1", 1)
+	if job == nil || job.Submission.CommitID != "commit2" || job.Submission.BeforeCommitID != "commit0" {
+		t.Fatalf("Expected commit2 since commit0, got %+v", job)
+	}
+	expected := []string{"workshop1/task1/solution.py", "workshop1/task2/solution.py"}
+	if strings.Join(job.Submission.ChangedFiles, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected changed files %v, got %v", expected, job.Submission.ChangedFiles)
+	}
+}
+
+func TestSubmitSkippedPush(t *testing.T) {
+	fake, client := newFakeGitea(t)
+	useMemoryDB(t)
+	pool := judge.NewPool(&judge.Executor{}, &scoreboard.ScoreboardManager{}, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10})
+
+	// Only a push whose commits all ask for it is skipped
+	pushes := []models.Submission{
+		{RepoName: "owner/repo", CommitID: "skipped", CommitMessages: []string{"WIP [skip judge]", "More WIP [skip judge]"}},
+		{RepoName: "owner/repo", CommitID: "judged", CommitMessages: []string{"WIP [skip judge]", "Done"}},
+	}
+	for _, push := range pushes {
+		push.GitClient = client
+		if err := pool.Submit(push); err != nil {
+			t.Fatalf("Expected submission to be accepted: %v", err)
+		}
+	}
+
+	if pool.QueueLength() != 1 {
+		t.Errorf("Expected 1 queued submission, got %d", pool.QueueLength())
+	}
+
+	// Stop waits for the status of the skipped push
+	pool.Stop()
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.statuses) != 1 || !strings.Contains(fake.statuses[0], "skipped") {
+		t.Errorf("Expected a status of the skipped commit only, got %v", fake.statuses)
 	}
 }
@@ -0,0 +1,493 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// processEnvironment is the name of the environment shown in the result, the toolchains of the host are used
+const processEnvironment = "host"
+
+// processPath is the search path of sandboxed processes
+const processPath = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// processSelfTestScript reports the properties of a sandboxed process as key=value lines. The environment holds
+// the path of the repository and of a file of the host.
+const processSelfTestScript = `echo "uid=$(id -u)"
+echo "pid=$$"
+echo "init=$(cat /proc/1/comm)"
+echo "interfaces=$(grep -c ':' /proc/net/dev)"
+touch /selftest 2>/dev/null && echo "root_writable=yes" || echo "root_writable=no"
+touch /tmp/selftest 2>/dev/null && echo "tmp_writable=yes" || echo "tmp_writable=no"
+touch "$SELFTEST_REPO/selftest" 2>/dev/null && echo "repo_writable=yes" || echo "repo_writable=no"
+test -e "$SELFTEST_HOST_FILE" && echo "host_visible=yes" || echo "host_visible=no"
+while IFS=: read -r key value; do
+  case "$key" in CapEff|NoNewPrivs|Seccomp) echo "$key=$(echo $value)" ;; esac
+done < /proc/self/status
+echo "nproc=$(sed -n 's/^Max processes *\([^ ]*\).*/\1/p' /proc/self/limits)"
+echo "fsize=$(sed -n 's/^Max file size *\([^ ]*\).*/\1/p' /proc/self/limits)"
+cgroup=$(sed -n 's/^0:://p' /proc/self/cgroup)
+echo "pids_max=$(cat /sys/fs/cgroup$cgroup/pids.max 2>/dev/null)"
+echo "memory_max=$(cat /sys/fs/cgroup$cgroup/memory.max 2>/dev/null)"`
+
+// ProcessRunner runs programs as local processes instead of containers. Every process gets its own PID,
+// network, IPC, UTS and mount namespace with a read-only root filesystem of the toolchain directories of the host,
+// and is limited with rlimits and, if a delegated cgroup is configured, with the cgroup v2 controllers. The
+// toolchains of the languages must be installed on the host.
+type ProcessRunner struct {
+	limits     models.Limits
+	security   models.Security
+	cgroup     string // Delegated cgroup v2 directory, only rlimits are used if empty
+	namespaces bool
+	warned     sync.Map // Security settings the runner warned about
+}
+
+// processRun describes a single process execution
+type processRun struct {
+	Command  string
+	Env      []string
+	Dir      string
+	Stdin    io.Reader
+	Stdout   io.Writer      // Gets the output instead of the result if set, e.g. the interactor of the case
+	Mounts   []processMount // Directories of the host the process can access
+	Limits   models.Limits
+	Security models.Security
+}
+
+// processMount is a directory of the host which is available at the same path in the sandbox
+type processMount struct {
+	Path     string
+	Writable bool
+}
+
+// NewProcessRunner creates a runner which uses limits and security for every value not set by the test case
+func NewProcessRunner(limits models.Limits, security models.Security, cgroup string, namespaces bool) (*ProcessRunner, error) {
+	log.Info("New process runner created")
+
+	if cgroup != "" {
+		if err := prepareCgroup(cgroup); err != nil {
+			return nil, fmt.Errorf("failed to prepare cgroup %s: %v", cgroup, err)
+		}
+	}
+
+	r := &ProcessRunner{
+		limits:     limits,
+		security:   security,
+		cgroup:     cgroup,
+		namespaces: namespaces,
+	}
+	r.warnUnenforced(security)
+	return r, nil
+}
+
+// unenforcedSecurity returns the settings of a security profile the process runner cannot enforce
+func (r *ProcessRunner) unenforcedSecurity(security models.Security) []string {
+	unenforced := make([]string, 0)
+	root := os.Getuid() == 0
+	if security.User != "" && !root {
+		unenforced = append(unenforced, "the user is ignored, processes run as the user of the judge")
+	}
+	if security.User == "" && root {
+		unenforced = append(unenforced, "no user is set, processes run as root")
+	}
+	if !r.namespaces {
+		unenforced = append(unenforced, "namespaces are disabled, processes see the filesystem, processes and network of the host")
+	}
+	if len(security.CapAdd) > 0 {
+		unenforced = append(unenforced, "capabilities cannot be added, every capability is dropped")
+	}
+	if security.Seccomp != SeccompUnconfined {
+		unenforced = append(unenforced, "seccomp profiles are not supported, processes run unconfined")
+	}
+	return unenforced
+}
+
+// warnUnenforced warns once about every setting of a security profile which is not enforced
+func (r *ProcessRunner) warnUnenforced(security models.Security) {
+	for _, warning := range r.unenforcedSecurity(security) {
+		if _, warned := r.warned.LoadOrStore(warning, true); !warned {
+			log.WithField("Reason", warning).Warn("Security profile is not fully enforced by the process runner")
+		}
+	}
+}
+
+// programMounts returns the repository and the build directory of a program. Only the build directory is writable
+// unless the security profile allows writing to the repository.
+func programMounts(repositoryDir string, program Program, security models.Security) []processMount {
+	mounts := []processMount{{Path: repositoryDir, Writable: !models.Enabled(security.ReadOnlyRepo)}}
+	if program.BuildDir != "" {
+		mounts = append(mounts, processMount{Path: program.BuildDir, Writable: true})
+	}
+	return mounts
+}
+
+// hostPaths returns the paths of the solution file and its directory on the host
+func (p Program) hostPaths(repositoryDir string) (string, string) {
+	file := filepath.Join(repositoryDir, p.File)
+	return file, filepath.Dir(file)
+}
+
+// EffectiveLimits returns the limits a test case is executed with. The limits of the language override the
+// defaults and are overridden by the limits of the test case.
+func (r *ProcessRunner) EffectiveLimits(language *models.Language, limits models.Limits) models.Limits {
+	return effectiveLimits(r.limits, language, limits)
+}
+
+// EffectiveSecurity returns the security profile a process is started with. The profile of the task
+// overrides the server defaults.
+func (r *ProcessRunner) EffectiveSecurity(security models.Security) models.Security {
+	effective := r.security.Merge(&security)
+	r.warnUnenforced(effective)
+	return effective
+}
+
+// Environment returns the host as environment, the image of the language is not used
+func (r *ProcessRunner) Environment(ctx context.Context, language *models.Language) (string, string, error) {
+	return processEnvironment, "", nil
+}
+
+// Compile runs the compile command of the language. Interpreted languages are not compiled.
+func (r *ProcessRunner) Compile(ctx context.Context, repositoryDir string, program Program) (*models.ExecutionResult, error) {
+	if program.Language.Compile == "" {
+		return &models.ExecutionResult{}, nil
+	}
+
+	limits := compileLimits(r.EffectiveLimits(program.Language, models.Limits{}))
+	security := r.EffectiveSecurity(program.Security)
+	file, dir := program.hostPaths(repositoryDir)
+	result, err := r.run(ctx, processRun{
+		Command:  program.Language.Command(program.Language.Compile, file, dir, program.BuildDir),
+		Dir:      dir,
+		Mounts:   programMounts(repositoryDir, program, security),
+		Limits:   limits,
+		Security: security,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return compileResult(result, limits), nil
+}
+
+// NewSession returns a session which runs every test case as a new process. Only the compilation is shared,
+// processes keep no state between cases.
+func (r *ProcessRunner) NewSession(ctx context.Context, repositoryDir string, program Program, limits models.Limits) (Session, error) {
+	return &processSession{
+		runner:        r,
+		repositoryDir: repositoryDir,
+		program:       program,
+	}, nil
+}
+
+// RunCode runs a prepared program with the input of the test case on stdin. Cases with files run in a working
+// directory of their own.
+func (r *ProcessRunner) RunCode(ctx context.Context, testCase models.TestCase, program Program) (*models.ExecutionResult, error) {
+	return r.runCode(ctx, testCase, program, strings.NewReader(testCase.Input), nil)
+}
+
+// RunInteractive runs a prepared program and the interactor of the test case as two processes. The interactor
+// runs with the security profile of the server and only its directory holds the input of the case.
+func (r *ProcessRunner) RunInteractive(ctx context.Context, testCase models.TestCase, program Program) (*models.ExecutionResult, error) {
+	tmpDir, err := getTempDir("interactor-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	command, err := writeInteractor(tmpDir, tmpDir, testCase.Interactor, testCase.Input)
+	if err != nil {
+		return nil, err
+	}
+	if err := shareWithSandbox(tmpDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	return interact(func(stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error) {
+		return r.runCode(ctx, testCase, program, stdin, stdout)
+	}, func(stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error) {
+		return r.run(ctx, processRun{
+			Command:  command,
+			Dir:      tmpDir,
+			Stdin:    stdin,
+			Stdout:   stdout,
+			Mounts:   []processMount{{Path: tmpDir}},
+			Limits:   testCase.Limits,
+			Security: r.security,
+		})
+	})
+}
+
+// runCode runs a prepared program with stdin. Its output is written to stdout if set, otherwise it is stored in
+// the result.
+func (r *ProcessRunner) runCode(ctx context.Context, testCase models.TestCase, program Program, stdin io.Reader, stdout io.Writer) (*models.ExecutionResult, error) {
+	file, dir := program.hostPaths(testCase.RepositoryDir)
+	command := caseCommand(program.Language.Command(program.Language.Run, file, dir, program.BuildDir), testCase.Args)
+	limits := r.EffectiveLimits(program.Language, testCase.Limits)
+	security := r.EffectiveSecurity(program.Security)
+	mounts := programMounts(testCase.RepositoryDir, program, security)
+
+	workDir := ""
+	if testCase.HasFiles() {
+		var err error
+		if workDir, err = prepareWorkDir(testCase); err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(workDir)
+		dir = workDir
+		mounts = append(mounts, processMount{Path: workDir, Writable: true})
+	}
+
+	result, err := r.run(ctx, processRun{
+		Command:  command,
+		Env:      caseEnv(testCase),
+		Dir:      dir,
+		Stdin:    stdin,
+		Stdout:   stdout,
+		Mounts:   mounts,
+		Limits:   limits,
+		Security: security,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if workDir != "" {
+		if err := readOutputFiles(workDir, testCase, limits, result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// RunChecker runs a custom checker program as a process. The checker is called with the paths of the input,
+// the actual output and the expected output as arguments.
+func (r *ProcessRunner) RunChecker(ctx context.Context, program string, limits models.Limits, input, expected, actual string) (*models.ExecutionResult, error) {
+	tmpDir, err := getTempDir("checker-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	command, err := writeChecker(tmpDir, tmpDir, program, input, expected, actual)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := shareWithSandbox(tmpDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	return r.run(ctx, processRun{
+		Command:  command,
+		Dir:      tmpDir,
+		Mounts:   []processMount{{Path: tmpDir}},
+		Limits:   limits,
+		Security: r.security,
+	})
+}
+
+// SelfTest starts a process with the default limits and security profile and verifies that it is isolated
+func (r *ProcessRunner) SelfTest(ctx context.Context) ([]SelfTestCheck, error) {
+	repoDir, err := getTempDir("selftest-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(repoDir)
+	// A file of the host next to the repository, which is not mounted into the sandbox
+	hostDir, err := getTempDir("selftest-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(hostDir)
+	hostFile := filepath.Join(hostDir, "selftest")
+	if err := os.WriteFile(hostFile, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to create file: %v", err)
+	}
+	if err := shareWithSandbox(repoDir, true); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+	if err := shareWithSandbox(hostDir, false); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	result, err := r.run(ctx, processRun{
+		Command:  processSelfTestScript,
+		Env:      []string{"SELFTEST_REPO=" + repoDir, "SELFTEST_HOST_FILE=" + hostFile},
+		Dir:      "/",
+		Mounts:   []processMount{{Path: repoDir, Writable: !models.Enabled(r.security.ReadOnlyRepo)}},
+		Limits:   r.limits,
+		Security: r.security,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("self-test exited with code %d: %s", result.ExitCode, Trim(result.Stderr))
+	}
+
+	return r.evaluateSelfTest(result.Output), nil
+}
+
+// evaluateSelfTest compares the reported properties of a sandboxed process with the expected ones
+func (r *ProcessRunner) evaluateSelfTest(output string) []SelfTestCheck {
+	checks := newSelfTestChecks(output)
+	if user, _, _ := strings.Cut(r.security.User, ":"); user != "" && os.Getuid() == 0 {
+		checks.check("User", "uid", user)
+	}
+	if r.namespaces {
+		checks.check("PID namespace", "pid", "1")
+		// The process is the first one of its own /proc
+		checks.check("Own /proc", "init", "sh")
+		// Only the loopback interface exists
+		checks.check("No network", "interfaces", "1")
+		checks.check("Read-only root filesystem", "root_writable", "no")
+		checks.check("Writable /tmp", "tmp_writable", "yes")
+		checks.check("Host filesystem hidden", "host_visible", "no")
+		if models.Enabled(r.security.ReadOnlyRepo) {
+			checks.check("Read-only repository", "repo_writable", "no")
+		}
+	}
+	checks.check("No capabilities", "CapEff", "0000000000000000")
+	checks.check("No new privileges", "NoNewPrivs", "1")
+	if r.security.Seccomp != SeccompUnconfined {
+		checks.check("Seccomp filter", "Seccomp", "2")
+	}
+	if r.limits.Pids > 0 {
+		if r.cgroup != "" {
+			checks.check("Process limit", "pids_max", strconv.FormatInt(r.limits.Pids, 10))
+		} else {
+			checks.check("Process limit", "nproc", strconv.FormatInt(r.limits.Pids, 10))
+		}
+	}
+	if r.security.TmpfsSize > 0 {
+		checks.check("File size limit", "fsize", strconv.FormatInt(r.security.TmpfsSize*1024*1024, 10))
+	}
+	if r.cgroup != "" && r.limits.Memory > 0 {
+		checks.check("Memory limit", "memory_max", strconv.FormatInt(r.limits.Memory*1024*1024, 10))
+	}
+
+	return checks.checks
+}
+
+// limitCommand sets the rlimits of a command with the ulimit builtin of the shell
+func limitCommand(command string, limits models.Limits, cgroup bool) string {
+	ulimit := cpuTimeUlimit(limits)
+	if limits.Memory > 0 && !cgroup {
+		// Without a cgroup only the address space can be limited, which is larger than the memory in use
+		ulimit += fmt.Sprintf("ulimit -v %d; ", limits.Memory*1024)
+	}
+	return ulimit + command
+}
+
+// run starts a process in the sandbox, waits until it exited or the wall time is exceeded and collects its
+// output and measurements
+func (r *ProcessRunner) run(ctx context.Context, spec processRun) (*models.ExecutionResult, error) {
+	// Home and temp directory of the process
+	tmpDir, err := getTempDir("sandbox-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := shareWithSandbox(tmpDir, true); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", limitCommand(spec.Command, spec.Limits, r.cgroup != ""))
+	cmd.Dir = spec.Dir
+	cmd.Env = append([]string{processPath, "HOME=" + tmpDir, "TMPDIR=" + tmpDir}, spec.Env...)
+	cmd.Stdin = spec.Stdin
+	output := newStreams(spec.Limits)
+	cmd.Stdout, cmd.Stderr = output.stdout, output.stderr
+	if spec.Stdout != nil {
+		cmd.Stdout = spec.Stdout
+	}
+	// Background processes which keep the output open do not block the judge
+	cmd.WaitDelay = time.Second
+
+	sandbox, err := r.sandbox(cmd, spec, tmpDir)
+	if err != nil {
+		return nil, err
+	}
+	defer sandbox.remove()
+
+	start := time.Now()
+	if err := sandbox.start(); err != nil {
+		return nil, fmt.Errorf("failed to start process: %v", err)
+	}
+
+	processFields := log.Fields{
+		"PID": cmd.Process.Pid,
+	}
+	log.WithFields(processFields).WithFields(log.Fields{
+		"Memory":   spec.Limits.Memory,
+		"CPUs":     spec.Limits.CPUs,
+		"Pids":     spec.Limits.Pids,
+		"WallTime": spec.Limits.WallTime,
+	}).Debug("Started process")
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- cmd.Wait()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, spec.Limits.WallTimeDuration())
+	defer cancel()
+
+	var result models.ExecutionResult
+	select {
+	case <-ctx.Done():
+		result.TimedOut = true
+		log.WithFields(processFields).Warn("execution timeout")
+		sandbox.kill()
+		err = <-waited
+	case <-output.exceeded:
+		log.WithFields(processFields).Warn("output limit exceeded")
+		sandbox.kill()
+		err = <-waited
+	case err = <-waited:
+	}
+	result.ExecutionTime = time.Since(start)
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+		return nil, fmt.Errorf("failed to wait for process: %v", err)
+	}
+
+	result.ExitCode = int64(cmd.ProcessState.ExitCode())
+	sandbox.measure(&result, cmd.ProcessState)
+	output.collect(&result)
+	return &result, nil
+}
+
+// processSession runs the test cases of a task after compiling the program once
+type processSession struct {
+	runner        *ProcessRunner
+	repositoryDir string
+	program       Program
+}
+
+func (s *processSession) Compile(ctx context.Context) (*models.ExecutionResult, error) {
+	return s.runner.Compile(ctx, s.repositoryDir, s.program)
+}
+
+// Accepts reports true, every process is started with the limits of its test case
+func (s *processSession) Accepts(limits models.Limits) bool {
+	return true
+}
+
+func (s *processSession) Run(ctx context.Context, testCase models.TestCase) (*models.ExecutionResult, error) {
+	return s.runner.RunCode(ctx, testCase, s.program)
+}
+
+func (s *processSession) Close() {}
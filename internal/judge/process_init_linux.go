@@ -0,0 +1,413 @@
+package judge
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+)
+
+// sandboxInit is the name the judge is started with to set up the sandbox of a process
+const sandboxInit = "gitcodejudge-sandbox"
+
+// Files the sandbox init receives from the judge
+const (
+	sandboxSpecFD  = 3 // The spec as JSON
+	sandboxErrorFD = 4 // Closed on exec, the init writes the error to it if it fails
+)
+
+// sandboxRootDirs are the directories of the host available read-only in the root filesystem of a sandbox. They hold
+// the toolchains of the languages and the files they need, e.g. the shared libraries and the users.
+var sandboxRootDirs = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64", "/libx32", "/etc", "/opt"}
+
+// sandboxDevices are the devices available in a sandbox
+var sandboxDevices = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom"}
+
+// sandboxSpec tells the sandbox init how to set up the sandbox before it executes the command
+type sandboxSpec struct {
+	Command    []string
+	Env        []string
+	Dir        string
+	Root       string              // Empty directory the root filesystem is built in, the host filesystem is kept if empty
+	Mounts     []processMount      // Directories of the host available at the same path
+	TmpfsSize  int64               // Size of /tmp in MB
+	Rlimits    []sandboxRlimit     // Limits set in addition to the ones of the shell
+	Credential *syscall.Credential // User the command runs as, nil keeps the user
+	Cgroup     string              // cgroup the command is moved into, empty if no cgroup is used
+}
+
+// sandboxRlimit is a resource limit of a sandbox
+type sandboxRlimit struct {
+	Resource int
+	Limit    uint64
+}
+
+// InitSandbox sets up the sandbox of a process and executes its command if the judge was started as sandbox init.
+// Otherwise it returns immediately. It must be called first in main, before any goroutine is started.
+func InitSandbox() {
+	if len(os.Args) == 0 || os.Args[0] != sandboxInit {
+		return
+	}
+
+	// Namespaces, capabilities and the no new privileges flag belong to the thread which executes the command
+	runtime.LockOSThread()
+	err := initSandbox()
+	// Only reached if the sandbox could not be set up
+	fmt.Fprint(os.NewFile(sandboxErrorFD, "error"), err)
+	os.Exit(1)
+}
+
+// initSandbox sets up the sandbox described by the spec and executes its command
+func initSandbox() error {
+	syscall.CloseOnExec(sandboxErrorFD)
+
+	specFile := os.NewFile(sandboxSpecFD, "spec")
+	var spec sandboxSpec
+	if err := json.NewDecoder(specFile).Decode(&spec); err != nil {
+		return fmt.Errorf("failed to read spec: %v", err)
+	}
+	specFile.Close()
+
+	// The cgroup is opened before the host filesystem is gone
+	var cgroup *os.File
+	if spec.Cgroup != "" {
+		var err error
+		if cgroup, err = os.OpenFile(filepath.Join(spec.Cgroup, "cgroup.procs"), os.O_WRONLY, 0); err != nil {
+			return fmt.Errorf("failed to open cgroup: %v", err)
+		}
+	}
+
+	if spec.Root != "" {
+		if err := buildRoot(spec); err != nil {
+			return err
+		}
+	}
+	if spec.Dir != "" {
+		if err := os.Chdir(spec.Dir); err != nil {
+			return fmt.Errorf("failed to change directory: %v", err)
+		}
+	}
+
+	for _, rlimit := range spec.Rlimits {
+		if err := unix.Setrlimit(rlimit.Resource, &unix.Rlimit{Cur: rlimit.Limit, Max: rlimit.Limit}); err != nil {
+			return fmt.Errorf("failed to set rlimit %d: %v", rlimit.Resource, err)
+		}
+	}
+
+	// The memory the init used is not counted, the command is moved into its cgroup right before it is executed
+	if cgroup != nil {
+		if _, err := cgroup.WriteString("0"); err != nil {
+			return fmt.Errorf("failed to join cgroup: %v", err)
+		}
+		cgroup.Close()
+	}
+
+	if err := dropCapabilities(); err != nil {
+		return err
+	}
+	if spec.Credential != nil {
+		if err := setCredential(spec.Credential); err != nil {
+			return err
+		}
+	}
+	// Changing the user resets the signal sent when the judge dies
+	if err := unix.Prctl(unix.PR_SET_PDEATHSIG, uintptr(syscall.SIGKILL), 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set parent death signal: %v", err)
+	}
+	// Neither setuid binaries nor file capabilities grant privileges to the command
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no new privileges: %v", err)
+	}
+
+	if err := syscall.Exec(spec.Command[0], spec.Command, spec.Env); err != nil {
+		return fmt.Errorf("failed to execute %s: %v", spec.Command[0], err)
+	}
+	return nil
+}
+
+// buildRoot builds a read-only root filesystem from the toolchain directories of the host, a fresh /proc, a minimal
+// /dev, a /tmp of its own and the mounts of the spec, and makes it the root of the process
+func buildRoot(spec sandboxSpec) error {
+	// Mounts of the sandbox do not propagate to the host
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %v", err)
+	}
+	root := spec.Root
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount root: %v", err)
+	}
+
+	for _, dir := range sandboxRootDirs {
+		target, err := bindHostPath(root, dir)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		// Mounts of the host inside of the directories are read-only as well
+		mountPoints, err := mountPointsBelow(target)
+		if err != nil {
+			return err
+		}
+		for _, mountPoint := range mountPoints {
+			if err := remountReadOnly(mountPoint); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "proc"), 0755); err != nil {
+		return fmt.Errorf("failed to create /proc: %v", err)
+	}
+	// Only shows the processes of the PID namespace
+	if err := unix.Mount("proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("failed to mount /proc: %v", err)
+	}
+	if err := buildDev(root, spec.TmpfsSize); err != nil {
+		return err
+	}
+	if err := mountTmpfs(filepath.Join(root, "tmp"), spec.TmpfsSize); err != nil {
+		return err
+	}
+
+	// Parents are mounted before the directories inside of them
+	mounts := slices.Clone(spec.Mounts)
+	slices.SortFunc(mounts, func(a, b processMount) int {
+		return strings.Count(a.Path, "/") - strings.Count(b.Path, "/")
+	})
+	for _, mount := range mounts {
+		target, err := bindHostPath(root, mount.Path)
+		if err != nil {
+			return err
+		}
+		flags := unix.MS_BIND | unix.MS_REMOUNT | unix.MS_NOSUID | unix.MS_NODEV | lockedFlags(target)
+		if !mount.Writable {
+			flags |= unix.MS_RDONLY
+		}
+		if err := unix.Mount("", target, "", flags, ""); err != nil {
+			return fmt.Errorf("failed to remount %s: %v", mount.Path, err)
+		}
+	}
+	if err := remountReadOnly(root); err != nil {
+		return err
+	}
+
+	// The host filesystem is detached once the new root is in place
+	if err := os.Chdir(root); err != nil {
+		return fmt.Errorf("failed to change to root: %v", err)
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("failed to pivot root: %v", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to detach host filesystem: %v", err)
+	}
+	return os.Chdir("/")
+}
+
+// bindHostPath makes a path of the host available at the same path below root and returns the mount point. Symlinks
+// are recreated, missing paths are skipped and return an empty mount point.
+func bindHostPath(root string, path string) (string, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	target := filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %v", filepath.Dir(path), err)
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		if err := os.Symlink(link, target); err != nil && !os.IsExist(err) {
+			return "", fmt.Errorf("failed to link %s: %v", path, err)
+		}
+		return "", nil
+	case info.IsDir():
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %v", path, err)
+		}
+	default:
+		file, err := os.OpenFile(target, os.O_CREATE, 0644)
+		if err != nil {
+			return "", fmt.Errorf("failed to create %s: %v", path, err)
+		}
+		file.Close()
+	}
+
+	if err := unix.Mount(path, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return "", fmt.Errorf("failed to mount %s: %v", path, err)
+	}
+	return target, nil
+}
+
+// buildDev mounts a /dev with the devices a program needs
+func buildDev(root string, size int64) error {
+	dev := filepath.Join(root, "dev")
+	if err := os.Mkdir(dev, 0755); err != nil {
+		return fmt.Errorf("failed to create /dev: %v", err)
+	}
+	if err := unix.Mount("tmpfs", dev, "tmpfs", unix.MS_NOSUID|unix.MS_NOEXEC, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount /dev: %v", err)
+	}
+
+	for _, device := range sandboxDevices {
+		if _, err := bindHostPath(root, device); err != nil {
+			return err
+		}
+	}
+	links := map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	}
+	for name, link := range links {
+		if err := os.Symlink(link, filepath.Join(dev, name)); err != nil {
+			return fmt.Errorf("failed to link /dev/%s: %v", name, err)
+		}
+	}
+
+	if err := mountTmpfs(filepath.Join(dev, "shm"), size); err != nil {
+		return err
+	}
+	return remountReadOnly(dev)
+}
+
+// mountTmpfs mounts a writable tmpfs of size MB, unlimited if size is 0
+func mountTmpfs(target string, size int64) error {
+	if err := os.Mkdir(target, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", target, err)
+	}
+	options := "mode=1777"
+	if size > 0 {
+		options += fmt.Sprintf(",size=%dm", size)
+	}
+	if err := unix.Mount("tmpfs", target, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, options); err != nil {
+		return fmt.Errorf("failed to mount %s: %v", target, err)
+	}
+	return nil
+}
+
+// remountReadOnly makes a mount read-only, the mounts below it are kept
+func remountReadOnly(mountPoint string) error {
+	flags := unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | lockedFlags(mountPoint)
+	if err := unix.Mount("", mountPoint, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to make %s read-only: %v", mountPoint, err)
+	}
+	return nil
+}
+
+// mountPointsBelow returns the mount point of target and the ones below it
+func mountPointsBelow(target string) ([]string, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mounts: %v", err)
+	}
+	defer file.Close()
+
+	mountPoints := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		// Spaces and other special characters are escaped as octal
+		mountPoint := strings.NewReplacer(`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`).Replace(fields[4])
+		if mountPoint == target || strings.HasPrefix(mountPoint, target+"/") {
+			mountPoints = append(mountPoints, mountPoint)
+		}
+	}
+	return mountPoints, scanner.Err()
+}
+
+// lockedFlags returns the flags of a mount which must be kept when it is remounted. Inside of a user namespace,
+// the flags of mounts from the host cannot be cleared.
+func lockedFlags(target string) uintptr {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(target, &stat); err != nil {
+		return 0
+	}
+
+	flags := map[int64]uintptr{
+		unix.ST_RDONLY:      unix.MS_RDONLY,
+		unix.ST_NOSUID:      unix.MS_NOSUID,
+		unix.ST_NODEV:       unix.MS_NODEV,
+		unix.ST_NOEXEC:      unix.MS_NOEXEC,
+		unix.ST_NOATIME:     unix.MS_NOATIME,
+		unix.ST_NODIRATIME:  unix.MS_NODIRATIME,
+		unix.ST_RELATIME:    unix.MS_RELATIME,
+		unix.ST_SYNCHRONOUS: unix.MS_SYNCHRONOUS,
+	}
+	var locked uintptr
+	for st, ms := range flags {
+		if stat.Flags&st != 0 {
+			locked |= ms
+		}
+	}
+	return locked
+}
+
+// dropCapabilities clears the capabilities the command could get, so it has none even if it runs as root
+func dropCapabilities() error {
+	for capability := 0; ; capability++ {
+		err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(capability), 0, 0, 0)
+		// The bounding set ends after the last capability of the kernel
+		if errors.Is(err, unix.EINVAL) {
+			break
+		}
+		// Without CAP_SETPCAP the process is no root and the command gets no capabilities anyway
+		if errors.Is(err, unix.EPERM) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to drop capability %d: %v", capability, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to clear ambient capabilities: %v", err)
+	}
+
+	// Inheritable capabilities are kept by an executed root process
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return fmt.Errorf("failed to read capabilities: %v", err)
+	}
+	data[0].Inheritable, data[1].Inheritable = 0, 0
+	if err := unix.Capset(&header, &data[0]); err != nil {
+		return fmt.Errorf("failed to clear inheritable capabilities: %v", err)
+	}
+	return nil
+}
+
+// setCredential switches every thread to the user and group of the credential
+func setCredential(credential *syscall.Credential) error {
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return fmt.Errorf("failed to clear groups: %v", err)
+	}
+	gid, uid := int(credential.Gid), int(credential.Uid)
+	if err := syscall.Setresgid(gid, gid, gid); err != nil {
+		return fmt.Errorf("failed to set group: %v", err)
+	}
+	if err := syscall.Setresuid(uid, uid, uid); err != nil {
+		return fmt.Errorf("failed to set user: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package judge
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// cgroupControllers are enabled for the cgroups of the processes if the delegated cgroup provides them
+var cgroupControllers = []string{"cpu", "memory", "pids"}
+
+// processSandbox isolates and limits a single process
+type processSandbox struct {
+	cmd    *exec.Cmd
+	spec   sandboxSpec
+	cgroup string // cgroup of the process, empty if no cgroup is used
+}
+
+// sandbox turns a command into the sandbox init, which sets up namespaces, the root filesystem, the rlimits, the
+// cgroup and the user before it executes the command. The home directory of the process is writable.
+func (r *ProcessRunner) sandbox(cmd *exec.Cmd, run processRun, home string) (*processSandbox, error) {
+	sandbox := &processSandbox{
+		cmd: cmd,
+		spec: sandboxSpec{
+			Command:   cmd.Args,
+			Env:       cmd.Env,
+			Dir:       cmd.Dir,
+			Mounts:    append(slices.Clone(run.Mounts), processMount{Path: home, Writable: true}),
+			TmpfsSize: run.Security.TmpfsSize,
+		},
+	}
+
+	if os.Getuid() == 0 {
+		credential, err := processCredential(run.Security.User)
+		if err != nil {
+			return nil, err
+		}
+		sandbox.spec.Credential = credential
+	}
+	if r.cgroup == "" && run.Limits.Pids > 0 {
+		// Counts the processes of every sandbox of the user, the cgroup limits the processes of this one only
+		sandbox.spec.Rlimits = append(sandbox.spec.Rlimits, sandboxRlimit{Resource: unix.RLIMIT_NPROC, Limit: uint64(run.Limits.Pids)})
+	}
+	if run.Security.TmpfsSize > 0 {
+		// Files are not larger than the /tmp of the process
+		sandbox.spec.Rlimits = append(sandbox.spec.Rlimits, sandboxRlimit{Resource: unix.RLIMIT_FSIZE, Limit: uint64(run.Security.TmpfsSize) * 1024 * 1024})
+	}
+
+	attr := &syscall.SysProcAttr{
+		// The process and its children are killed as a group
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if r.namespaces {
+		attr.Cloneflags = syscall.CLONE_NEWPID | syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS | syscall.CLONE_NEWNS
+		if os.Getuid() != 0 {
+			// Other users can only create namespaces inside a user namespace. The user of the judge is mapped to root,
+			// which has every capability inside of it to build the root filesystem. The capabilities are dropped
+			// before the command is executed.
+			attr.Cloneflags |= syscall.CLONE_NEWUSER
+			attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}}
+			attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}}
+			attr.GidMappingsEnableSetgroups = false
+		}
+
+		root, err := getTempDir("root-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create root dir: %v", err)
+		}
+		sandbox.spec.Root = root
+	}
+
+	// The sandbox init is the judge itself, it gets the command with the spec
+	cmd.Path, cmd.Args = "/proc/self/exe", []string{sandboxInit}
+	cmd.Env, cmd.Dir = []string{}, ""
+	cmd.SysProcAttr = attr
+	if r.cgroup == "" {
+		return sandbox, nil
+	}
+
+	cgroup, err := newCgroup(r.cgroup, run.Limits)
+	if err != nil {
+		sandbox.remove()
+		return nil, err
+	}
+	sandbox.cgroup, sandbox.spec.Cgroup = cgroup, cgroup
+	// Programs can read the usage of their own cgroup
+	sandbox.spec.Mounts = append(sandbox.spec.Mounts, processMount{Path: cgroup})
+	return sandbox, nil
+}
+
+// start starts the sandbox init and waits until it executed the command
+func (s *processSandbox) start() error {
+	specReader, specWriter, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pipe: %v", err)
+	}
+	defer specWriter.Close()
+	errorReader, errorWriter, err := os.Pipe()
+	if err != nil {
+		specReader.Close()
+		return fmt.Errorf("failed to create pipe: %v", err)
+	}
+	defer errorReader.Close()
+
+	s.cmd.ExtraFiles = []*os.File{specReader, errorWriter}
+	err = s.cmd.Start()
+	specReader.Close()
+	errorWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(specWriter).Encode(s.spec); err != nil {
+		s.kill()
+		s.cmd.Wait()
+		return fmt.Errorf("failed to send spec: %v", err)
+	}
+	specWriter.Close()
+
+	// The pipe is closed without an error once the command is executed
+	message, _ := io.ReadAll(errorReader)
+	if len(message) > 0 {
+		s.cmd.Wait()
+		return fmt.Errorf("failed to set up sandbox: %s", message)
+	}
+	return nil
+}
+
+// processCredential returns the credential of a numeric uid:gid user. An empty user keeps the user of the judge.
+func processCredential(user string) (*syscall.Credential, error) {
+	if user == "" {
+		return nil, nil
+	}
+
+	uidPart, gidPart, found := strings.Cut(user, ":")
+	if !found {
+		gidPart = uidPart
+	}
+	uid, err := strconv.ParseUint(uidPart, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("process runner requires a numeric user: %s", user)
+	}
+	gid, err := strconv.ParseUint(gidPart, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("process runner requires a numeric group: %s", user)
+	}
+
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: []uint32{}}, nil
+}
+
+// kill kills every process of the sandbox
+func (s *processSandbox) kill() {
+	if s.cgroup != "" {
+		// Also reaches processes which left the process group
+		os.WriteFile(filepath.Join(s.cgroup, "cgroup.kill"), []byte("1"), 0)
+	}
+	// The init is not in the cgroup until it executed the command
+	if s.cmd.Process != nil {
+		syscall.Kill(-s.cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+// measure stores the CPU time, peak memory and signal of the exited process in the result
+func (s *processSandbox) measure(result *models.ExecutionResult, state *os.ProcessState) {
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// The usage includes every child the process waited for
+		result.CPUTime = time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+		result.MemoryPeak = usage.Maxrss * 1024
+	}
+
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		result.Signal = int64(status.Signal())
+		result.ExitCode = 128 + result.Signal
+	} else if result.ExitCode > 128 {
+		// The shell reports processes killed by a signal as 128 + signal number
+		result.Signal = result.ExitCode - 128
+	}
+
+	if s.cgroup == "" {
+		return
+	}
+	// The cgroup also counts memory of processes the shell did not wait for
+	if peak, err := os.ReadFile(filepath.Join(s.cgroup, "memory.peak")); err == nil {
+		if value, err := strconv.ParseInt(strings.TrimSpace(string(peak)), 10, 64); err == nil {
+			result.MemoryPeak = value
+		}
+	}
+	if events, err := os.ReadFile(filepath.Join(s.cgroup, "memory.events")); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(events)))
+		for scanner.Scan() {
+			if count, found := strings.CutPrefix(scanner.Text(), "oom_kill "); found && count != "0" {
+				result.OOMKilled = true
+			}
+		}
+	}
+}
+
+// remove kills the remaining processes of the sandbox and removes its root dir and cgroup
+func (s *processSandbox) remove() {
+	s.kill()
+	if s.spec.Root != "" {
+		// The root filesystem is only mounted in the mount namespace of the process
+		os.Remove(s.spec.Root)
+	}
+	if s.cgroup == "" {
+		return
+	}
+
+	// A cgroup can only be removed once its processes are gone
+	for i := 0; ; i++ {
+		err := os.Remove(s.cgroup)
+		if err == nil {
+			return
+		}
+		if i == 50 {
+			log.WithField("Cgroup", s.cgroup).WithError(err).Error("Failed to remove cgroup")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// prepareCgroup enables the controllers used by the judge for the children of a delegated cgroup v2 directory
+func prepareCgroup(dir string) error {
+	available, err := os.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("not a cgroup v2 directory: %v", err)
+	}
+
+	controllers := strings.Fields(string(available))
+	for _, controller := range cgroupControllers {
+		if !slices.Contains(controllers, controller) {
+			log.WithField("Controller", controller).Warn("cgroup controller is not delegated, limit is not enforced")
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0); err != nil {
+			return fmt.Errorf("failed to enable %s controller: %v", controller, err)
+		}
+	}
+	return nil
+}
+
+// newCgroup creates a cgroup for a single process below the delegated cgroup
+func newCgroup(parent string, limits models.Limits) (string, error) {
+	cgroup, err := os.MkdirTemp(parent, "judge-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cgroup: %v", err)
+	}
+
+	files := make(map[string]string)
+	if limits.Memory > 0 {
+		files["memory.max"] = strconv.FormatInt(limits.Memory*1024*1024, 10)
+		files["memory.swap.max"] = "0" // disable swap
+	}
+	if limits.Pids > 0 {
+		files["pids.max"] = strconv.FormatInt(limits.Pids, 10)
+	}
+	if limits.CPUs > 0 {
+		files["cpu.max"] = fmt.Sprintf("%d 100000", int64(limits.CPUs*100000))
+	}
+
+	for name, value := range files {
+		err := os.WriteFile(filepath.Join(cgroup, name), []byte(value), 0)
+		// Swap accounting is optional
+		if os.IsNotExist(err) && name == "memory.swap.max" {
+			continue
+		}
+		if err != nil {
+			os.Remove(cgroup)
+			return "", fmt.Errorf("failed to set %s: %v", name, err)
+		}
+	}
+	return cgroup, nil
+}
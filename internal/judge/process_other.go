@@ -0,0 +1,37 @@
+//go:build !linux
+
+package judge
+
+import (
+	"errors"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"os"
+	"os/exec"
+)
+
+// errProcessUnsupported is returned on systems without the namespaces and cgroups the process runner needs
+var errProcessUnsupported = errors.New("the process runner requires Linux")
+
+// processSandbox isolates and limits a single process
+type processSandbox struct{}
+
+// InitSandbox returns immediately, processes are only sandboxed on Linux
+func InitSandbox() {}
+
+func (r *ProcessRunner) sandbox(cmd *exec.Cmd, run processRun, home string) (*processSandbox, error) {
+	return nil, errProcessUnsupported
+}
+
+func (s *processSandbox) start() error {
+	return errProcessUnsupported
+}
+
+func (s *processSandbox) kill() {}
+
+func (s *processSandbox) measure(result *models.ExecutionResult, state *os.ProcessState) {}
+
+func (s *processSandbox) remove() {}
+
+func prepareCgroup(dir string) error {
+	return errProcessUnsupported
+}
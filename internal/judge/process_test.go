@@ -0,0 +1,576 @@
+package judge
+
+import (
+	"context"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/language"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// processLanguages do not depend on any toolchain, the compiled language copies the script
+const processLanguages = `
+languages:
+  - name: shell
+    patterns: ["solution.sh"]
+    run: sh {file}
+  - name: copied
+    patterns: ["solution.copy"]
+    compile: cp {file} {build}/solution
+    run: sh {build}/solution
+`
+
+const processSolution = `read a b
+case "$a" in
+  loop) while :; do :; done ;;
+  fail) echo boom >&2; exit 3 ;;
+  *) echo $((a + b)) ;;
+esac
+`
+
+const processConfig = `
+name: "Sum"
+cases:
+  - input: "1 2"
+    expected: "3"
+  - input: "2 2"
+    expected: "5"
+  - input: "loop"
+    expected: "0"
+    limits:
+      wall_time: 1
+  - input: "fail"
+    expected: "0"
+`
+
+func TestMain(m *testing.M) {
+	// The process runner starts the test binary as sandbox init
+	InitSandbox()
+	os.Exit(m.Run())
+}
+
+func newTestProcessRunner(t *testing.T, namespaces bool) *ProcessRunner {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires Linux")
+	}
+	runner, err := NewProcessRunner(models.Limits{WallTime: 10, Output: 64, Stderr: 64}, models.Security{}, "", namespaces)
+	require.NoError(t, err)
+	return runner
+}
+
+func TestProcessRunnerJudge(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+	languages, err := language.Parse([]byte(processLanguages))
+	require.NoError(t, err)
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(taskDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(processConfig), 0644))
+
+	for _, file := range []string{"solution.sh", "solution.copy"} {
+		t.Run(file, func(t *testing.T) {
+			repoDir := t.TempDir()
+			solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+			require.NoError(t, os.MkdirAll(solutionDir, 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(solutionDir, file), []byte(processSolution), 0644))
+
+			testCases, err := LoadTestCases(taskDir)
+			require.NoError(t, err)
+			for i := range testCases {
+				testCases[i].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+				testCases[i].RepositoryDir = repoDir
+			}
+
+			executor := NewExecutor(runner, testCaseDir, languages)
+			results, err := executor.judge(context.Background(), testCases, log.Fields{})
+			require.NoError(t, err)
+			require.Len(t, results, 4)
+
+			assert.Equal(t, status.StatusPassed, results[0].Status, results[0].Error)
+			assert.Equal(t, processEnvironment, results[0].Image)
+			assert.Equal(t, status.StatusWrongAnswer, results[1].Status, results[1].Error)
+			assert.Equal(t, status.StatusTimeLimitExceeded, results[2].Status, results[2].Error)
+			assert.Equal(t, status.StatusRuntimeError, results[3].Status, results[3].Error)
+			assert.Equal(t, "boom", results[3].Stderr)
+		})
+	}
+}
+
+const processFilesSolution = `sum=0
+while IFS=, read name amount; do sum=$((sum + amount)); done < sales.csv
+echo "$1: $GREETING" > report.txt
+echo "total=$sum" >> report.txt
+`
+
+const processFilesConfig = `
+name: "Report"
+cases:
+  - args: ["Sales report"]
+    env:
+      GREETING: "hello"
+    files: ["data/sales.csv"]
+    expected_files:
+      - name: report.txt
+        expected: |
+          Sales report: hello
+          total=5
+  - files: ["data/sales.csv"]
+    expected_files:
+      - name: report.txt
+        expected: "total=5"
+  - files: ["data/sales.csv"]
+    expected_files:
+      - name: summary.txt
+        expected: "total=5"
+`
+
+func TestProcessRunnerCaseFiles(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+	languages, err := language.Parse([]byte(processLanguages))
+	require.NoError(t, err)
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(filepath.Join(taskDir, "data"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(processFilesConfig), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "data", "sales.csv"), []byte("apple,2\npear,3\n"), 0644))
+
+	repoDir := t.TempDir()
+	solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(solutionDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sh"), []byte(processFilesSolution), 0644))
+
+	testCases, err := LoadTestCases(taskDir)
+	require.NoError(t, err)
+	for i := range testCases {
+		testCases[i].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+		testCases[i].RepositoryDir = repoDir
+	}
+
+	executor := NewExecutor(runner, testCaseDir, languages)
+	results, err := executor.judge(context.Background(), testCases, log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, status.StatusPassed, results[0].Status, results[0].Error)
+	assert.Equal(t, status.StatusWrongAnswer, results[1].Status)
+	assert.True(t, strings.HasPrefix(results[1].Error, "report.txt: "), results[1].Error)
+	assert.Equal(t, status.StatusWrongAnswer, results[2].Status)
+	assert.Equal(t, "Output file summary.txt was not written", results[2].Error)
+
+	// The program ran in a working directory of its own
+	_, err = os.Stat(filepath.Join(solutionDir, "report.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+const processUnitSolution = `add() { echo $(($1 + $2)); }
+mul() { echo $(($1 + $2)); }
+`
+
+// processUnitTests prints a JUnit report for the functions of the solution
+const processUnitTests = `. ./solution.sh
+check() {
+  if [ "$($2)" = "$3" ]; then
+    echo "<testcase name=\"$1\"/>"
+  else
+    echo "<testcase name=\"$1\"><failure message=\"$2 is not $3\"/></testcase>"
+  fi
+}
+echo "<testsuite>"
+check test_add "add 1 2" 3
+check test_mul "mul 2 3" 6
+check test_extra "add 0 0" 0
+echo "</testsuite>"
+`
+
+const processUnitConfig = `
+name: "Functions"
+type: unit
+unit:
+  framework: junit
+  command: sh run_tests.sh > "$UNIT_REPORT"
+  files: ["tests/run_tests.sh"]
+  tests:
+    - name: test_add
+      points: 2
+    - name: test_mul
+      hidden: true
+    - name: test_div
+`
+
+func TestProcessRunnerUnitTests(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+	languages, err := language.Parse([]byte(processLanguages))
+	require.NoError(t, err)
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(filepath.Join(taskDir, "tests"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(processUnitConfig), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "tests", "run_tests.sh"), []byte(processUnitTests), 0644))
+
+	repoDir := t.TempDir()
+	solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(solutionDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sh"), []byte(processUnitSolution), 0644))
+	// Reports in the output or the checkout of the solution are ignored
+	forged := `echo '<testsuite><testcase name="test_div"/></testsuite>'` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "run_tests.sh"), []byte(forged), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, unitReport), []byte(forged), 0644))
+
+	testCases, err := LoadTestCases(taskDir)
+	require.NoError(t, err)
+	require.Len(t, testCases, 1)
+	testCases[0].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+	testCases[0].RepositoryDir = repoDir
+
+	executor := NewExecutor(runner, testCaseDir, languages)
+	results, err := executor.judge(context.Background(), testCases, log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// test_extra is not configured and therefore not scored
+	names := make([]string, 0, len(results))
+	for i, result := range results {
+		assert.Equal(t, i+1, result.TestNumber)
+		names = append(names, result.Name)
+	}
+	assert.Equal(t, []string{"test_add", "test_mul", "test_div"}, names)
+
+	assert.Equal(t, status.StatusPassed, results[0].Status, results[0].Error)
+	assert.Equal(t, float64(2), results[0].Points)
+	assert.Equal(t, status.StatusWrongAnswer, results[1].Status)
+	assert.Equal(t, "mul 2 3 is not 6", results[1].Error)
+	assert.True(t, results[1].IsHidden)
+	assert.Equal(t, status.StatusWrongAnswer, results[2].Status)
+	assert.Equal(t, "Test was not run", results[2].Error)
+
+	scores := models.ComputeScores(results)
+	require.Len(t, scores, 1)
+	assert.Equal(t, float64(2), scores[0].Score)
+	assert.Equal(t, float64(4), scores[0].MaxScore)
+
+	// The test files were added to a copy of the solution
+	data, err := os.ReadFile(filepath.Join(solutionDir, "run_tests.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, forged, string(data))
+
+	// A solution which breaks the tests fails every configured test
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sh"), []byte("exit 1"), 0644))
+	results, err = executor.judge(context.Background(), testCases, log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.Equal(t, status.StatusCompileError, result.Status, result.Error)
+	}
+
+	// Test files which cannot be copied are a judge error of the task
+	require.NoError(t, os.Remove(filepath.Join(taskDir, "tests", "run_tests.sh")))
+	results, err = executor.judge(context.Background(), testCases, log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.Equal(t, status.StatusError, result.Status)
+		assert.Contains(t, result.Error, "failed to read test file")
+	}
+}
+
+// processInteractor lets the solution guess the number of the input in at most three guesses
+const processInteractor = `read secret < "$1"
+n=0
+while read guess; do
+  n=$((n + 1))
+  if [ "$n" -gt 3 ]; then echo "too many guesses" >&2; exit 1; fi
+  if [ "$guess" -lt "$secret" ]; then echo higher
+  elif [ "$guess" -gt "$secret" ]; then echo lower
+  else echo correct; exit 0; fi
+done
+echo "no guess" >&2
+exit 1
+`
+
+const processGuesser = `lo=1
+hi=7
+while :; do
+  mid=$(((lo + hi) / 2))
+  echo "$mid"
+  read answer
+  case "$answer" in
+    higher) lo=$((mid + 1)) ;;
+    lower) hi=$((mid - 1)) ;;
+    *) exit 0 ;;
+  esac
+done
+`
+
+const processInteractiveConfig = `
+name: "Guess"
+type: interactive
+interactor: interactor.sh
+cases:
+  - input: "3"
+  - input: "5"
+  - input: "4"
+    env:
+      SLOW: "1"
+    limits:
+      wall_time: 1
+hidden_cases:
+  - input: "9"
+`
+
+func TestProcessRunnerInteractive(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+	languages, err := language.Parse([]byte(processLanguages))
+	require.NoError(t, err)
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(taskDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(processInteractiveConfig), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "interactor.sh"), []byte(processInteractor), 0644))
+
+	repoDir := t.TempDir()
+	solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(solutionDir, 0755))
+	// The third case never gets an answer and exceeds the time limit of the pair
+	solution := `[ -n "$SLOW" ] && sleep 5` + "\n" + processGuesser
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sh"), []byte(solution), 0644))
+
+	testCases, err := LoadTestCases(taskDir)
+	require.NoError(t, err)
+	for i := range testCases {
+		testCases[i].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+		testCases[i].RepositoryDir = repoDir
+	}
+
+	executor := NewExecutor(runner, testCaseDir, languages)
+	results, err := executor.judge(context.Background(), testCases, log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	assert.Equal(t, status.StatusPassed, results[0].Status, results[0].Error)
+	assert.Equal(t, "> 4\n< lower\n> 2\n< higher\n> 3\n< correct", results[0].Transcript)
+	assert.Equal(t, status.StatusPassed, results[1].Status, results[1].Error)
+	assert.Equal(t, status.StatusTimeLimitExceeded, results[2].Status, results[2].Error)
+	assert.Equal(t, status.StatusWrongAnswer, results[3].Status)
+	assert.Equal(t, "too many guesses", results[3].Error)
+	assert.Empty(t, results[3].Transcript, "transcripts of hidden cases are not kept")
+}
+
+func TestProcessRunnerInteractiveIsolation(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires Linux")
+	}
+	runner, err := NewProcessRunner(models.Limits{WallTime: 10, Output: 64, Stderr: 64}, models.Security{User: "65534:65534"}, "", true)
+	require.NoError(t, err)
+	if _, err := runner.SelfTest(context.Background()); err != nil && strings.Contains(err.Error(), "operation not permitted") {
+		t.Skip("namespaces are not available")
+	}
+	languages, err := language.Parse([]byte(processLanguages))
+	require.NoError(t, err)
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(taskDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(processInteractiveConfig), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "interactor.sh"), []byte(processInteractor), 0644))
+
+	// The solution guesses right at once if it can read the input of the interactor
+	repoDir := t.TempDir()
+	solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(solutionDir, 0755))
+	input := filepath.Join(os.TempDir(), "interactor-*", "input.txt")
+	solution := "secret=$(cat " + input + " 2>/dev/null)\necho \"${secret:-1}\"\nread answer\n[ \"$answer\" = correct ]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sh"), []byte(solution), 0644))
+
+	testCases, err := LoadTestCases(taskDir)
+	require.NoError(t, err)
+	testCases[0].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+	testCases[0].RepositoryDir = repoDir
+
+	executor := NewExecutor(runner, testCaseDir, languages)
+	results, err := executor.judge(context.Background(), testCases[:1], log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, status.StatusWrongAnswer, results[0].Status, results[0].Error)
+	assert.Equal(t, "> 1\n< higher", results[0].Transcript)
+
+	// The interactor is not copied next to the solution
+	entries, err := os.ReadDir(solutionDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+const processSQLConfig = `
+name: "Orders"
+type: sql
+sql:
+  dataset: orders.sql
+  ignore_order: true
+cases:
+  - expected: |
+      name,total
+      Bob,4
+      Alice,5
+  - input: "INSERT INTO orders VALUES ('Carol', 1);"
+    expected: |
+      name,total
+      Alice,5
+      Bob,4
+hidden_cases:
+  - dataset: empty.sql
+    expected: |
+      name,total
+`
+
+func TestProcessRunnerSQL(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("requires sqlite3")
+	}
+
+	// The builtin language with the absolute path of sqlite3, the sandbox has its own search path
+	builtin, err := language.Load("")
+	require.NoError(t, err)
+	sql := *builtin.Get("sql")
+	sql.Run = strings.Replace(sql.Run, "sqlite3", sqlite, 1)
+	languages := &language.Registry{Languages: []models.Language{sql}}
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(taskDir, 0755))
+	files := map[string]string{
+		"config.yaml": processSQLConfig,
+		"orders.sql":  "CREATE TABLE orders(name TEXT, total INT);\nINSERT INTO orders VALUES ('Alice', 2), ('Bob', 4), ('Alice', 3);",
+		"empty.sql":   "CREATE TABLE customers(name TEXT);",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(taskDir, name), []byte(content), 0644))
+	}
+
+	repoDir := t.TempDir()
+	solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(solutionDir, 0755))
+	query := "SELECT name, SUM(total) AS total FROM orders GROUP BY name;\n"
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sql"), []byte(query), 0644))
+
+	testCases, err := LoadTestCases(taskDir)
+	require.NoError(t, err)
+	for i := range testCases {
+		testCases[i].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+		testCases[i].RepositoryDir = repoDir
+	}
+
+	executor := NewExecutor(runner, testCaseDir, languages)
+	results, err := executor.judge(context.Background(), testCases, log.Fields{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, status.StatusPassed, results[0].Status, results[0].Error)
+	assert.Nil(t, results[0].ResultSet)
+
+	assert.Equal(t, status.StatusWrongAnswer, results[1].Status)
+	assert.Equal(t, "Expected 2 rows, got 3", results[1].Error)
+	require.NotNil(t, results[1].ResultSet, "failed public cases show the result set")
+	assert.Equal(t, []string{"name", "total"}, results[1].ResultSet.Columns)
+	assert.Equal(t, 3, results[1].ResultSet.Total)
+
+	// The table of the query does not exist in the dataset
+	assert.Equal(t, status.StatusRuntimeError, results[2].Status)
+	assert.Nil(t, results[2].ResultSet)
+}
+
+func TestProcessRunnerCheckerCancel(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+	languages, err := language.Parse([]byte(processLanguages))
+	require.NoError(t, err)
+
+	testCaseDir := t.TempDir()
+	taskDir := filepath.Join(testCaseDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(taskDir, 0755))
+	config := "name: Sum\nchecker:\n  type: custom\n  program: checker.sh\ncases:\n  - input: \"1 2\"\n    expected: \"3\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(taskDir, "checker.sh"), []byte("sleep 30\n"), 0644))
+
+	repoDir := t.TempDir()
+	solutionDir := filepath.Join(repoDir, "workshop1", "task1")
+	require.NoError(t, os.MkdirAll(solutionDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(solutionDir, "solution.sh"), []byte(processSolution), 0644))
+
+	testCases, err := LoadTestCases(taskDir)
+	require.NoError(t, err)
+	for i := range testCases {
+		testCases[i].Solution = &models.Solution{Workshop: "workshop1", Task: "task1"}
+		testCases[i].RepositoryDir = repoDir
+	}
+
+	// Cancelling the job stops the checker instead of waiting for its time limit
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	executor := NewExecutor(runner, testCaseDir, languages)
+	_, err = executor.judge(ctx, testCases, log.Fields{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestProcessRunnerCompileError(t *testing.T) {
+	runner := newTestProcessRunner(t, false)
+
+	repoDir := t.TempDir()
+	language := &models.Language{Name: "broken", Compile: "echo 'syntax error' >&2; exit 1", Run: "true"}
+	result, err := runner.Compile(context.Background(), repoDir, Program{Language: language, File: "solution", BuildDir: t.TempDir()})
+	require.NoError(t, err)
+
+	assert.True(t, result.CompileError)
+	assert.Equal(t, "syntax error", result.Output)
+}
+
+func TestProcessRunnerNamespaces(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("requires Linux")
+	}
+	enabled := true
+	security := models.Security{
+		User:         "65534:65534",
+		ReadOnlyRepo: &enabled,
+		Seccomp:      SeccompUnconfined,
+		TmpfsSize:    16,
+	}
+	runner, err := NewProcessRunner(models.Limits{WallTime: 10, Pids: 32, Output: 64, Stderr: 64}, security, "", true)
+	require.NoError(t, err)
+
+	checks, err := runner.SelfTest(context.Background())
+	if err != nil && strings.Contains(err.Error(), "operation not permitted") {
+		t.Skip("namespaces are not available")
+	}
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(checks))
+	for _, check := range checks {
+		names = append(names, check.Name)
+		assert.True(t, check.Passed, "%s: expected %s, got %s", check.Name, check.Expected, check.Actual)
+	}
+	assert.Subset(t, names, []string{"Own /proc", "Read-only root filesystem", "Host filesystem hidden", "Read-only repository", "No new privileges", "Process limit", "File size limit"})
+}
+
+func TestProcessRunnerUnenforcedSecurity(t *testing.T) {
+	runner := &ProcessRunner{namespaces: false}
+
+	unenforced := runner.unenforcedSecurity(models.Security{User: "65534:65534", CapAdd: []string{"NET_RAW"}, Seccomp: SeccompDefault})
+	assert.Contains(t, unenforced, "capabilities cannot be added, every capability is dropped")
+	assert.Contains(t, unenforced, "seccomp profiles are not supported, processes run unconfined")
+	assert.Contains(t, unenforced, "namespaces are disabled, processes see the filesystem, processes and network of the host")
+}
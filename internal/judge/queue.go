@@ -0,0 +1,279 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrQueueFull   = errors.New("submission queue is full")
+	ErrQueueClosed = errors.New("submission queue is closed")
+)
+
+const queueKeyPrefix = "queue:"
+
+// jobQueue is a bounded in-memory scheduler for jobs. Jobs of a higher priority class are always started first.
+// Within a class the users take turns, so a user with many queued jobs does not delay everyone else.
+// Jobs of the same user are started in the order they were queued.
+type jobQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	classes  map[models.JobPriority]*userQueues
+	size     int
+	capacity int
+	closed   bool
+
+	// maxRunningPerUser limits the jobs of a single user which are processed at the same time. 0 means unlimited.
+	maxRunningPerUser int
+	running           map[string]int
+}
+
+// userQueues holds the jobs of one priority class
+type userQueues struct {
+	users []string // round-robin order, the next user to be served is first
+	jobs  map[string][]models.Job
+}
+
+func newJobQueue(capacity int, maxRunningPerUser int) *jobQueue {
+	q := &jobQueue{
+		classes:           make(map[models.JobPriority]*userQueues),
+		capacity:          capacity,
+		maxRunningPerUser: maxRunningPerUser,
+		running:           make(map[string]int),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds a job to the queue without blocking
+func (q *jobQueue) push(job models.Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if q.size >= q.capacity {
+		return ErrQueueFull
+	}
+
+	class, exists := q.classes[job.Priority]
+	if !exists {
+		class = &userQueues{jobs: make(map[string][]models.Job)}
+		q.classes[job.Priority] = class
+	}
+
+	user := job.Submission.User()
+	if len(class.jobs[user]) == 0 {
+		class.users = append(class.users, user)
+	}
+	class.jobs[user] = append(class.jobs[user], job)
+	q.size++
+
+	q.cond.Broadcast()
+	return nil
+}
+
+// pop blocks until a job can be started and counts it as running for its user until done is called.
+// It returns false if the queue was closed and is empty.
+func (q *jobQueue) pop() (models.Job, bool) {
+	return q.popContext(context.Background())
+}
+
+// popContext works like pop but gives up once ctx is done
+func (q *jobQueue) popContext(ctx context.Context) (models.Job, bool) {
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		q.cond.Broadcast()
+	})
+	defer stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if ctx.Err() != nil {
+			return models.Job{}, false
+		}
+		if job, ok := q.next(); ok {
+			q.running[job.Submission.User()]++
+			return job, true
+		}
+		if q.closed && q.size == 0 {
+			return models.Job{}, false
+		}
+		q.cond.Wait()
+	}
+}
+
+// next removes the next job which may be started. The caller must hold the lock.
+func (q *jobQueue) next() (models.Job, bool) {
+	priorities := make([]models.JobPriority, 0, len(q.classes))
+	for priority := range q.classes {
+		priorities = append(priorities, priority)
+	}
+	sort.Slice(priorities, func(i, j int) bool {
+		return priorities[i] > priorities[j]
+	})
+
+	for _, priority := range priorities {
+		class := q.classes[priority]
+		for i, user := range class.users {
+			if q.maxRunningPerUser > 0 && q.running[user] >= q.maxRunningPerUser {
+				continue
+			}
+
+			job := class.jobs[user][0]
+			class.jobs[user] = class.jobs[user][1:]
+
+			// Move the user to the end of the round
+			class.users = append(class.users[:i], class.users[i+1:]...)
+			if len(class.jobs[user]) > 0 {
+				class.users = append(class.users, user)
+			} else {
+				delete(class.jobs, user)
+			}
+			if len(class.users) == 0 {
+				delete(q.classes, priority)
+			}
+
+			q.size--
+			return job, true
+		}
+	}
+
+	return models.Job{}, false
+}
+
+// done marks a job returned by pop as finished
+func (q *jobQueue) done(job models.Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	user := job.Submission.User()
+	q.running[user]--
+	if q.running[user] <= 0 {
+		delete(q.running, user)
+	}
+	q.cond.Broadcast()
+}
+
+// removeWhere removes and returns all queued jobs matching the predicate
+func (q *jobQueue) removeWhere(match func(job models.Job) bool) []models.Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	removed := make([]models.Job, 0)
+	for priority, class := range q.classes {
+		users := class.users[:0]
+		for _, user := range class.users {
+			kept := make([]models.Job, 0, len(class.jobs[user]))
+			for _, job := range class.jobs[user] {
+				if match(job) {
+					removed = append(removed, job)
+				} else {
+					kept = append(kept, job)
+				}
+			}
+
+			if len(kept) == 0 {
+				delete(class.jobs, user)
+				continue
+			}
+			class.jobs[user] = kept
+			users = append(users, user)
+		}
+
+		class.users = users
+		if len(class.users) == 0 {
+			delete(q.classes, priority)
+		}
+	}
+
+	q.size -= len(removed)
+	return removed
+}
+
+func (q *jobQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+func (q *jobQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// queueStore persists jobs in the database. A store without database does nothing.
+type queueStore struct {
+	db *badger.DB
+}
+
+func (s *queueStore) save(job models.Job) error {
+	if s.db == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set([]byte(queueKeyPrefix+job.ID), data)
+	})
+}
+
+func (s *queueStore) delete(id string) error {
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(queueKeyPrefix + id))
+	})
+}
+
+// list returns all persisted jobs ordered by the time they were enqueued
+func (s *queueStore) list() ([]models.Job, error) {
+	jobs := make([]models.Job, 0)
+	if s.db == nil {
+		return jobs, nil
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(queueKeyPrefix)
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var job models.Job
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &job)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to decode job %s: %v", it.Item().Key(), err)
+			}
+			jobs = append(jobs, job)
+		}
+		return nil
+	})
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].EnqueuedAt.Before(jobs[j].EnqueuedAt)
+	})
+
+	return jobs, err
+}
@@ -0,0 +1,87 @@
+package judge
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func queuedJob(id string, user string, priority models.JobPriority) models.Job {
+	return models.Job{
+		ID:         id,
+		Submission: models.Submission{RepoName: "org/" + user},
+		Priority:   priority,
+	}
+}
+
+func popIDs(q *jobQueue, n int) []string {
+	ids := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		job, ok := q.pop()
+		if !ok {
+			break
+		}
+		ids = append(ids, job.ID)
+		q.done(job)
+	}
+	return ids
+}
+
+func TestJobQueueRoundRobin(t *testing.T) {
+	q := newJobQueue(10, 0)
+	for _, job := range []models.Job{
+		queuedJob("a1", "alice", models.PriorityNormal),
+		queuedJob("a2", "alice", models.PriorityNormal),
+		queuedJob("a3", "alice", models.PriorityNormal),
+		queuedJob("b1", "bob", models.PriorityNormal),
+		queuedJob("c1", "carol", models.PriorityNormal),
+		queuedJob("b2", "bob", models.PriorityNormal),
+	} {
+		assert.NoError(t, q.push(job))
+	}
+
+	assert.Equal(t, []string{"a1", "b1", "c1", "a2", "b2", "a3"}, popIDs(q, 6))
+	assert.Equal(t, 0, q.len())
+}
+
+func TestJobQueuePriority(t *testing.T) {
+	q := newJobQueue(10, 0)
+	assert.NoError(t, q.push(queuedJob("low", "alice", models.PriorityLow)))
+	assert.NoError(t, q.push(queuedJob("normal", "alice", models.PriorityNormal)))
+	assert.NoError(t, q.push(queuedJob("high", "bob", models.PriorityHigh)))
+
+	assert.Equal(t, []string{"high", "normal", "low"}, popIDs(q, 3))
+}
+
+func TestJobQueueMaxRunningPerUser(t *testing.T) {
+	q := newJobQueue(10, 1)
+	assert.NoError(t, q.push(queuedJob("a1", "alice", models.PriorityNormal)))
+	assert.NoError(t, q.push(queuedJob("a2", "alice", models.PriorityNormal)))
+	assert.NoError(t, q.push(queuedJob("b1", "bob", models.PriorityNormal)))
+
+	first, _ := q.pop()
+	assert.Equal(t, "a1", first.ID)
+
+	// alice already has a running job, so bob is next even though alice is first in line again
+	second, _ := q.pop()
+	assert.Equal(t, "b1", second.ID)
+
+	q.done(first)
+	third, _ := q.pop()
+	assert.Equal(t, "a2", third.ID)
+}
+
+func TestJobQueueRemoveWhere(t *testing.T) {
+	q := newJobQueue(10, 0)
+	assert.NoError(t, q.push(queuedJob("a1", "alice", models.PriorityNormal)))
+	assert.NoError(t, q.push(queuedJob("a2", "alice", models.PriorityHigh)))
+	assert.NoError(t, q.push(queuedJob("b1", "bob", models.PriorityNormal)))
+
+	removed := q.removeWhere(func(job models.Job) bool {
+		return job.Submission.User() == "alice"
+	})
+
+	assert.Len(t, removed, 2)
+	assert.Equal(t, 1, q.len())
+	assert.Equal(t, []string{"b1"}, popIDs(q, 1))
+}
@@ -0,0 +1,250 @@
+package judge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dgraph-io/badger/v4"
+	appConfig "github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/db"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/gitea"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const submissionKeyPrefix = "submission:"
+
+// taskSubmissionKeyPrefix indexes the recorded submissions by workshop/task
+const taskSubmissionKeyPrefix = "task-submission:"
+
+// ErrUnknownCommit is returned if a commit to rejudge was never judged
+var ErrUnknownCommit = errors.New("commit was never judged")
+
+// saveSubmission records which tasks were judged for a commit, so the commit can be rejudged later
+func saveSubmission(submission models.Submission, scores []models.TaskScore) error {
+	submission.Solutions = make([]models.Solution, 0, len(scores))
+	for _, score := range scores {
+		submission.Solutions = append(submission.Solutions, score.Solution)
+	}
+	submission.Rejudge = false
+
+	data, err := json.Marshal(submission)
+	if err != nil {
+		return err
+	}
+
+	return db.DB.Update(func(txn *badger.Txn) error {
+		if err := txn.SetEntry(recordEntry(submissionKeyPrefix+submission.CommitID, data)); err != nil {
+			return err
+		}
+		for _, solution := range submission.Solutions {
+			if err := txn.SetEntry(recordEntry(taskSubmissionKey(solution)+submission.CommitID, nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// recordEntry returns the entry of a record which expires with the database TTL
+func recordEntry(key string, data []byte) *badger.Entry {
+	e := badger.NewEntry([]byte(key), data)
+	if appConfig.CFG.DatabaseTTL != 0 {
+		e = e.WithTTL(time.Hour * time.Duration(appConfig.CFG.DatabaseTTL))
+	}
+	return e
+}
+
+// taskSubmissionKey returns the prefix of the index entries of the workshop/task
+func taskSubmissionKey(solution models.Solution) string {
+	return taskSubmissionKeyPrefix + solution.Workshop + "/" + solution.Task + ":"
+}
+
+// loadTaskCommits returns the commits of all recorded submissions of the workshop/task
+func loadTaskCommits(solution models.Solution) ([]string, error) {
+	prefix := []byte(taskSubmissionKey(solution))
+	commits := make([]string, 0)
+
+	err := db.DB.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = prefix
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			commits = append(commits, string(it.Item().Key()[len(prefix):]))
+		}
+		return nil
+	})
+
+	return commits, err
+}
+
+// loadSubmission returns the recorded submission of a commit or nil if there is none
+func loadSubmission(commitID string) (*models.Submission, error) {
+	var submission models.Submission
+
+	err := db.DB.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(submissionKeyPrefix + commitID))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &submission)
+		})
+	})
+
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &submission, nil
+}
+
+// RejudgeCommit judges a commit again with the tasks it was judged for before
+func (p *Pool) RejudgeCommit(commitID string) (int, error) {
+	submission, err := p.rejudgeSubmission(commitID, nil)
+	if err != nil {
+		return 0, err
+	}
+	if submission == nil {
+		return 0, ErrUnknownCommit
+	}
+
+	if err := p.SubmitWithPriority(*submission, models.PriorityLow); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// RejudgeTask judges every recorded submission of the workshop/task again. Commits judged before submissions were
+// recorded are taken from the scoreboard, which only knows the best and the latest commit of every user.
+func (p *Pool) RejudgeTask(workshop, task string) (int, error) {
+	solution := models.Solution{Workshop: workshop, Task: task}
+
+	commits, err := loadTaskCommits(solution)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load submissions: %v", err)
+	}
+	recorded, err := p.scoreboardManager.TaskSubmissions(workshop, task)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load submissions: %v", err)
+	}
+
+	fallbacks := make(map[string]*models.ScoreboardUserSubmission)
+	for i, r := range recorded {
+		commits = append(commits, r.CommitID)
+		fallbacks[r.CommitID] = &recorded[i]
+	}
+
+	count := 0
+	seen := make(map[string]bool)
+	for _, commitID := range commits {
+		if seen[commitID] {
+			continue
+		}
+		seen[commitID] = true
+
+		submission, err := p.rejudgeSubmission(commitID, fallbacks[commitID])
+		if err != nil {
+			return count, err
+		}
+		if submission == nil {
+			continue
+		}
+
+		// The other tasks of the commit are not affected by a change of this task
+		submission.Solutions = []models.Solution{solution}
+		if err := p.SubmitWithPriority(*submission, models.PriorityLow); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// RejudgeHeads judges the latest commit of every repository of the organization for all tasks
+func (p *Pool) RejudgeHeads(org string) (int, error) {
+	client := gitea.NewGiteaClient(appConfig.CFG.GiteaURL, appConfig.CFG.GiteaToken)
+	heads, err := client.ListRepositoryHeads(org)
+	if err != nil {
+		return 0, err
+	}
+
+	tasks, err := FindAllTasks(p.executor.testCaseDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load tasks: %v", err)
+	}
+	solutions := make([]models.Solution, 0, len(tasks))
+	for _, task := range tasks {
+		solutions = append(solutions, models.Solution{Workshop: task.Workshop, Task: task.Task})
+	}
+
+	count := 0
+	for _, head := range heads {
+		err := p.SubmitWithPriority(models.Submission{
+			RepoName:   head.FullName,
+			CommitID:   head.CommitID,
+			BranchName: "refs/heads/" + head.Branch,
+			CloneURL:   head.CloneURL,
+			Solutions:  solutions,
+			Rejudge:    true,
+			GitClient:  client,
+		}, models.PriorityLow)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// rejudgeSubmission builds the submission to judge a commit again. Commits judged before submissions were
+// recorded are looked up in the scoreboard, the fallback is used for those if it is not nil.
+func (p *Pool) rejudgeSubmission(commitID string, fallback *models.ScoreboardUserSubmission) (*models.Submission, error) {
+	submission, err := loadSubmission(commitID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load submission %s: %v", commitID, err)
+	}
+
+	if submission == nil {
+		recorded, solutions, err := p.scoreboardManager.FindCommit(commitID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find commit %s: %v", commitID, err)
+		}
+		if recorded == nil {
+			recorded = fallback
+		}
+		if recorded == nil {
+			return nil, nil
+		}
+
+		// Records of older versions have no branch, the clone then checks out the default branch
+		submission = &models.Submission{
+			RepoName:   recorded.RepoName,
+			CommitID:   commitID,
+			BranchName: recorded.BranchName,
+			CloneURL:   recorded.CloneURL,
+			Solutions:  solutions,
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"Repo":   submission.RepoName,
+		"Commit": commitID,
+		"Branch": submission.BranchName,
+		"Tasks":  submission.Solutions,
+	}).Info("Rejudging commit")
+
+	submission.Rejudge = true
+	submission.GitClient = gitea.NewGiteaClient(appConfig.CFG.GiteaURL, appConfig.CFG.GiteaToken)
+	return submission, nil
+}
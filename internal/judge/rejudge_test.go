@@ -0,0 +1,86 @@
+package judge_test
+
+import (
+	"context"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/db"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/scoreboard"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// useMemoryDB replaces the database by an in-memory one for the duration of the test
+func useMemoryDB(t *testing.T) *badger.DB {
+	database, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	require.NoError(t, err)
+	previous := db.DB
+	db.DB = database
+	t.Cleanup(func() {
+		db.DB = previous
+		database.Close()
+	})
+	return database
+}
+
+func TestRejudgeTask(t *testing.T) {
+	_, client := newFakeGitea(t)
+	database := useMemoryDB(t)
+
+	sm := scoreboard.NewScoreboardManager(database)
+	pool := judge.NewPool(&judge.Executor{}, sm, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10})
+	defer pool.Stop()
+
+	task := models.Solution{Workshop: "workshop1", Task: "task1"}
+	other := models.Solution{Workshop: "workshop1", Task: "task2"}
+	result := func(solutions ...models.Solution) *models.TestResult {
+		testCases := make([]models.TestCaseResult, 0, len(solutions))
+		for i, solution := range solutions {
+			testCases = append(testCases, models.TestCaseResult{TestNumber: i + 1, Solution: solution, Status: status.StatusWrongAnswer, Points: 1})
+		}
+		return &models.TestResult{Status: status.StatusFailed, TestCases: testCases, Scores: models.ComputeScores(testCases)}
+	}
+
+	// Every judged commit is recorded, not only the best and the latest one
+	judged := []struct {
+		commit    string
+		solutions []models.Solution
+	}{
+		{"commit1", []models.Solution{task}},
+		{"commit2", []models.Solution{task, other}},
+		{"commit3", []models.Solution{task}},
+		{"unrelated", []models.Solution{other}},
+	}
+	for _, j := range judged {
+		require.NoError(t, pool.Submit(models.Submission{RepoName: "owner/alice", CommitID: j.commit, BranchName: "refs/heads/feature", GitClient: client}))
+		job := pool.Claim(context.Background(), "worker1", "10.0.0.1", 1)
+		require.NotNil(t, job)
+		require.NoError(t, pool.Complete("worker1", job.ID, result(j.solutions...), nil))
+	}
+
+	// Judged before submissions were recorded, only the scoreboard knows the commit
+	legacy := models.Submission{RepoName: "owner/bob", CommitID: "legacy", BranchName: "refs/heads/main"}
+	require.NoError(t, sm.ProcessTestResults(legacy, result(task).TestCases))
+
+	count, err := pool.RejudgeTask("workshop1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	branches := make(map[string]string)
+	for i := 0; i < count; i++ {
+		job := pool.Claim(context.Background(), "worker1", "10.0.0.1", 1)
+		require.NotNil(t, job)
+		assert.True(t, job.Submission.Rejudge)
+		assert.Equal(t, []models.Solution{task}, job.Submission.Solutions)
+		branches[job.Submission.CommitID] = job.Submission.BranchName
+	}
+	assert.Equal(t, map[string]string{
+		"commit1": "refs/heads/feature",
+		"commit2": "refs/heads/feature",
+		"commit3": "refs/heads/feature",
+		"legacy":  "refs/heads/main",
+	}, branches)
+}
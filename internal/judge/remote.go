@@ -0,0 +1,241 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+// Errors reported to remote workers
+var (
+	ErrJobNotAssigned = errors.New("job is not assigned to the worker")
+	ErrJobCancelled   = errors.New("job was cancelled")
+)
+
+// errWorkerLost is the cancel cause of jobs whose remote worker stopped sending heartbeats
+var errWorkerLost = errors.New("worker lost")
+
+// defaultHeartbeatInterval is used if lost workers are not detected
+const defaultHeartbeatInterval = 15 * time.Second
+
+// localWorker is the name of the in-process workers in the worker status
+const localWorker = "local"
+
+// Claim hands the next job to a remote worker. It waits until a job can be started or ctx is done, in which
+// case it returns nil.
+func (p *Pool) Claim(ctx context.Context, worker string, address string, capacity int) *models.Job {
+	p.seen(worker, address, capacity)
+
+	job, ok := p.queue.popContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	job, _ = p.markRunning(job, worker)
+	p.seen(worker, address, capacity)
+	log.WithFields(log.Fields{
+		"Job":    job.ID,
+		"Worker": worker,
+		"Repo":   job.Submission.RepoName,
+		"Commit": job.Submission.CommitID,
+	}).Info("Job claimed by worker")
+
+	if !p.begin(job.Submission) {
+		if running, ok := p.takeRunning(job.ID, worker); ok {
+			p.finished(running)
+		}
+		return nil
+	}
+	return &job
+}
+
+// Heartbeat keeps a job of a remote worker alive. It returns ErrJobCancelled if the worker should stop the job,
+// e.g. because it was superseded. The worker still has to complete the job.
+func (p *Pool) Heartbeat(worker string, id string) error {
+	p.runningMu.Lock()
+	defer p.runningMu.Unlock()
+	p.touch(worker)
+
+	running, exists := p.running[id]
+	if !exists || running.worker != worker {
+		return ErrJobNotAssigned
+	}
+	running.heartbeat = time.Now()
+	p.running[id] = running
+
+	if running.ctx.Err() != nil {
+		return ErrJobCancelled
+	}
+	return nil
+}
+
+// Complete stores and reports the result of a job judged by a remote worker. execErr is set if the worker could
+// not judge the job. It returns ErrJobNotAssigned if the job was requeued in between.
+func (p *Pool) Complete(worker string, id string, result *models.TestResult, execErr error) error {
+	running, ok := p.takeRunning(id, worker)
+	if !ok {
+		return ErrJobNotAssigned
+	}
+
+	if execErr == nil && result == nil {
+		execErr = errors.New("worker sent no result")
+	}
+	p.finish(running.ctx, running.job.Submission, result, execErr)
+	p.finished(running)
+
+	p.runningMu.Lock()
+	p.touch(worker)
+	if status, exists := p.workers[worker]; exists {
+		status.Completed++
+	}
+	p.runningMu.Unlock()
+
+	log.WithFields(log.Fields{
+		"Job":    id,
+		"Worker": worker,
+	}).Info("Job completed by worker")
+	return nil
+}
+
+// touch records a sign of life of a known remote worker. The caller must hold the lock.
+func (p *Pool) touch(worker string) {
+	if status, exists := p.workers[worker]; exists {
+		status.LastSeen = time.Now()
+	}
+}
+
+// seen records a claim of a remote worker
+func (p *Pool) seen(worker string, address string, capacity int) {
+	p.runningMu.Lock()
+	defer p.runningMu.Unlock()
+
+	status, exists := p.workers[worker]
+	if !exists {
+		log.WithFields(log.Fields{
+			"Worker":  worker,
+			"Address": address,
+		}).Info("Worker connected")
+		status = &models.WorkerStatus{Name: worker, Remote: true}
+		p.workers[worker] = status
+	}
+	status.Address = address
+	status.Capacity = capacity
+	status.LastSeen = time.Now()
+}
+
+// watchWorkers requeues the jobs of remote workers which stopped sending heartbeats
+func (p *Pool) watchWorkers() {
+	ticker := time.NewTicker(p.config.WorkerTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.requeueLost(now)
+		}
+	}
+}
+
+// requeueLost requeues the jobs of remote workers whose last heartbeat is older than the worker timeout. Jobs
+// which were started maxJobAttempts times are given up.
+func (p *Pool) requeueLost(now time.Time) {
+	p.runningMu.Lock()
+	lost := make([]runningJob, 0)
+	for id, running := range p.running {
+		if running.worker != "" && now.Sub(running.heartbeat) > p.config.WorkerTimeout {
+			lost = append(lost, running)
+			delete(p.running, id)
+		}
+	}
+	p.runningMu.Unlock()
+
+	for _, running := range lost {
+		job := running.job
+		fields := log.Fields{
+			"Job":      job.ID,
+			"Worker":   running.worker,
+			"Repo":     job.Submission.RepoName,
+			"Commit":   job.Submission.CommitID,
+			"Attempts": job.Attempts,
+		}
+
+		running.cancel(errWorkerLost)
+		p.queue.done(job)
+
+		if job.Attempts >= maxJobAttempts {
+			log.WithFields(fields).Warn("Worker lost, giving up job interrupted too often")
+			p.abandon(job)
+			continue
+		}
+
+		job.State = models.JobQueued
+		job.StartedAt = nil
+		job.Worker = ""
+		if err := p.queue.push(job); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to requeue job of lost worker")
+			p.abandon(job)
+			continue
+		}
+		if err := p.store.save(job); err != nil {
+			log.WithFields(fields).WithError(err).Error("Failed to persist requeued job")
+		}
+		log.WithFields(fields).Warn("Worker lost, requeued job")
+	}
+}
+
+// Workers returns the status of the in-process workers and of all remote workers seen since the start. A remote
+// worker is healthy if it was seen within four heartbeat intervals.
+func (p *Pool) Workers() []models.WorkerStatus {
+	p.runningMu.Lock()
+	defer p.runningMu.Unlock()
+
+	now := time.Now()
+	local := models.WorkerStatus{
+		Name:     localWorker,
+		Capacity: p.config.MaxWorkers,
+		Jobs:     make([]string, 0),
+		LastSeen: now,
+		Healthy:  true,
+	}
+	jobs := make(map[string][]string)
+	for id, running := range p.running {
+		if running.worker == "" {
+			local.Jobs = append(local.Jobs, id)
+		} else {
+			jobs[running.worker] = append(jobs[running.worker], id)
+		}
+	}
+
+	remote := make([]models.WorkerStatus, 0, len(p.workers))
+	for name, worker := range p.workers {
+		status := *worker
+		status.Jobs = append(make([]string, 0), jobs[name]...)
+		sort.Strings(status.Jobs)
+		status.Healthy = now.Sub(status.LastSeen) <= 4*p.HeartbeatInterval()
+		remote = append(remote, status)
+	}
+	sort.Slice(remote, func(i, j int) bool {
+		return remote[i].Name < remote[j].Name
+	})
+	sort.Strings(local.Jobs)
+
+	return append([]models.WorkerStatus{local}, remote...)
+}
+
+// HeartbeatInterval returns the interval in which remote workers have to send heartbeats for their jobs
+func (p *Pool) HeartbeatInterval() time.Duration {
+	if p.config.WorkerTimeout <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return max(p.config.WorkerTimeout/4, time.Second)
+}
+
+// WarmPoolStats returns the statistics of the warm container pool of the in-process workers, nil if there is none
+func (p *Pool) WarmPoolStats() *WarmPoolStats {
+	return p.executor.WarmPoolStats()
+}
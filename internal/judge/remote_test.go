@@ -0,0 +1,169 @@
+package judge_test
+
+import (
+	"context"
+	"errors"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/gitea"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/scoreboard"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitea records the descriptions of the commit statuses posted to it
+type fakeGitea struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func newFakeGitea(t *testing.T) (*fakeGitea, *gitea.GiteaClient) {
+	fake := &fakeGitea{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/version") {
+			w.Write([]byte(`{"version":"1.22.0"}`))
+			return
+		}
+		fake.mu.Lock()
+		fake.statuses = append(fake.statuses, r.URL.Path)
+		fake.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	config.CFG = &config.Config{BaseURL: "http://judge"}
+	return fake, gitea.NewGiteaClient(server.URL, "token")
+}
+
+func (f *fakeGitea) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.statuses)
+}
+
+func TestRemoteWorkerClaim(t *testing.T) {
+	fake, client := newFakeGitea(t)
+	pool := judge.NewPool(&judge.Executor{}, &scoreboard.ScoreboardManager{}, judge.PoolConfig{MaxWorkers: 0, QueueSize: 10})
+	defer pool.Stop()
+
+	// Nothing queued, the claim gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Nil(t, pool.Claim(ctx, "worker1", "10.0.0.1", 2))
+
+	require.NoError(t, pool.Submit(models.Submission{RepoName: "owner/repo", CommitID: "commit1", GitClient: client}))
+	job := pool.Claim(context.Background(), "worker1", "10.0.0.1", 2)
+	require.NotNil(t, job)
+	assert.Equal(t, "commit1", job.Submission.CommitID)
+	assert.Equal(t, "worker1", job.Worker)
+	assert.Equal(t, 1, fake.count(), "judge started is posted")
+
+	assert.NoError(t, pool.Heartbeat("worker1", job.ID))
+	assert.ErrorIs(t, pool.Heartbeat("worker2", job.ID), judge.ErrJobNotAssigned)
+
+	workers := pool.Workers()
+	require.Len(t, workers, 2)
+	assert.Equal(t, "local", workers[0].Name)
+	assert.Equal(t, "worker1", workers[1].Name)
+	assert.Equal(t, "10.0.0.1", workers[1].Address)
+	assert.Equal(t, 2, workers[1].Capacity)
+	assert.Equal(t, []string{job.ID}, workers[1].Jobs)
+	assert.True(t, workers[1].Healthy)
+
+	// A failed job is reported as internal error and not written to the database
+	require.NoError(t, pool.Complete("worker1", job.ID, nil, errors.New("clone failed")))
+	assert.Equal(t, 2, fake.count())
+	assert.ErrorIs(t, pool.Complete("worker1", job.ID, nil, errors.New("clone failed")), judge.ErrJobNotAssigned)
+
+	workers = pool.Workers()
+	assert.Empty(t, workers[1].Jobs)
+	assert.Equal(t, 1, workers[1].Completed)
+}
+
+func TestRemoteWorkerLost(t *testing.T) {
+	_, client := newFakeGitea(t)
+	pool := judge.NewPool(&judge.Executor{}, &scoreboard.ScoreboardManager{}, judge.PoolConfig{
+		MaxWorkers:    0,
+		QueueSize:     10,
+		WorkerTimeout: 100 * time.Millisecond,
+	})
+	defer pool.Stop()
+
+	require.NoError(t, pool.Submit(models.Submission{RepoName: "owner/repo", CommitID: "commit1", GitClient: client}))
+	job := pool.Claim(context.Background(), "worker1", "", 1)
+	require.NotNil(t, job)
+
+	// worker1 does not send heartbeats, so the job is handed to worker2
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	requeued := pool.Claim(ctx, "worker2", "", 1)
+	require.NotNil(t, requeued)
+	assert.Equal(t, job.ID, requeued.ID)
+	assert.Equal(t, 2, requeued.Attempts)
+
+	assert.ErrorIs(t, pool.Heartbeat("worker1", job.ID), judge.ErrJobNotAssigned)
+	assert.ErrorIs(t, pool.Complete("worker1", job.ID, nil, errors.New("late")), judge.ErrJobNotAssigned)
+	assert.NoError(t, pool.Heartbeat("worker2", job.ID))
+}
+
+func TestRemoteWorkerCancelled(t *testing.T) {
+	_, client := newFakeGitea(t)
+	pool := judge.NewPool(&judge.Executor{}, &scoreboard.ScoreboardManager{}, judge.PoolConfig{
+		MaxWorkers:       0,
+		QueueSize:        10,
+		SupersedeQueued:  true,
+		CancelSuperseded: true,
+	})
+	defer pool.Stop()
+
+	submission := models.Submission{RepoName: "owner/repo", BranchName: "main", CommitID: "commit1", GitClient: client}
+	require.NoError(t, pool.Submit(submission))
+	job := pool.Claim(context.Background(), "worker1", "", 1)
+	require.NotNil(t, job)
+
+	submission.CommitID = "commit2"
+	require.NoError(t, pool.Submit(submission))
+	assert.ErrorIs(t, pool.Heartbeat("worker1", job.ID), judge.ErrJobCancelled)
+	assert.NoError(t, pool.Complete("worker1", job.ID, nil, judge.ErrJobCancelled))
+}
+
+func TestWorkerClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get(models.WorkerHeader) != "worker1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Invalid worker token"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/worker/jobs/claim":
+			w.WriteHeader(http.StatusNoContent)
+		case "/worker/jobs/job1/heartbeat":
+			w.WriteHeader(http.StatusGone)
+		case "/worker/jobs/job2/heartbeat":
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.Write([]byte(`{"status":"ok"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := judge.NewWorkerClient(server.URL+"/", "secret", "worker1")
+	assignment, err := client.Claim(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Nil(t, assignment)
+
+	assert.ErrorIs(t, client.Heartbeat(context.Background(), "job1"), judge.ErrJobCancelled)
+	assert.ErrorIs(t, client.Heartbeat(context.Background(), "job2"), judge.ErrJobNotAssigned)
+	assert.NoError(t, client.Complete(context.Background(), "job3", &models.TestResult{}, nil))
+
+	_, err = judge.NewWorkerClient(server.URL, "wrong", "worker1").Claim(context.Background(), 1)
+	assert.ErrorContains(t, err, "Invalid worker token")
+}
@@ -0,0 +1,133 @@
+package judge
+
+import (
+	"context"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Backends which can run the programs of the judge
+const (
+	RunnerDocker  = "docker"
+	RunnerProcess = "process"
+)
+
+// Runner executes programs in a sandbox. The executor only uses this interface, so the sandbox can be a
+// container or a local process.
+type Runner interface {
+	// EffectiveLimits returns the limits a test case is executed with
+	EffectiveLimits(language *models.Language, limits models.Limits) models.Limits
+	// Environment makes the environment of a language available, e.g. by pulling its image. It returns the
+	// name and digest of the environment shown in the result.
+	Environment(ctx context.Context, language *models.Language) (string, string, error)
+	// Compile runs the compile command of the language
+	Compile(ctx context.Context, repositoryDir string, program Program) (*models.ExecutionResult, error)
+	// NewSession prepares a sandbox which compiles the program once and runs the test cases of a task
+	NewSession(ctx context.Context, repositoryDir string, program Program, limits models.Limits) (Session, error)
+	// RunCode runs a prepared program with the input of the test case on stdin
+	RunCode(ctx context.Context, testCase models.TestCase, program Program) (*models.ExecutionResult, error)
+	// RunInteractive runs a prepared program together with the interactor of the test case. The interactor runs in
+	// a sandbox of its own, which is the only one with access to the input of the case.
+	RunInteractive(ctx context.Context, testCase models.TestCase, program Program) (*models.ExecutionResult, error)
+	// RunChecker runs a custom checker program with the paths of the input, the actual and the expected output
+	RunChecker(ctx context.Context, program string, limits models.Limits, input, expected, actual string) (*models.ExecutionResult, error)
+	// SelfTest verifies that the configured limits and security profile are in force
+	SelfTest(ctx context.Context) ([]SelfTestCheck, error)
+}
+
+// Session runs every test case of a task after compiling the program once
+type Session interface {
+	Compile(ctx context.Context) (*models.ExecutionResult, error)
+	// Accepts reports if the test case can run in the session
+	Accepts(limits models.Limits) bool
+	Run(ctx context.Context, testCase models.TestCase) (*models.ExecutionResult, error)
+	Close()
+}
+
+// NewRunner creates the runner selected by RUNNER
+func NewRunner(cfg *config.Config) (Runner, error) {
+	switch cfg.Runner {
+	case RunnerDocker:
+		return NewDockerExecutor(cfg.DockerNetwork, cfg.DockerWorkspace, cfg.DockerWarmPool, DefaultLimits(cfg), DefaultSecurity(cfg))
+	case RunnerProcess:
+		return NewProcessRunner(DefaultLimits(cfg), DefaultSecurity(cfg), cfg.ProcessCgroup, cfg.ProcessNamespaces)
+	}
+	return nil, fmt.Errorf("unknown runner %s", cfg.Runner)
+}
+
+// effectiveLimits merges the limits of a test case into the limits of its language and the defaults
+func effectiveLimits(defaults models.Limits, language *models.Language, limits models.Limits) models.Limits {
+	if language != nil {
+		defaults = defaults.Merge(language.Limits)
+	}
+	return defaults.Merge(&limits)
+}
+
+// compileLimits returns the limits of a compilation. Compiler output is never truncated, it is needed to
+// understand the error.
+func compileLimits(limits models.Limits) models.Limits {
+	limits.Output = 0
+	limits.Stderr = 0
+	return limits
+}
+
+// compileResult marks a failed compilation as compile error. Compilers report errors on both streams, the
+// output of a compilation contains both.
+func compileResult(result *models.ExecutionResult, limits models.Limits) *models.ExecutionResult {
+	result.Output = strings.TrimSpace(result.Output + "\n" + result.Stderr)
+	result.Stderr = ""
+
+	switch {
+	case result.Error != "":
+	case result.TimedOut:
+		result.TimedOut = false
+		result.CompileError = true
+		result.Output = fmt.Sprintf("compilation exceeded the time limit of %gs", limits.WallTime)
+	case result.OOMKilled:
+		result.OOMKilled = false
+		result.CompileError = true
+		result.Output = fmt.Sprintf("compilation exceeded the memory limit of %d MB", limits.Memory)
+	case result.ExitCode != 0:
+		result.CompileError = true
+	}
+	return result
+}
+
+// writeChecker writes a custom checker program and its files to a directory and returns the command running
+// it. The directory is visible to the checker as dir.
+func writeChecker(tmpDir string, dir string, program string, input, expected, actual string) (string, error) {
+	files := map[string]string{
+		"input.txt":    input,
+		"expected.txt": expected,
+		"output.txt":   actual,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %v", name, err)
+		}
+	}
+
+	programData, err := os.ReadFile(program)
+	if err != nil {
+		return "", fmt.Errorf("failed to read checker: %v", err)
+	}
+	checkerName := "checker" + filepath.Ext(program)
+	if err := os.WriteFile(filepath.Join(tmpDir, checkerName), programData, 0755); err != nil {
+		return "", fmt.Errorf("failed to write checker: %v", err)
+	}
+
+	checker := path.Join(dir, checkerName)
+	args := fmt.Sprintf("%s %s %s", path.Join(dir, "input.txt"), path.Join(dir, "output.txt"), path.Join(dir, "expected.txt"))
+	switch filepath.Ext(program) {
+	case ".py":
+		return fmt.Sprintf("python3 %s %s", checker, args), nil
+	case ".go":
+		return fmt.Sprintf("GOCACHE=/tmp/gocache go run %s %s", checker, args), nil
+	}
+	return fmt.Sprintf("%s %s", checker, args), nil
+}
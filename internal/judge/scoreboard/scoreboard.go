@@ -31,8 +31,8 @@ func (sm *ScoreboardManager) ProcessTestResults(submission models.Submission, te
 	}
 	username := parts[1]
 
-	// Group test cases by workshop/task
-	taskResults := make(map[models.ScoreboardWorkshopTask]bool)
+	// Group test cases by workshop/task. The verdict of a task is the verdict of its first failed case.
+	taskResults := make(map[models.ScoreboardWorkshopTask]status.Status)
 	for _, tc := range testCases {
 		wt := models.ScoreboardWorkshopTask{
 			Workshop: tc.Solution.Workshop,
@@ -40,33 +40,40 @@ func (sm *ScoreboardManager) ProcessTestResults(submission models.Submission, te
 		}
 
 		if _, exists := taskResults[wt]; !exists {
-			taskResults[wt] = true
+			taskResults[wt] = status.StatusPassed
 		}
 
-		if tc.Status != status.StatusPassed {
-			taskResults[wt] = false
+		if tc.Status != status.StatusPassed && taskResults[wt] == status.StatusPassed {
+			taskResults[wt] = tc.Status
 		}
 	}
 
 	return sm.db.Update(func(txn *badger.Txn) error {
-		// Process each passed workshop/task
-		for wt, passed := range taskResults {
-			if !passed {
-				continue
+		for _, score := range models.ComputeScores(testCases) {
+			wt := models.ScoreboardWorkshopTask{
+				Workshop: score.Solution.Workshop,
+				Task:     score.Solution.Task,
 			}
 
 			userSubmission := models.ScoreboardUserSubmission{
-				RepoName:  submission.RepoName,
-				CommitID:  submission.CommitID,
-				CloneURL:  submission.CloneURL,
-				Timestamp: time.Now(),
+				RepoName:   submission.RepoName,
+				CommitID:   submission.CommitID,
+				BranchName: submission.BranchName,
+				CloneURL:   submission.CloneURL,
+				Timestamp:  time.Now(),
 			}
 
-			if err := sm.updateUserProgress(txn, username, wt, userSubmission); err != nil {
+			wasCompleted, completed, err := sm.updateUserProgress(txn, username, wt, userSubmission, taskResults[wt], score, submission.Rejudge)
+			if err != nil {
 				return err
 			}
 
-			if err := sm.updateWorkshopStats(txn, wt, userSubmission); err != nil {
+			// The workshop statistics only change if the user gained or lost the completion of the task
+			if wasCompleted == completed {
+				continue
+			}
+
+			if err := sm.updateWorkshopStats(txn, wt, userSubmission, completed); err != nil {
 				return err
 			}
 		}
@@ -74,13 +81,16 @@ func (sm *ScoreboardManager) ProcessTestResults(submission models.Submission, te
 	})
 }
 
-func (sm *ScoreboardManager) updateUserProgress(txn *badger.Txn, username string, wt models.ScoreboardWorkshopTask, submission models.ScoreboardUserSubmission) error {
+// updateUserProgress stores the latest attempt and the best score of the user for the workshop/task. The best score
+// is the best of all judged commits, a rejudged commit replaces its previous score even if it got worse. It returns
+// whether the task was completed with full score before and after the update.
+func (sm *ScoreboardManager) updateUserProgress(txn *badger.Txn, username string, wt models.ScoreboardWorkshopTask, submission models.ScoreboardUserSubmission, verdict status.Status, score models.TaskScore, rejudge bool) (bool, bool, error) {
 	userKey := []byte(fmt.Sprintf("user:%s", username))
 	var progress models.ScoreboardUserProgress
 
 	item, err := txn.Get(userKey)
 	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
-		return err
+		return false, false, err
 	}
 
 	if err == nil {
@@ -88,54 +98,169 @@ func (sm *ScoreboardManager) updateUserProgress(txn *badger.Txn, username string
 			return json.Unmarshal(val, &progress)
 		})
 		if err != nil {
-			return err
+			return false, false, err
 		}
 	} else {
 		progress = models.ScoreboardUserProgress{
-			User: username,
-			Submissions: []struct {
-				Workshop   string                          `json:"workshop"`
-				Task       string                          `json:"task"`
-				Submission models.ScoreboardUserSubmission `json:"submission"`
-			}{},
+			User:        username,
+			Submissions: []models.ScoreboardUserTask{},
 		}
 	}
 
-	// Check if this workshop/task already exists
+	attempt := models.ScoreboardAttempt{
+		Workshop:   wt.Workshop,
+		Task:       wt.Task,
+		Status:     verdict,
+		Score:      score.Score,
+		MaxScore:   score.MaxScore,
+		CommitID:   submission.CommitID,
+		RepoName:   submission.RepoName,
+		BranchName: submission.BranchName,
+		CloneURL:   submission.CloneURL,
+		Timestamp:  submission.Timestamp,
+	}
+
 	found := false
-	for i, s := range progress.Submissions {
-		if s.Workshop == wt.Workshop && s.Task == wt.Task {
-			progress.Submissions[i].Submission = submission
+	for i, a := range progress.Attempts {
+		if a.Workshop == wt.Workshop && a.Task == wt.Task {
 			found = true
+			// A rejudge of an older commit must not replace the latest attempt
+			if rejudge && a.CommitID != submission.CommitID {
+				break
+			}
+			if rejudge {
+				attempt.Timestamp = a.Timestamp
+			}
+			progress.Attempts[i] = attempt
 			break
 		}
 	}
 
 	if !found {
-		progress.Submissions = append(progress.Submissions, struct {
-			Workshop   string                          `json:"workshop"`
-			Task       string                          `json:"task"`
-			Submission models.ScoreboardUserSubmission `json:"submission"`
-		}{
-			Workshop:   wt.Workshop,
-			Task:       wt.Task,
-			Submission: submission,
-		})
+		progress.Attempts = append(progress.Attempts, attempt)
+	}
+
+	result := models.ScoreboardUserTask{
+		Workshop:   wt.Workshop,
+		Task:       wt.Task,
+		Score:      score.Score,
+		MaxScore:   score.MaxScore,
+		Submission: submission,
+	}
+
+	// Every judged commit keeps its own score, so a rejudge which lowers the best score falls back to the next
+	// best commit instead of losing it
+	commits, err := sm.commitScores(txn, username, wt)
+	if err != nil {
+		return false, false, err
+	}
+	if previous, exists := commits[submission.CommitID]; exists {
+		result.Submission.Timestamp = previous.Submission.Timestamp
+	}
+	commits[submission.CommitID] = result
+	if err := sm.saveCommitScore(txn, username, result); err != nil {
+		return false, false, err
+	}
+
+	wasCompleted := false
+	index := -1
+	for i, s := range progress.Submissions {
+		if s.Workshop == wt.Workshop && s.Task == wt.Task {
+			index = i
+			wasCompleted = s.Completed()
+			// Best scores recorded before the scores of the commits were kept
+			if _, exists := commits[s.Submission.CommitID]; !exists {
+				commits[s.Submission.CommitID] = s
+			}
+			break
+		}
+	}
+
+	var best *models.ScoreboardUserTask
+	for _, c := range commits {
+		if c.Score > 0 && (best == nil || betterScore(c, *best)) {
+			best = &c
+		}
+	}
+
+	completed := best != nil && best.Completed()
+	switch {
+	case best != nil && index >= 0:
+		progress.Submissions[index] = *best
+	case best != nil:
+		progress.Submissions = append(progress.Submissions, *best)
+	case index >= 0:
+		progress.Submissions = append(progress.Submissions[:index], progress.Submissions[index+1:]...)
 	}
 
 	data, err := json.Marshal(progress)
 	if err != nil {
-		return err
+		return false, false, err
 	}
 
 	e := badger.NewEntry(userKey, data)
 	if appConfig.CFG.DatabaseTTL != 0 {
 		e = e.WithTTL(time.Hour * time.Duration(appConfig.CFG.DatabaseTTL))
 	}
+	return wasCompleted, completed, txn.SetEntry(e)
+}
+
+// commitScoreKey returns the prefix of the scores of the commits of the user for the workshop/task
+func commitScoreKey(username string, workshop, task string) string {
+	return fmt.Sprintf("commit-score:%s:%s/%s:", username, workshop, task)
+}
+
+// commitScores returns the recorded score of every judged commit of the user for the workshop/task
+func (sm *ScoreboardManager) commitScores(txn *badger.Txn, username string, wt models.ScoreboardWorkshopTask) (map[string]models.ScoreboardUserTask, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = []byte(commitScoreKey(username, wt.Workshop, wt.Task))
+
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	commits := make(map[string]models.ScoreboardUserTask)
+	for it.Rewind(); it.Valid(); it.Next() {
+		var commit models.ScoreboardUserTask
+		err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, &commit)
+		})
+		if err != nil {
+			return nil, err
+		}
+		commits[commit.Submission.CommitID] = commit
+	}
+	return commits, nil
+}
+
+// saveCommitScore records the score of a judged commit
+func (sm *ScoreboardManager) saveCommitScore(txn *badger.Txn, username string, commit models.ScoreboardUserTask) error {
+	data, err := json.Marshal(commit)
+	if err != nil {
+		return err
+	}
+
+	e := badger.NewEntry([]byte(commitScoreKey(username, commit.Workshop, commit.Task)+commit.Submission.CommitID), data)
+	if appConfig.CFG.DatabaseTTL != 0 {
+		e = e.WithTTL(time.Hour * time.Duration(appConfig.CFG.DatabaseTTL))
+	}
 	return txn.SetEntry(e)
 }
 
-func (sm *ScoreboardManager) updateWorkshopStats(txn *badger.Txn, wt models.ScoreboardWorkshopTask, submission models.ScoreboardUserSubmission) error {
+// betterScore reports if a commit ranks above another one. A full score beats a partial one of the same points and
+// of equal commits the earlier one is kept.
+func betterScore(a, b models.ScoreboardUserTask) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Completed() != b.Completed() {
+		return a.Completed()
+	}
+	return a.Submission.Timestamp.Before(b.Submission.Timestamp)
+}
+
+// updateWorkshopStats adds the user to the completions of the workshop/task or removes them if the
+// completion was lost
+func (sm *ScoreboardManager) updateWorkshopStats(txn *badger.Txn, wt models.ScoreboardWorkshopTask, submission models.ScoreboardUserSubmission, completed bool) error {
 	workshopKey := []byte(fmt.Sprintf("workshop:%s:%s", wt.Workshop, wt.Task))
 	var stats models.WorkshopStats
 
@@ -153,10 +278,24 @@ func (sm *ScoreboardManager) updateWorkshopStats(txn *badger.Txn, wt models.Scor
 		}
 	}
 
-	stats.TotalUsers++
-	stats.CompletedAt = append(stats.CompletedAt, time.Now())
-	stats.LatestSubmit = time.Now()
-	stats.Submissions = append(stats.Submissions, submission)
+	if completed {
+		stats.TotalUsers++
+		stats.CompletedAt = append(stats.CompletedAt, time.Now())
+		stats.LatestSubmit = time.Now()
+		stats.Submissions = append(stats.Submissions, submission)
+	} else {
+		for i, s := range stats.Submissions {
+			if s.RepoName != submission.RepoName {
+				continue
+			}
+			stats.TotalUsers--
+			stats.Submissions = append(stats.Submissions[:i], stats.Submissions[i+1:]...)
+			if i < len(stats.CompletedAt) {
+				stats.CompletedAt = append(stats.CompletedAt[:i], stats.CompletedAt[i+1:]...)
+			}
+			break
+		}
+	}
 
 	data, err := json.Marshal(stats)
 	if err != nil {
@@ -223,6 +362,7 @@ func (sm *ScoreboardManager) GetWorkshopStats(workshop, task string) (*models.Wo
 func (sm *ScoreboardManager) GetLeaderboard(limit int) ([]models.Leaderboard, error) {
 	type userScore struct {
 		username       string
+		points         float64
 		completedTasks int
 		lastSubmission time.Time
 		latestRepoName string
@@ -248,6 +388,11 @@ func (sm *ScoreboardManager) GetLeaderboard(limit int) ([]models.Leaderboard, er
 				return err
 			}
 
+			// Users without any scored task are not ranked
+			if len(progress.Submissions) == 0 {
+				continue
+			}
+
 			var lastSubmission time.Time
 			var latestRepoName string
 
@@ -260,7 +405,8 @@ func (sm *ScoreboardManager) GetLeaderboard(limit int) ([]models.Leaderboard, er
 
 			scores = append(scores, userScore{
 				username:       progress.User,
-				completedTasks: len(progress.Submissions),
+				points:         progress.Points(),
+				completedTasks: progress.CompletedTasks(),
 				lastSubmission: lastSubmission,
 				latestRepoName: latestRepoName,
 			})
@@ -274,6 +420,9 @@ func (sm *ScoreboardManager) GetLeaderboard(limit int) ([]models.Leaderboard, er
 
 	// Sort scores
 	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].points != scores[j].points {
+			return scores[i].points > scores[j].points
+		}
 		if scores[i].completedTasks == scores[j].completedTasks {
 			return scores[i].lastSubmission.After(scores[j].lastSubmission)
 		}
@@ -289,6 +438,7 @@ func (sm *ScoreboardManager) GetLeaderboard(limit int) ([]models.Leaderboard, er
 	for i := 0; i < limit; i++ {
 		result[i] = models.Leaderboard{
 			Username:       scores[i].username,
+			Points:         scores[i].points,
 			CompletedTasks: scores[i].completedTasks,
 			LastSubmission: scores[i].lastSubmission,
 			LatestRepoName: scores[i].latestRepoName,
@@ -297,3 +447,118 @@ func (sm *ScoreboardManager) GetLeaderboard(limit int) ([]models.Leaderboard, er
 
 	return result, nil
 }
+
+// TaskSubmissions returns the commits which determine the standing of every user for the workshop/task:
+// the commit of the best score and the commit of the latest attempt.
+func (sm *ScoreboardManager) TaskSubmissions(workshop, task string) ([]models.ScoreboardUserSubmission, error) {
+	submissions := make([]models.ScoreboardUserSubmission, 0)
+
+	err := sm.forEachUser(func(progress models.ScoreboardUserProgress) {
+		seen := make(map[string]bool)
+		var repo models.ScoreboardUserSubmission
+
+		for _, s := range progress.Submissions {
+			repo = s.Submission
+			if s.Workshop == workshop && s.Task == task && !seen[s.Submission.CommitID] {
+				seen[s.Submission.CommitID] = true
+				submissions = append(submissions, s.Submission)
+			}
+		}
+
+		for _, a := range progress.Attempts {
+			if a.Workshop != workshop || a.Task != task || seen[a.CommitID] {
+				continue
+			}
+			seen[a.CommitID] = true
+
+			submission := models.ScoreboardUserSubmission{
+				RepoName:   a.RepoName,
+				CommitID:   a.CommitID,
+				BranchName: a.BranchName,
+				CloneURL:   a.CloneURL,
+				Timestamp:  a.Timestamp,
+			}
+			// Attempts recorded by older versions do not contain the repository
+			if submission.RepoName == "" {
+				submission.RepoName = repo.RepoName
+				submission.CloneURL = repo.CloneURL
+			}
+			if submission.RepoName != "" {
+				submissions = append(submissions, submission)
+			}
+		}
+	})
+
+	return submissions, err
+}
+
+// FindCommit returns the repository of a commit and the workshop/tasks it was scored for. It returns nil if the
+// commit is not part of the scoreboard.
+func (sm *ScoreboardManager) FindCommit(commitID string) (*models.ScoreboardUserSubmission, []models.Solution, error) {
+	var found *models.ScoreboardUserSubmission
+	solutions := make([]models.Solution, 0)
+
+	err := sm.forEachUser(func(progress models.ScoreboardUserProgress) {
+		tasks := make(map[models.Solution]bool)
+		var repo models.ScoreboardUserSubmission
+		var branch string
+
+		for _, s := range progress.Submissions {
+			repo = s.Submission
+			if s.Submission.CommitID == commitID {
+				tasks[models.Solution{Workshop: s.Workshop, Task: s.Task}] = true
+				branch = s.Submission.BranchName
+			}
+		}
+		for _, a := range progress.Attempts {
+			if a.CommitID == commitID {
+				tasks[models.Solution{Workshop: a.Workshop, Task: a.Task}] = true
+				if a.RepoName != "" {
+					repo = models.ScoreboardUserSubmission{RepoName: a.RepoName, CloneURL: a.CloneURL}
+				}
+				if a.BranchName != "" {
+					branch = a.BranchName
+				}
+			}
+		}
+
+		if len(tasks) == 0 || repo.RepoName == "" {
+			return
+		}
+
+		found = &models.ScoreboardUserSubmission{
+			RepoName:   repo.RepoName,
+			CommitID:   commitID,
+			BranchName: branch,
+			CloneURL:   repo.CloneURL,
+		}
+		for solution := range tasks {
+			solutions = append(solutions, solution)
+		}
+	})
+
+	return found, solutions, err
+}
+
+// forEachUser calls fn with the progress of every user
+func (sm *ScoreboardManager) forEachUser(fn func(progress models.ScoreboardUserProgress)) error {
+	return sm.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte("user:")
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var progress models.ScoreboardUserProgress
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &progress)
+			})
+			if err != nil {
+				return err
+			}
+			fn(progress)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,110 @@
+package scoreboard_test
+
+import (
+	"github.com/dgraph-io/badger/v4"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/scoreboard"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func newManager(t *testing.T) *scoreboard.ScoreboardManager {
+	config.CFG = &config.Config{}
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return scoreboard.NewScoreboardManager(db)
+}
+
+func results(statuses ...status.Status) []models.TestCaseResult {
+	testCases := make([]models.TestCaseResult, 0, len(statuses))
+	for i, s := range statuses {
+		testCases = append(testCases, models.TestCaseResult{
+			TestNumber: i + 1,
+			Solution:   models.Solution{Workshop: "workshop1", Task: "task1"},
+			Status:     s,
+			Points:     1,
+		})
+	}
+	return testCases
+}
+
+func TestRejudgeLosesCompletion(t *testing.T) {
+	sm := newManager(t)
+	submission := models.Submission{RepoName: "org/alice", CommitID: "commit1"}
+
+	require.NoError(t, sm.ProcessTestResults(submission, results(status.StatusPassed, status.StatusPassed)))
+
+	stats, err := sm.GetWorkshopStats("workshop1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalUsers)
+
+	submission.Rejudge = true
+	require.NoError(t, sm.ProcessTestResults(submission, results(status.StatusPassed, status.StatusWrongAnswer)))
+
+	progress, err := sm.GetUserProgress("alice")
+	require.NoError(t, err)
+	assert.Equal(t, 0, progress.CompletedTasks())
+	assert.Equal(t, 1.0, progress.Points())
+
+	stats, err = sm.GetWorkshopStats("workshop1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.TotalUsers)
+	assert.Empty(t, stats.Submissions)
+}
+
+func TestRejudgeGainsCompletion(t *testing.T) {
+	sm := newManager(t)
+	first := models.Submission{RepoName: "org/bob", CommitID: "commit1"}
+	second := models.Submission{RepoName: "org/bob", CommitID: "commit2"}
+
+	require.NoError(t, sm.ProcessTestResults(first, results(status.StatusWrongAnswer, status.StatusWrongAnswer)))
+	require.NoError(t, sm.ProcessTestResults(second, results(status.StatusPassed, status.StatusWrongAnswer)))
+
+	// The older commit passes after the expected output was fixed
+	first.Rejudge = true
+	require.NoError(t, sm.ProcessTestResults(first, results(status.StatusPassed, status.StatusPassed)))
+
+	progress, err := sm.GetUserProgress("bob")
+	require.NoError(t, err)
+	assert.Equal(t, 1, progress.CompletedTasks())
+	// The rejudge of the older commit does not replace the latest attempt
+	require.Len(t, progress.Attempts, 1)
+	assert.Equal(t, "commit2", progress.Attempts[0].CommitID)
+
+	stats, err := sm.GetWorkshopStats("workshop1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.TotalUsers)
+
+	submissions, err := sm.TaskSubmissions("workshop1", "task1")
+	require.NoError(t, err)
+	assert.Len(t, submissions, 2)
+}
+
+func TestRejudgeFallsBackToNextBestCommit(t *testing.T) {
+	sm := newManager(t)
+	best := models.Submission{RepoName: "org/carol", CommitID: "commit1"}
+	other := models.Submission{RepoName: "org/carol", CommitID: "commit2"}
+
+	require.NoError(t, sm.ProcessTestResults(best, results(status.StatusPassed, status.StatusPassed, status.StatusPassed)))
+	require.NoError(t, sm.ProcessTestResults(other, results(status.StatusPassed, status.StatusPassed, status.StatusWrongAnswer)))
+
+	// The best commit gets worse than the other one, which never was the best
+	best.Rejudge = true
+	require.NoError(t, sm.ProcessTestResults(best, results(status.StatusPassed, status.StatusWrongAnswer, status.StatusWrongAnswer)))
+
+	progress, err := sm.GetUserProgress("carol")
+	require.NoError(t, err)
+	require.Len(t, progress.Submissions, 1)
+	assert.Equal(t, "commit2", progress.Submissions[0].Submission.CommitID)
+	assert.Equal(t, 2.0, progress.Points())
+
+	stats, err := sm.GetWorkshopStats("workshop1", "task1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.TotalUsers)
+}
@@ -0,0 +1,70 @@
+package judge
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/config"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"os"
+)
+
+// Seccomp profiles which are not read from a file
+const (
+	SeccompDefault    = "default"
+	SeccompUnconfined = "unconfined"
+)
+
+// DefaultSecurity returns the security profile configured through the environment
+func DefaultSecurity(cfg *config.Config) models.Security {
+	return models.Security{
+		User:            cfg.SandboxUser,
+		ReadOnlyRoot:    &cfg.SandboxReadOnlyRoot,
+		ReadOnlyRepo:    &cfg.SandboxReadOnlyRepo,
+		NoNewPrivileges: &cfg.SandboxNoNewPrivileges,
+		CapDrop:         cfg.SandboxCapDrop,
+		Seccomp:         cfg.SandboxSeccomp,
+		TmpfsSize:       cfg.SandboxTmpfsSize,
+	}
+}
+
+// EffectiveSecurity returns the security profile a container is created with. The profile of the task
+// overrides the server defaults.
+func (e *DockerExecutor) EffectiveSecurity(security models.Security) models.Security {
+	return e.security.Merge(&security)
+}
+
+// applySecurity hardens a container according to the security profile
+func applySecurity(hostConfig *container.HostConfig, security models.Security) error {
+	hostConfig.ReadonlyRootfs = models.Enabled(security.ReadOnlyRoot)
+	hostConfig.CapDrop = security.CapDrop
+	hostConfig.CapAdd = security.CapAdd
+
+	if models.Enabled(security.NoNewPrivileges) {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+
+	switch security.Seccomp {
+	case "", SeccompDefault:
+		// The daemon applies its default profile
+	case SeccompUnconfined:
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp=unconfined")
+	default:
+		// The API expects the profile itself, not its path
+		profile, err := os.ReadFile(security.Seccomp)
+		if err != nil {
+			return fmt.Errorf("failed to read seccomp profile: %v", err)
+		}
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+string(profile))
+	}
+
+	// A read-only root still needs a writable scratch directory, e.g. for compiler caches
+	if hostConfig.ReadonlyRootfs || security.TmpfsSize > 0 {
+		options := "rw,exec,nosuid,nodev,mode=1777"
+		if security.TmpfsSize > 0 {
+			options += fmt.Sprintf(",size=%dm", security.TmpfsSize)
+		}
+		hostConfig.Tmpfs = map[string]string{"/tmp": options}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package judge
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selfTestScript reports the properties of the sandbox as key=value lines. The repository is given as argument.
+const selfTestScript = `echo "uid=$(id -u)"
+touch /selftest 2>/dev/null && echo "root_writable=yes" || echo "root_writable=no"
+touch /tmp/selftest 2>/dev/null && echo "tmp_writable=yes" || echo "tmp_writable=no"
+touch "$1/selftest" 2>/dev/null && echo "repo_writable=yes" || echo "repo_writable=no"
+while IFS=: read -r key value; do
+  case "$key" in CapEff|NoNewPrivs|Seccomp) echo "$key=$(echo $value)" ;; esac
+done < /proc/self/status
+echo "pids_max=$(cat /sys/fs/cgroup/pids.max 2>/dev/null || cat /sys/fs/cgroup/pids/pids.max 2>/dev/null)"
+echo "interfaces=$(grep -c ':' /proc/net/dev)"`
+
+// SelfTestCheck is a single property of the sandbox verified by the self-test
+type SelfTestCheck struct {
+	Name     string
+	Expected string
+	Actual   string
+	Passed   bool
+}
+
+// SelfTest starts a container with the default limits and security profile and verifies that the profile is
+// in force
+func (e *DockerExecutor) SelfTest(ctx context.Context) ([]SelfTestCheck, error) {
+	repoDir, err := getTempDir("selftest-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(repoDir)
+	if err := shareWithSandbox(repoDir, true); err != nil {
+		return nil, fmt.Errorf("failed to prepare temp dir: %v", err)
+	}
+
+	result, err := e.run(ctx, containerRun{
+		Entrypoint: []string{"/bin/sh", "-c", selfTestScript, "selftest", e.containerPath("repo")},
+		Workspaces: []workspace{
+			{
+				Dir:      repoDir,
+				Name:     "repo",
+				ReadOnly: models.Enabled(e.security.ReadOnlyRepo),
+			},
+		},
+		Limits:   e.limits,
+		Security: e.security,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("self-test failed: %s", result.Error)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("self-test exited with code %d: %s", result.ExitCode, Trim(result.Stderr))
+	}
+
+	return evaluateSelfTest(result.Output, e.security, e.limits, e.network), nil
+}
+
+// evaluateSelfTest compares the reported properties of the sandbox with the expected ones
+func evaluateSelfTest(output string, security models.Security, limits models.Limits, network string) []SelfTestCheck {
+	checks := newSelfTestChecks(output)
+	check := checks.check
+
+	if user, _, _ := strings.Cut(security.User, ":"); user != "" {
+		if _, err := strconv.Atoi(user); err == nil {
+			check("User", "uid", user)
+		}
+	}
+	if models.Enabled(security.ReadOnlyRoot) {
+		check("Read-only root filesystem", "root_writable", "no")
+	}
+	check("Writable /tmp", "tmp_writable", "yes")
+	if models.Enabled(security.ReadOnlyRepo) {
+		check("Read-only repository", "repo_writable", "no")
+	}
+	if slices.Contains(security.CapDrop, "ALL") && len(security.CapAdd) == 0 {
+		check("No capabilities", "CapEff", "0000000000000000")
+	}
+	if models.Enabled(security.NoNewPrivileges) {
+		check("No new privileges", "NoNewPrivs", "1")
+	}
+	if security.Seccomp != SeccompUnconfined {
+		check("Seccomp filter", "Seccomp", "2")
+	}
+	if limits.Pids > 0 {
+		check("Process limit", "pids_max", strconv.FormatInt(limits.Pids, 10))
+	}
+	if network == "none" {
+		// Only the loopback interface exists
+		check("No network", "interfaces", "1")
+	}
+
+	return checks.checks
+}
+
+// selfTestChecks compares the key=value lines printed by a self-test script with the expected values
+type selfTestChecks struct {
+	actual map[string]string
+	checks []SelfTestCheck
+}
+
+func newSelfTestChecks(output string) *selfTestChecks {
+	c := &selfTestChecks{
+		actual: make(map[string]string),
+		checks: make([]SelfTestCheck, 0),
+	}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if key, value, found := strings.Cut(scanner.Text(), "="); found {
+			c.actual[key] = value
+		}
+	}
+	return c
+}
+
+func (c *selfTestChecks) check(name, key, expected string) {
+	c.checks = append(c.checks, SelfTestCheck{
+		Name:     name,
+		Expected: expected,
+		Actual:   c.actual[key],
+		Passed:   c.actual[key] == expected,
+	})
+}
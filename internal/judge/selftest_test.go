@@ -0,0 +1,48 @@
+package judge
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEvaluateSelfTest(t *testing.T) {
+	enabled := true
+	security := models.Security{
+		User:            "65534:65534",
+		ReadOnlyRoot:    &enabled,
+		ReadOnlyRepo:    &enabled,
+		NoNewPrivileges: &enabled,
+		CapDrop:         []string{"ALL"},
+		Seccomp:         SeccompDefault,
+	}
+	output := `uid=65534
+root_writable=no
+tmp_writable=yes
+repo_writable=yes
+CapEff=0000000000000000
+NoNewPrivs=1
+Seccomp=2
+pids_max=max
+interfaces=1
+`
+
+	checks := evaluateSelfTest(output, security, models.Limits{Pids: 128}, "none")
+
+	failed := make([]string, 0)
+	for _, check := range checks {
+		if !check.Passed {
+			failed = append(failed, check.Name)
+		}
+	}
+	assert.Len(t, checks, 9)
+	assert.Equal(t, []string{"Read-only repository", "Process limit"}, failed)
+}
+
+func TestEvaluateSelfTestUnconfined(t *testing.T) {
+	security := models.Security{Seccomp: SeccompUnconfined}
+
+	checks := evaluateSelfTest("tmp_writable=yes\n", security, models.Limits{}, "bridge")
+
+	assert.Equal(t, []SelfTestCheck{{Name: "Writable /tmp", Expected: "yes", Actual: "yes", Passed: true}}, checks)
+}
@@ -0,0 +1,242 @@
+package judge
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// containerSession is a container which compiles a program once and runs every test case of a task in it. Each
+// compilation and test case is a separate exec with its own stdin and wall time.
+type containerSession struct {
+	docker      *DockerExecutor
+	containerID string
+	program     Program
+	limits      models.Limits
+	remove      func()
+}
+
+// NewSession starts a container for the program or takes one from the warm pool. The resources of the container
+// are set by limits, the wall time is enforced for every exec.
+func (e *DockerExecutor) NewSession(ctx context.Context, repositoryDir string, program Program, limits models.Limits) (Session, error) {
+	security := e.EffectiveSecurity(program.Security)
+	_, dir := e.programPaths(program)
+	spec := containerRun{
+		Image: program.Language.Image,
+		// Keeps the container alive until it is removed
+		Entrypoint: []string{"tail", "-f", "/dev/null"},
+		WorkingDir: dir,
+		Workspaces: e.programWorkspaces(program, repositoryDir, security),
+		Limits:     limits,
+		Security:   security,
+	}
+
+	var containerID string
+	var remove func()
+	warm := false
+	if e.warm != nil {
+		containerID, remove, warm = e.warm.acquire(ctx, spec)
+	}
+	if !warm {
+		start := time.Now()
+		var err error
+		containerID, remove, err = e.startContainer(ctx, spec)
+		if err != nil {
+			return nil, err
+		}
+		if e.warm != nil {
+			e.warm.recordCold(time.Since(start))
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"ContainerID": containerID,
+		"Language":    program.Language.Name,
+		"Memory":      limits.Memory,
+		"CPUs":        limits.CPUs,
+		"Pids":        limits.Pids,
+		"Warm":        warm,
+	}).Debug("Started session")
+
+	return &containerSession{
+		docker:      e,
+		containerID: containerID,
+		program:     program,
+		limits:      limits,
+		remove:      remove,
+	}, nil
+}
+
+// startContainer creates and starts a container which keeps running until it is removed
+func (e *DockerExecutor) startContainer(ctx context.Context, spec containerRun) (string, func(), error) {
+	containerID, remove, err := e.createContainer(ctx, spec)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := e.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		remove()
+		return "", nil, fmt.Errorf("failed to start container: %v", err)
+	}
+	return containerID, remove, nil
+}
+
+// Compile runs the compile command of the language. Interpreted languages are not compiled.
+func (s *containerSession) Compile(ctx context.Context) (*models.ExecutionResult, error) {
+	if s.program.Language.Compile == "" {
+		return &models.ExecutionResult{}, nil
+	}
+
+	limits := compileLimits(s.docker.EffectiveLimits(s.program.Language, models.Limits{}))
+	file, dir := s.docker.programPaths(s.program)
+	result, err := s.exec(ctx, s.program.Language.Command(s.program.Language.Compile, file, dir, s.docker.containerPath("build")), nil, "", limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return compileResult(result, limits), nil
+}
+
+// Accepts reports if the test case can run in the session. Cases with other container resources than the
+// session need their own container.
+func (s *containerSession) Accepts(limits models.Limits) bool {
+	return limits.Memory == s.limits.Memory && limits.CPUs == s.limits.CPUs && limits.Pids == s.limits.Pids
+}
+
+// Run runs the compiled program with the input of the test case on stdin. Cases with files are not run in sessions.
+func (s *containerSession) Run(ctx context.Context, testCase models.TestCase) (*models.ExecutionResult, error) {
+	file, dir := s.docker.programPaths(s.program)
+	command := caseCommand(s.program.Language.Command(s.program.Language.Run, file, dir, s.docker.containerPath("build")), testCase.Args)
+	result, err := s.exec(ctx, measureCommand(command, testCase.Limits), caseEnv(testCase), testCase.Input, measureLimits(testCase.Limits))
+	if err != nil {
+		return nil, err
+	}
+
+	return runResult(result, testCase.Limits), nil
+}
+
+// Close removes the container of the session
+func (s *containerSession) Close() {
+	s.remove()
+}
+
+// exec runs a command in the container and kills every process of it once the wall time is exceeded
+func (s *containerSession) exec(ctx context.Context, command string, env []string, stdin string, limits models.Limits) (*models.ExecutionResult, error) {
+	_, dir := s.docker.programPaths(s.program)
+	execResp, err := s.docker.cli.ContainerExecCreate(ctx, s.containerID, container.ExecOptions{
+		Cmd:          []string{"/bin/sh", "-c", command},
+		Env:          env,
+		WorkingDir:   dir,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %v", err)
+	}
+
+	execFields := log.Fields{
+		"ContainerID": s.containerID,
+		"ExecID":      execResp.ID,
+	}
+
+	start := time.Now()
+	attach, err := s.docker.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start exec: %v", err)
+	}
+	defer attach.Close()
+
+	go func() {
+		// A program which does not read its input makes the write fail, this is not an error
+		io.Copy(attach.Conn, strings.NewReader(stdin))
+		attach.CloseWrite()
+	}()
+
+	output := newStreams(limits)
+	done := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(output.stdout, output.stderr, attach.Reader)
+		done <- err
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, limits.WallTimeDuration())
+	defer cancel()
+
+	var result models.ExecutionResult
+	select {
+	case <-ctx.Done():
+		result.ExecutionTime = time.Since(start)
+		result.TimedOut = true
+		log.WithFields(execFields).Warn("execution timeout")
+		s.kill()
+		return &result, nil
+	case <-output.exceeded:
+		result.ExecutionTime = time.Since(start)
+		log.WithFields(execFields).Warn("output limit exceeded")
+		s.kill()
+		<-done
+		output.collect(&result)
+		return &result, nil
+	case err := <-done:
+		result.ExecutionTime = time.Since(start)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read output: %v", err)
+		}
+	}
+
+	inspect, err := s.inspect(execResp.ID)
+	if err != nil {
+		return nil, err
+	}
+	result.ExitCode = int64(inspect.ExitCode)
+
+	// Only the memory limit kills a program which did not exceed its wall time. The OOM flag of the
+	// container is not set for processes of an exec.
+	if result.ExitCode == 128+int64(syscall.SIGKILL) {
+		result.OOMKilled = true
+	}
+
+	output.collect(&result)
+	return &result, nil
+}
+
+// inspect waits until the exec is no longer running and returns its state
+func (s *containerSession) inspect(execID string) (container.ExecInspect, error) {
+	for i := 0; ; i++ {
+		inspect, err := s.docker.cli.ContainerExecInspect(context.Background(), execID)
+		if err != nil {
+			return inspect, fmt.Errorf("failed to inspect exec: %v", err)
+		}
+		// The output stream can end shortly before the exit code is recorded
+		if !inspect.Running || i == 50 {
+			return inspect, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// kill kills every process of the session except the one keeping the container alive and waits until
+// they are gone, so they cannot interfere with the next exec
+func (s *containerSession) kill() {
+	execResp, err := s.docker.cli.ContainerExecCreate(context.Background(), s.containerID, container.ExecOptions{
+		// The builtin of the shell is used as not every image ships a kill binary
+		Cmd: []string{"/bin/sh", "-c", "kill -9 -1"},
+	})
+	if err == nil {
+		err = s.docker.cli.ContainerExecStart(context.Background(), execResp.ID, container.ExecStartOptions{})
+	}
+	if err == nil {
+		_, err = s.inspect(execResp.ID)
+	}
+	if err != nil {
+		log.WithField("ContainerID", s.containerID).WithError(err).Error("Failed to kill processes")
+	}
+}
@@ -0,0 +1,63 @@
+package judge
+
+import (
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/checker"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+)
+
+// sqlLanguage is the language SQL tasks accept unless the task restricts the languages itself
+const sqlLanguage = "sql"
+
+// resultSetRows limits the rows of a result set shown in the results
+const resultSetRows = 20
+
+// sqlInput returns the input of a case of an SQL task. The run command of the language executes the input before
+// the query of the solution, so it is the dataset of the case followed by the statements of the case itself.
+func sqlInput(taskDir string, sql *models.SQLConfig, dataset string, input string) (string, error) {
+	if dataset == "" {
+		dataset = sql.Dataset
+	}
+	if dataset == "" {
+		return input, nil
+	}
+
+	path, err := taskPath(taskDir, dataset)
+	if err != nil {
+		return "", fmt.Errorf("dataset %s: %v", dataset, err)
+	}
+	data, err := readTestFile(path)
+	if err != nil {
+		return "", err
+	}
+	if input == "" {
+		return data, nil
+	}
+	return data + "\n" + input, nil
+}
+
+// sqlChecker returns the checker comparing the result set of an SQL task
+func sqlChecker(sql *models.SQLConfig) checker.Checker {
+	return &checker.ResultSet{
+		IgnoreOrder:       sql.IgnoreOrder,
+		IgnoreColumnNames: sql.IgnoreColumnNames,
+	}
+}
+
+// sqlResultSet returns the first rows of the result set a query printed, nil if it printed none
+func sqlResultSet(output string) *models.ResultSet {
+	columns, rows, err := checker.ParseResultSet(output)
+	if err != nil || columns == nil {
+		return nil
+	}
+
+	resultSet := &models.ResultSet{
+		Columns: columns,
+		Rows:    rows,
+		Total:   len(rows),
+	}
+	if len(rows) > resultSetRows {
+		resultSet.Rows = rows[:resultSetRows]
+	}
+	return resultSet
+}
@@ -0,0 +1,94 @@
+package judge
+
+import (
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// taskConfigCache keeps the parsed configurations of tasks until their config.yaml changes
+type taskConfigCache struct {
+	testCaseDir string
+
+	mu      sync.Mutex
+	configs map[string]cachedTaskConfig
+}
+
+type cachedTaskConfig struct {
+	modTime time.Time
+	size    int64
+	config  models.TestCaseConfig
+}
+
+func newTaskConfigCache(testCaseDir string) *taskConfigCache {
+	return &taskConfigCache{
+		testCaseDir: testCaseDir,
+		configs:     make(map[string]cachedTaskConfig),
+	}
+}
+
+// get returns the configuration of a task. Only the modification time of the file is read if the configuration
+// is cached already.
+func (c *taskConfigCache) get(solution models.Solution) (models.TestCaseConfig, error) {
+	path := filepath.Join(c.testCaseDir, solution.Workshop, solution.Task, "config.yaml")
+	info, err := os.Stat(path)
+	if err != nil {
+		return models.TestCaseConfig{}, err
+	}
+
+	c.mu.Lock()
+	cached, exists := c.configs[path]
+	c.mu.Unlock()
+	if exists && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return models.TestCaseConfig{}, fmt.Errorf("failed to read config file: %v", err)
+	}
+	var config models.TestCaseConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return models.TestCaseConfig{}, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	c.mu.Lock()
+	c.configs[path] = cachedTaskConfig{modTime: info.ModTime(), size: info.Size(), config: config}
+	c.mu.Unlock()
+	return config, nil
+}
+
+// submissionTasks returns the tasks a submission judges as far as they are known before the repository is cloned:
+// the requested tasks or the task directories of the files changed according to the webhook and the tasks requested
+// by the commit messages
+func submissionTasks(submission models.Submission) []models.Solution {
+	if len(submission.Solutions) > 0 {
+		return submission.Solutions
+	}
+
+	seen := make(map[models.Solution]bool)
+	tasks := make([]models.Solution, 0)
+	for _, file := range submission.ChangedFiles {
+		parts := strings.Split(filepath.ToSlash(file), "/")
+		if len(parts) < 3 {
+			continue
+		}
+		task := models.Solution{Workshop: parts[0], Task: parts[1]}
+		if !seen[task] {
+			seen[task] = true
+			tasks = append(tasks, task)
+		}
+	}
+	for _, task := range pushDirectives(submission.CommitMessages).Tasks {
+		if !seen[task] {
+			seen[task] = true
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
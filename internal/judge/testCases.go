@@ -3,11 +3,16 @@
 package judge
 
 import (
+	"compress/gzip"
 	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/checker"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	"gopkg.in/yaml.v3"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -109,22 +114,442 @@ func loadTestCasesFromConfig(configPath string) ([]models.TestCase, error) {
 		return nil, nil
 	}
 
-	listCases := [][]models.Case{config.Cases, config.HiddenCases}
-	testCases := make([]models.TestCase, 0)
+	var checkerConfig models.CheckerConfig
+	if config.Checker != nil {
+		checkerConfig = *config.Checker
+	}
 
-	for j, cases := range listCases {
-		for _, c := range cases {
-			testCases = append(testCases, models.TestCase{
-				Input:    c.Input,
-				Expected: FormatExpectedString(c.Expected),
-				IsHidden: j == 1,
-			})
+	switch config.Isolation {
+	case "", models.IsolationSession, models.IsolationCase:
+	default:
+		return nil, fmt.Errorf("unknown isolation %s", config.Isolation)
+	}
+
+	subtaskPoints, err := resolveSubtaskPoints(config)
+	if err != nil {
+		return nil, err
+	}
+
+	taskDir := filepath.Dir(configPath)
+	interactor := ""
+	var sql *models.SQLConfig
+	switch config.Type {
+	case "", models.TaskTypeIO:
+	case models.TaskTypeUnit:
+		return loadUnitTestCase(config, taskDir, checkerConfig, subtaskPoints)
+	case models.TaskTypeInteractive:
+		if config.Interactor == "" {
+			return nil, fmt.Errorf("interactive task requires an interactor")
+		}
+		if interactor, err = taskPath(taskDir, config.Interactor); err != nil {
+			return nil, fmt.Errorf("interactor %v", err)
+		}
+	case models.TaskTypeSQL:
+		if config.Checker != nil {
+			return nil, fmt.Errorf("sql task compares result sets and cannot have a checker")
 		}
+		sql = &models.SQLConfig{}
+		if config.SQL != nil {
+			sql = config.SQL
+		}
+		if len(config.Languages) == 0 {
+			config.Languages = []string{sqlLanguage}
+		}
+	default:
+		return nil, fmt.Errorf("unknown task type %s", config.Type)
+	}
+
+	cases := make([]taskCase, 0, len(config.Cases)+len(config.HiddenCases))
+	for _, c := range config.Cases {
+		cases = append(cases, taskCase{Case: c, Expected: FormatExpectedString(c.Expected)})
+	}
+	for _, c := range config.HiddenCases {
+		cases = append(cases, taskCase{Case: c, Expected: FormatExpectedString(c.Expected), Hidden: true})
+	}
+	fileCases, err := loadFileCases(filepath.Join(taskDir, testFilesDir), config.TestFiles)
+	if err != nil {
+		return nil, err
+	}
+	cases = append(cases, fileCases...)
+
+	testCases := make([]models.TestCase, 0, len(cases))
+	for _, c := range cases {
+		files, expectedFiles, err := loadCaseFiles(taskDir, c.Case)
+		if err != nil {
+			return nil, err
+		}
+		if interactor != "" && len(c.Args) > 0 {
+			return nil, fmt.Errorf("cases of interactive tasks have no arguments")
+		}
+
+		input := c.Input
+		if sql != nil {
+			if input, err = sqlInput(taskDir, sql, c.Dataset, c.Input); err != nil {
+				return nil, err
+			}
+			if _, _, err := checker.ParseResultSet(c.Expected); err != nil {
+				return nil, fmt.Errorf("invalid expected rows: %v", err)
+			}
+		} else if c.Dataset != "" {
+			return nil, fmt.Errorf("only cases of sql tasks have a dataset")
+		}
+
+		testCases = append(testCases, models.TestCase{
+			Input:         input,
+			Expected:      c.Expected,
+			IsHidden:      c.Hidden,
+			Limits:        models.Limits{}.Merge(config.Limits).Merge(c.Limits),
+			Checker:       checkerConfig,
+			Security:      models.Security{}.Merge(config.Security),
+			Languages:     config.Languages,
+			Isolated:      config.Isolation == models.IsolationCase,
+			TaskDir:       taskDir,
+			Points:        casePoints(c.Points, c.Subtask),
+			Subtask:       c.Subtask,
+			SubtaskPoints: subtaskPoints[c.Subtask],
+			Args:          c.Args,
+			Env:           c.Env,
+			Files:         files,
+			ExpectedFiles: expectedFiles,
+			Interactor:    interactor,
+			SQL:           sql,
+		})
 	}
 
 	return testCases, nil
 }
 
+// taskCase is a case of config.yaml or of the tests directory with its expected output as it is compared
+type taskCase struct {
+	models.Case
+	Expected string
+	Hidden   bool
+}
+
+// loadFileCases loads the cases of the tests directory with the scoring configured for them in config.yaml
+func loadFileCases(dir string, configs []models.TestFile) ([]taskCase, error) {
+	files, err := loadTestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	scoring := make(map[string]models.TestFile)
+	for _, config := range configs {
+		if _, exists := scoring[config.Name]; exists {
+			return nil, fmt.Errorf("test file %s is configured twice", config.Name)
+		}
+		scoring[config.Name] = config
+	}
+
+	cases := make([]taskCase, 0, len(files))
+	for _, file := range files {
+		config := scoring[file.Name]
+		delete(scoring, file.Name)
+		cases = append(cases, taskCase{
+			Case: models.Case{
+				Input:   file.Input,
+				Points:  config.Points,
+				Subtask: config.Subtask,
+			},
+			// Files are used as they are
+			Expected: file.Expected,
+			Hidden:   file.Hidden,
+		})
+	}
+	for name := range scoring {
+		return nil, fmt.Errorf("configured test file %s does not exist", name)
+	}
+	return cases, nil
+}
+
+// loadUnitTestCase loads a unit test task. All tests of the task run at once, so the task has a single case which
+// is split into a result per test once its report was parsed.
+func loadUnitTestCase(config models.TestCaseConfig, taskDir string, checkerConfig models.CheckerConfig, subtaskPoints map[string]float64) ([]models.TestCase, error) {
+	if config.Unit == nil {
+		return nil, fmt.Errorf("unit test task requires a unit section")
+	}
+	if len(config.Cases) > 0 || len(config.HiddenCases) > 0 {
+		return nil, fmt.Errorf("unit test task must not have cases")
+	}
+
+	unit := *config.Unit
+	switch unit.Framework {
+	case models.UnitFrameworkPytest, models.UnitFrameworkGo:
+	case models.UnitFrameworkJUnit:
+		if unit.Command == "" {
+			return nil, fmt.Errorf("unit test framework junit requires a command")
+		}
+	default:
+		return nil, fmt.Errorf("unknown unit test framework %s", unit.Framework)
+	}
+
+	if unit.Command == "" && len(unit.Files) == 0 {
+		return nil, fmt.Errorf("unit test framework %s requires test files", unit.Framework)
+	}
+
+	unit.Files = make([]string, 0, len(config.Unit.Files))
+	for _, file := range config.Unit.Files {
+		path, err := taskPath(taskDir, file)
+		if err != nil {
+			return nil, fmt.Errorf("test file %s: %v", file, err)
+		}
+		unit.Files = append(unit.Files, path)
+	}
+
+	unit.Tests = make([]models.UnitTest, 0, len(config.Unit.Tests))
+	for _, test := range config.Unit.Tests {
+		if test.Name == "" {
+			return nil, fmt.Errorf("unit test requires a name")
+		}
+		points := casePoints(test.Points, test.Subtask)
+		test.Points = &points
+		test.SubtaskPoints = subtaskPoints[test.Subtask]
+		unit.Tests = append(unit.Tests, test)
+	}
+
+	return []models.TestCase{
+		{
+			Limits:    models.Limits{}.Merge(config.Limits),
+			Checker:   checkerConfig,
+			Security:  models.Security{}.Merge(config.Security),
+			Languages: config.Languages,
+			// The report is read from the working directory of the run, which a session does not have
+			Isolated:      true,
+			TaskDir:       taskDir,
+			ExpectedFiles: []models.ExpectedFile{{Name: unitReport}},
+			Unit:          &unit,
+		},
+	}, nil
+}
+
+// loadCaseFiles resolves the fixtures and expected output files of a case. Fixtures are copied into the working
+// directory of the program under their base name, expected files must be plain names in it.
+func loadCaseFiles(taskDir string, c models.Case) ([]string, []models.ExpectedFile, error) {
+	for name := range c.Env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return nil, nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+
+	files := make([]string, 0, len(c.Files))
+	names := make(map[string]bool)
+	for _, file := range c.Files {
+		path, err := taskPath(taskDir, file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fixture %s: %v", file, err)
+		}
+		if names[filepath.Base(path)] {
+			return nil, nil, fmt.Errorf("fixtures with the same name %s", filepath.Base(path))
+		}
+		names[filepath.Base(path)] = true
+		files = append(files, path)
+	}
+
+	expectedFiles := make([]models.ExpectedFile, 0, len(c.ExpectedFiles))
+	for _, file := range c.ExpectedFiles {
+		if file.Name == "" || file.Name != filepath.Base(file.Name) || file.Name == "." || file.Name == ".." {
+			return nil, nil, fmt.Errorf("expected file %q must be a name in the working directory", file.Name)
+		}
+
+		expected := FormatExpectedString(file.Expected)
+		if file.File != "" {
+			path, err := taskPath(taskDir, file.File)
+			if err != nil {
+				return nil, nil, fmt.Errorf("expected file %s: %v", file.File, err)
+			}
+			if expected, err = readTestFile(path); err != nil {
+				return nil, nil, err
+			}
+		}
+		expectedFiles = append(expectedFiles, models.ExpectedFile{Name: file.Name, Expected: expected})
+	}
+
+	return files, expectedFiles, nil
+}
+
+// taskPath returns the absolute path of a file relative to the task directory. The file must be inside it.
+func taskPath(taskDir string, file string) (string, error) {
+	path := filepath.Join(taskDir, filepath.Clean(file))
+	if rel, err := filepath.Rel(taskDir, path); err != nil || strings.HasPrefix(rel, "..") || rel == "." {
+		return "", fmt.Errorf("must be inside the task directory")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return abs, nil
+}
+
+// testFilesDir is the directory next to config.yaml with cases stored as files
+const testFilesDir = "tests"
+
+// testFile is a case stored as NAME.in and NAME.out in the tests directory
+type testFile struct {
+	Name     string
+	Input    string
+	Expected string
+	Hidden   bool // An empty file NAME.hidden exists
+}
+
+// loadTestFiles loads the cases of the tests directory ordered by their name. Numeric names are ordered by their
+// value. Inputs and outputs can be compressed with gzip, e.g. NAME.in.gz. A missing directory has no cases.
+func loadTestFiles(dir string) ([]testFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test files: %v", err)
+	}
+
+	inputs := make(map[string]string)
+	outputs := make(map[string]string)
+	hidden := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		name, ext := cutTestFileExt(entry.Name())
+		var files map[string]string
+		switch ext {
+		case ".in":
+			files = inputs
+		case ".out":
+			files = outputs
+		case ".hidden":
+			hidden[name] = true
+			continue
+		default:
+			continue
+		}
+		if other, exists := files[name]; exists {
+			return nil, fmt.Errorf("test files %s and %s are ambiguous", other, entry.Name())
+		}
+		files[name] = entry.Name()
+	}
+
+	for name := range outputs {
+		if _, exists := inputs[name]; !exists {
+			return nil, fmt.Errorf("test file %s has no input", outputs[name])
+		}
+	}
+
+	files := make([]testFile, 0, len(inputs))
+	for name, input := range inputs {
+		output, exists := outputs[name]
+		if !exists {
+			return nil, fmt.Errorf("test file %s has no expected output", input)
+		}
+
+		file := testFile{Name: name, Hidden: hidden[name]}
+		if file.Input, err = readTestFile(filepath.Join(dir, input)); err != nil {
+			return nil, err
+		}
+		if file.Expected, err = readTestFile(filepath.Join(dir, output)); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		a, errA := strconv.Atoi(files[i].Name)
+		b, errB := strconv.Atoi(files[j].Name)
+		if errA == nil && errB == nil && a != b {
+			return a < b
+		}
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// cutTestFileExt splits a test file name into the name of its case and its type, ignoring a .gz extension
+func cutTestFileExt(file string) (string, string) {
+	file = strings.TrimSuffix(file, ".gz")
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext), ext
+}
+
+// readTestFile reads a test file and decompresses it if its name ends with .gz
+func readTestFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read test file: %v", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress test file %s: %v", filepath.Base(path), err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read test file %s: %v", filepath.Base(path), err)
+	}
+	return string(data), nil
+}
+
+// resolveSubtaskPoints returns the points of every subtask. Subtasks without points are worth one point per case.
+func resolveSubtaskPoints(config models.TestCaseConfig) (map[string]float64, error) {
+	points := make(map[string]float64)
+	unset := make(map[string]bool)
+	for _, subtask := range config.Subtasks {
+		points[subtask.Name] = 0
+		if subtask.Points == nil {
+			unset[subtask.Name] = true
+		} else {
+			points[subtask.Name] = *subtask.Points
+		}
+	}
+
+	subtasks := make([]string, 0)
+	for _, c := range append(config.Cases, config.HiddenCases...) {
+		subtasks = append(subtasks, c.Subtask)
+	}
+	for _, file := range config.TestFiles {
+		subtasks = append(subtasks, file.Subtask)
+	}
+	if config.Unit != nil {
+		for _, test := range config.Unit.Tests {
+			subtasks = append(subtasks, test.Subtask)
+		}
+	}
+
+	caseCount := make(map[string]float64)
+	for _, subtask := range subtasks {
+		if subtask == "" {
+			continue
+		}
+		if _, exists := points[subtask]; !exists {
+			return nil, fmt.Errorf("case references unknown subtask: %s", subtask)
+		}
+		caseCount[subtask]++
+	}
+
+	for name := range unset {
+		points[name] = caseCount[name]
+	}
+
+	return points, nil
+}
+
+// casePoints returns the points of a case or unit test. Unset points default to 1 outside of subtasks, the points
+// of cases in a subtask are ignored.
+func casePoints(points *float64, subtask string) float64 {
+	if points != nil {
+		return *points
+	}
+	if subtask == "" {
+		return 1
+	}
+	return 0
+}
+
 // LoadWorkshopTask loads the configuration for a specific workshop task
 func LoadWorkshopTask(testPath, workshopID, taskID string) (*WorkshopTask, error) {
 	// Clean and validate path components
@@ -1,9 +1,13 @@
 package judge_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/judge"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -53,3 +57,544 @@ func TestLoadTestCases(t *testing.T) {
 		t.Fatalf("Failed to walk through root directory %s: %v", cwd, err)
 	}
 }
+
+func TestLoadTestCasesLimits(t *testing.T) {
+	taskDir := t.TempDir()
+	config := `
+name: "Limits"
+limits:
+  memory: 2048
+  wall_time: 60
+cases:
+  - input: "1"
+    expected: "1"
+  - input: "2"
+    expected: "2"
+    limits:
+      wall_time: 2
+      pids: 16
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+
+	if len(testCases) != 2 {
+		t.Fatalf("Expected 2 test cases, got %d", len(testCases))
+	}
+
+	if testCases[0].Limits.Memory != 2048 || testCases[0].Limits.WallTime != 60 {
+		t.Errorf("Expected task limits on first case, got %+v", testCases[0].Limits)
+	}
+
+	if testCases[1].Limits.Memory != 2048 || testCases[1].Limits.WallTime != 2 || testCases[1].Limits.Pids != 16 {
+		t.Errorf("Expected overridden limits on second case, got %+v", testCases[1].Limits)
+	}
+}
+
+func TestLoadTestCasesSubtasks(t *testing.T) {
+	taskDir := filepath.Join(t.TempDir(), "workshop", "task")
+	if err := os.MkdirAll(taskDir, 0755); err != nil {
+		t.Fatalf("Failed to create task dir: %v", err)
+	}
+	config := `
+name: "Subtasks"
+subtasks:
+  - name: small
+    points: 30
+  - name: large
+cases:
+  - input: "1"
+    expected: "1"
+    points: 10
+  - input: "2"
+    expected: "2"
+    subtask: small
+hidden_cases:
+  - input: "3"
+    expected: "3"
+    subtask: small
+  - input: "4"
+    expected: "4"
+    subtask: large
+  - input: "5"
+    expected: "5"
+    subtask: large
+  - input: "6"
+    expected: "6"
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+
+	// Points of cases outside of a subtask, subtasks without points are worth one point per case
+	expectedPoints := []float64{10, 0, 0, 0, 0, 1}
+	expectedSubtaskPoints := []float64{0, 30, 30, 2, 2, 0}
+	for i, tc := range testCases {
+		if tc.Points != expectedPoints[i] || tc.SubtaskPoints != expectedSubtaskPoints[i] {
+			t.Errorf("Case %d: expected %g points and %g subtask points, got %g and %g",
+				i, expectedPoints[i], expectedSubtaskPoints[i], tc.Points, tc.SubtaskPoints)
+		}
+	}
+
+	// Zero points are kept
+	config = strings.Replace(config, "points: 10", "points: 0", 1)
+	config = strings.Replace(config, "  - name: large\n", "  - name: large\n    points: 0\n", 1)
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	testCases, err = judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+	if testCases[0].Points != 0 || testCases[3].SubtaskPoints != 0 {
+		t.Errorf("Expected zero points, got %g and %g subtask points", testCases[0].Points, testCases[3].SubtaskPoints)
+	}
+}
+
+func TestLoadTestCasesUnknownSubtask(t *testing.T) {
+	taskDir := t.TempDir()
+	config := `
+name: "Unknown"
+cases:
+  - input: "1"
+    expected: "1"
+    subtask: missing
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := judge.LoadTestCases(taskDir); err == nil {
+		t.Error("Expected error for unknown subtask")
+	}
+}
+
+func TestLoadTestCasesIsolation(t *testing.T) {
+	taskDir := t.TempDir()
+	config := `
+name: "Isolated"
+isolation: case
+cases:
+  - input: "1"
+    expected: "1"
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+	if len(testCases) != 1 || !testCases[0].Isolated {
+		t.Errorf("Expected one isolated case, got %+v", testCases)
+	}
+
+	config = `
+name: "Unknown"
+isolation: vm
+cases:
+  - input: "1"
+    expected: "1"
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if _, err := judge.LoadTestCases(taskDir); err == nil {
+		t.Error("Expected error for unknown isolation")
+	}
+}
+
+func TestLoadTestCasesSecurity(t *testing.T) {
+	taskDir := t.TempDir()
+	config := `
+name: "Security"
+security:
+  read_only_repo: false
+  tmpfs_size: 256
+cases:
+  - input: "1"
+    expected: "1"
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+
+	enabled := true
+	security := models.Security{User: "65534:65534", ReadOnlyRepo: &enabled, TmpfsSize: 64}.Merge(&testCases[0].Security)
+	if models.Enabled(security.ReadOnlyRepo) {
+		t.Error("Expected the task to make the repository writable")
+	}
+	if security.TmpfsSize != 256 || security.User != "65534:65534" {
+		t.Errorf("Unexpected security profile %+v", security)
+	}
+}
+
+func TestLoadTestCasesFiles(t *testing.T) {
+	taskDir := t.TempDir()
+	testsDir := filepath.Join(taskDir, "tests")
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		t.Fatalf("Failed to create tests dir: %v", err)
+	}
+	config := `
+name: "Files"
+limits:
+  wall_time: 5
+cases:
+  - input: "inline"
+    expected: "inline"
+`
+	files := map[string]string{
+		"config.yaml":     config,
+		"tests/2.in":      "2\n",
+		"tests/2.out":     "4\n",
+		"tests/10.in":     "10\n",
+		"tests/10.out":    "20\n",
+		"tests/10.hidden": "",
+		"tests/README.md": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(taskDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// A compressed large input
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(strings.Repeat("1 2 3\n", 1000)))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(testsDir, "3.in.gz"), compressed.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write compressed input: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(testsDir, "3.out"), []byte("6000"), 0644); err != nil {
+		t.Fatalf("Failed to write output: %v", err)
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+
+	if len(testCases) != 4 {
+		t.Fatalf("Expected 4 test cases, got %d", len(testCases))
+	}
+
+	expected := []struct {
+		input    string
+		expected string
+		hidden   bool
+	}{
+		{"inline", "inline", false},
+		{"2\n", "4\n", false},
+		{strings.Repeat("1 2 3\n", 1000), "6000", false},
+		{"10\n", "20\n", true},
+	}
+	for i, e := range expected {
+		tc := testCases[i]
+		if tc.Input != e.input || tc.Expected != e.expected || tc.IsHidden != e.hidden {
+			t.Errorf("Case %d: expected %q -> %q (hidden %v), got %q -> %q (hidden %v)",
+				i, e.input, e.expected, e.hidden, tc.Input, tc.Expected, tc.IsHidden)
+		}
+		if tc.Limits.WallTime != 5 || tc.Points != 1 {
+			t.Errorf("Case %d: expected task limits and one point, got %+v and %g", i, tc.Limits, tc.Points)
+		}
+	}
+
+	// Every input needs an expected output
+	if err := os.Remove(filepath.Join(testsDir, "2.out")); err != nil {
+		t.Fatalf("Failed to remove output: %v", err)
+	}
+	if _, err := judge.LoadTestCases(taskDir); err == nil {
+		t.Error("Expected an error for an input without expected output")
+	}
+}
+
+func TestLoadTestCasesFileScoring(t *testing.T) {
+	taskDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(taskDir, "tests"), 0755); err != nil {
+		t.Fatalf("Failed to create tests dir: %v", err)
+	}
+	config := `
+name: "File scoring"
+subtasks:
+  - name: large
+cases:
+  - input: "1"
+    expected: "1"
+    subtask: large
+test_files:
+  - name: "1"
+    points: 3
+  - name: "2"
+    subtask: large
+`
+	files := map[string]string{
+		"config.yaml": config,
+		"tests/1.in":  "1",
+		"tests/1.out": "1",
+		"tests/2.in":  "2",
+		"tests/2.out": "2",
+		"tests/3.in":  "3",
+		"tests/3.out": "3",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(taskDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+	if len(testCases) != 4 {
+		t.Fatalf("Expected 4 test cases, got %d", len(testCases))
+	}
+
+	// File cases count towards their subtask, unconfigured ones are worth one point
+	expectedPoints := []float64{0, 3, 0, 1}
+	expectedSubtaskPoints := []float64{2, 0, 2, 0}
+	for i, tc := range testCases {
+		if tc.Points != expectedPoints[i] || tc.SubtaskPoints != expectedSubtaskPoints[i] {
+			t.Errorf("Case %d: expected %g points and %g subtask points, got %g and %g",
+				i, expectedPoints[i], expectedSubtaskPoints[i], tc.Points, tc.SubtaskPoints)
+		}
+	}
+
+	invalid := []string{
+		config + "  - name: \"3\"\n    subtask: missing\n",
+		config + "  - name: \"4\"\n",
+		config + "  - name: \"1\"\n",
+	}
+	for _, c := range invalid {
+		if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := judge.LoadTestCases(taskDir); err == nil {
+			t.Errorf("Expected an error for config %s", c)
+		}
+	}
+}
+
+func TestLoadTestCasesCaseFiles(t *testing.T) {
+	taskDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(taskDir, "data"), 0755); err != nil {
+		t.Fatalf("Failed to create data dir: %v", err)
+	}
+	config := `
+name: "Report"
+cases:
+  - args: ["--verbose", "sales.csv"]
+    env:
+      LANG: "C"
+    files: ["data/sales.csv"]
+    expected_files:
+      - name: report.txt
+        file: data/report.txt
+`
+	files := map[string]string{
+		"config.yaml":     config,
+		"data/sales.csv":  "apple,2\n",
+		"data/report.txt": "total=2\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(taskDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+	if len(testCases) != 1 {
+		t.Fatalf("Expected 1 test case, got %d", len(testCases))
+	}
+
+	tc := testCases[0]
+	if strings.Join(tc.Args, " ") != "--verbose sales.csv" || tc.Env["LANG"] != "C" {
+		t.Errorf("Expected arguments and environment of the case, got %v and %v", tc.Args, tc.Env)
+	}
+	if len(tc.Files) != 1 || tc.Files[0] != filepath.Join(taskDir, "data", "sales.csv") {
+		t.Errorf("Expected the absolute path of the fixture, got %v", tc.Files)
+	}
+	if len(tc.ExpectedFiles) != 1 || tc.ExpectedFiles[0].Name != "report.txt" || tc.ExpectedFiles[0].Expected != "total=2\n" {
+		t.Errorf("Expected report.txt with the content of data/report.txt, got %+v", tc.ExpectedFiles)
+	}
+
+	// Fixtures must be inside the task directory and expected files inside the working directory
+	invalid := []string{
+		strings.Replace(config, "data/sales.csv", "../sales.csv", 1),
+		strings.Replace(config, "name: report.txt", "name: ../report.txt", 1),
+	}
+	for _, c := range invalid {
+		if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := judge.LoadTestCases(taskDir); err == nil {
+			t.Errorf("Expected an error for config %s", c)
+		}
+	}
+}
+
+func TestLoadTestCasesUnit(t *testing.T) {
+	taskDir := t.TempDir()
+	config := `
+name: "Functions"
+type: unit
+subtasks:
+  - name: edge
+    points: 3
+unit:
+  framework: pytest
+  files: ["test_solution.py"]
+  tests:
+    - name: test_add
+    - name: test_zero
+      subtask: edge
+      hidden: true
+`
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+	if len(testCases) != 1 || testCases[0].Unit == nil {
+		t.Fatalf("Expected a single unit test case, got %+v", testCases)
+	}
+
+	unit := testCases[0].Unit
+	if len(unit.Files) != 1 || unit.Files[0] != filepath.Join(taskDir, "test_solution.py") {
+		t.Errorf("Expected the absolute path of the test file, got %v", unit.Files)
+	}
+	if len(unit.Tests) != 2 {
+		t.Fatalf("Expected 2 unit tests, got %+v", unit.Tests)
+	}
+	if add := unit.Tests[0]; add.Name != "test_add" || add.Points == nil || *add.Points != 1 {
+		t.Errorf("Expected test_add with the default point, got %+v", add)
+	}
+	if zero := unit.Tests[1]; zero.Name != "test_zero" || zero.Subtask != "edge" || !zero.Hidden || zero.SubtaskPoints != 3 {
+		t.Errorf("Expected the hidden test_zero in subtask edge, got %+v", zero)
+	}
+
+	// Unit test tasks have no cases, junit needs a command writing the report and the frameworks test files
+	invalid := []string{
+		config + "cases:\n  - input: \"1\"\n    expected: \"1\"\n",
+		strings.Replace(config, "framework: pytest", "framework: junit", 1),
+		strings.Replace(config, `files: ["test_solution.py"]`, "files: []", 1),
+		strings.Replace(config, "framework: pytest", "framework: tap", 1),
+	}
+	for _, c := range invalid {
+		if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := judge.LoadTestCases(taskDir); err == nil {
+			t.Errorf("Expected an error for config %s", c)
+		}
+	}
+}
+
+func TestLoadTestCasesSQL(t *testing.T) {
+	taskDir := t.TempDir()
+	config := `
+name: "Orders"
+type: sql
+sql:
+  dataset: schema.sql
+  ignore_order: true
+cases:
+  - expected: |
+      name,total
+      Alice,3
+  - dataset: large.sql
+    input: "INSERT INTO orders VALUES ('Carol', 5);"
+    expected: |
+      name,total
+      Carol,5
+`
+	files := map[string]string{
+		"config.yaml": config,
+		"schema.sql":  "CREATE TABLE orders(name TEXT, total INT);",
+		"large.sql":   "CREATE TABLE orders(name TEXT, total INT);\nINSERT INTO orders VALUES ('Bob', 4);",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(taskDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	testCases, err := judge.LoadTestCases(taskDir)
+	if err != nil {
+		t.Fatalf("Failed to load test cases: %v", err)
+	}
+	if len(testCases) != 2 {
+		t.Fatalf("Expected 2 test cases, got %d", len(testCases))
+	}
+
+	// The dataset is the input, the statements of a case follow it
+	if testCases[0].Input != files["schema.sql"] {
+		t.Errorf("Expected the dataset of the task as input, got %q", testCases[0].Input)
+	}
+	if expected := files["large.sql"] + "\nINSERT INTO orders VALUES ('Carol', 5);"; testCases[1].Input != expected {
+		t.Errorf("Expected the dataset of the case followed by its input, got %q", testCases[1].Input)
+	}
+	for i, tc := range testCases {
+		if tc.SQL == nil || !tc.SQL.IgnoreOrder {
+			t.Errorf("Case %d: expected the comparison of the task, got %+v", i, tc.SQL)
+		}
+		if len(tc.Languages) != 1 || tc.Languages[0] != "sql" {
+			t.Errorf("Case %d: expected only sql solutions, got %v", i, tc.Languages)
+		}
+	}
+
+	invalid := []string{
+		config + "checker:\n  type: tokens\n",
+		strings.Replace(config, "dataset: large.sql", "dataset: ../large.sql", 1),
+		strings.Replace(config, "Carol,5", "\"Carol,5", 1),
+		strings.Replace(config, "type: sql", "type: io", 1),
+	}
+	for _, c := range invalid {
+		if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(c), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := judge.LoadTestCases(taskDir); err == nil {
+			t.Errorf("Expected an error for config %s", c)
+		}
+	}
+
+	// The expected rows of file cases are validated as well
+	if err := os.WriteFile(filepath.Join(taskDir, "config.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(taskDir, "tests"), 0755); err != nil {
+		t.Fatalf("Failed to create tests dir: %v", err)
+	}
+	for name, content := range map[string]string{"tests/1.in": "", "tests/1.out": "name,total\n\"Alice,3\n"} {
+		if err := os.WriteFile(filepath.Join(taskDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if _, err := judge.LoadTestCases(taskDir); err == nil {
+		t.Error("Expected an error for invalid expected rows of a file case")
+	}
+}
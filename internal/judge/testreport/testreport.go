@@ -0,0 +1,167 @@
+package testreport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJUnit = "junit"
+	FormatGo    = "go"
+)
+
+// Test is the outcome of a single test function. Failed assertions are wrong answers, unexpected errors
+// are runtime errors. Skipped tests are not passed, so a solution cannot skip the tests it fails.
+type Test struct {
+	Name    string
+	Status  status.Status
+	Message string
+	Time    time.Duration
+}
+
+// Parse parses a report of the format
+func Parse(format string, data []byte) ([]Test, error) {
+	switch format {
+	case FormatJUnit:
+		return ParseJUnit(data)
+	case FormatGo:
+		return ParseGo(data)
+	}
+	return nil, fmt.Errorf("unknown report format %s", format)
+}
+
+type junitCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    string        `xml:"time,attr"`
+	Failure *junitMessage `xml:"failure"`
+	Error   *junitMessage `xml:"error"`
+	Skipped *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// text returns the message of a failure or its first line if it has none
+func (m *junitMessage) text() string {
+	if m.Message != "" {
+		return strings.TrimSpace(m.Message)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(m.Text), "\n")
+	return line
+}
+
+// ParseJUnit parses a JUnit XML report as written by pytest --junitxml, Maven or Gradle. Test cases are read
+// from any suite of the report.
+func ParseJUnit(data []byte) ([]Test, error) {
+	tests := make([]Test, 0)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JUnit report: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "testcase" {
+			continue
+		}
+		var c junitCase
+		if err := decoder.DecodeElement(&c, &start); err != nil {
+			return nil, fmt.Errorf("invalid JUnit test case: %v", err)
+		}
+
+		test := Test{Name: c.Name, Status: status.StatusPassed}
+		if seconds, err := strconv.ParseFloat(c.Time, 64); err == nil {
+			test.Time = time.Duration(seconds * float64(time.Second))
+		}
+		switch {
+		case c.Error != nil:
+			test.Status, test.Message = status.StatusRuntimeError, c.Error.text()
+		case c.Failure != nil:
+			test.Status, test.Message = status.StatusWrongAnswer, c.Failure.text()
+		case c.Skipped != nil:
+			test.Status, test.Message = status.StatusWrongAnswer, "Test was skipped"
+		}
+		tests = append(tests, test)
+	}
+
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("JUnit report contains no tests")
+	}
+	return tests, nil
+}
+
+type goEvent struct {
+	Action  string  `json:"Action"`
+	Test    string  `json:"Test"`
+	Output  string  `json:"Output"`
+	Elapsed float64 `json:"Elapsed"`
+}
+
+// ParseGo parses the output of go test -json. Subtests fail their parent, so only top-level tests are reported.
+// Tests which never finished, e.g. because the test binary crashed, are runtime errors.
+func ParseGo(data []byte) ([]Test, error) {
+	tests := make([]Test, 0)
+	index := make(map[string]int)
+	output := make(map[string][]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event goEvent
+		// Lines which are no events, e.g. printed by the build, are ignored
+		if json.Unmarshal(scanner.Bytes(), &event) != nil || event.Test == "" {
+			continue
+		}
+
+		// The output of subtests is part of the message of their parent
+		name, subtest, _ := strings.Cut(event.Test, "/")
+		line := strings.TrimSpace(event.Output)
+		if event.Action == "output" && line != "" && !strings.HasPrefix(line, "=== ") && !strings.HasPrefix(line, "--- ") {
+			output[name] = append(output[name], line)
+		}
+		if subtest != "" {
+			continue
+		}
+
+		i, exists := index[event.Test]
+		if !exists {
+			i = len(tests)
+			index[event.Test] = i
+			tests = append(tests, Test{Name: event.Test, Status: status.StatusRuntimeError, Message: "Test did not finish"})
+		}
+
+		switch event.Action {
+		case "pass":
+			tests[i].Status, tests[i].Message = status.StatusPassed, ""
+		case "fail":
+			tests[i].Status, tests[i].Message = status.StatusWrongAnswer, strings.Join(output[event.Test], "\n")
+		case "skip":
+			tests[i].Status, tests[i].Message = status.StatusWrongAnswer, "Test was skipped"
+		}
+		if event.Elapsed > 0 {
+			tests[i].Time = time.Duration(event.Elapsed * float64(time.Second))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid go test report: %v", err)
+	}
+
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("go test report contains no tests")
+	}
+	return tests, nil
+}
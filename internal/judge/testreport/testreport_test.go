@@ -0,0 +1,82 @@
+package testreport_test
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/testreport"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// pytestReport is written by pytest --junitxml
+const pytestReport = `<?xml version="1.0" encoding="utf-8"?>
+<testsuites>
+  <testsuite name="pytest" errors="1" failures="1" skipped="1" tests="5">
+    <testcase classname="test_solution" name="test_add" time="0.250"/>
+    <testcase classname="test_solution" name="test_sub" time="0.001">
+      <failure message="assert -1 == 1">def test_sub():
+&gt;       assert sub(2, 1) == 1</failure>
+    </testcase>
+    <testcase classname="test_solution" name="test_div[1-0]" time="0.001">
+      <error message="ZeroDivisionError: division by zero">traceback</error>
+    </testcase>
+    <testcase classname="test_solution" name="test_mul" time="0.000">
+      <skipped type="pytest.skip" message="not implemented">skipped</skipped>
+    </testcase>
+    <testcase classname="test_solution" name="test_pow" time="0.000">
+      <failure>AssertionError
+second line</failure>
+    </testcase>
+  </testsuite>
+</testsuites>`
+
+// goReport is written by go test -json
+const goReport = `{"Action":"start","Package":"command-line-arguments"}
+{"Action":"run","Package":"command-line-arguments","Test":"TestAdd"}
+{"Action":"output","Package":"command-line-arguments","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"output","Package":"command-line-arguments","Test":"TestAdd","Output":"--- PASS: TestAdd (0.00s)\n"}
+{"Action":"pass","Package":"command-line-arguments","Test":"TestAdd","Elapsed":0.5}
+{"Action":"run","Package":"command-line-arguments","Test":"TestSub"}
+{"Action":"run","Package":"command-line-arguments","Test":"TestSub/negative"}
+{"Action":"output","Package":"command-line-arguments","Test":"TestSub/negative","Output":"    solution_test.go:12: Sub(1, 2) = 1, want -1\n"}
+{"Action":"fail","Package":"command-line-arguments","Test":"TestSub/negative","Elapsed":0}
+{"Action":"output","Package":"command-line-arguments","Test":"TestSub","Output":"    solution_test.go:14: 1 case failed\n"}
+{"Action":"fail","Package":"command-line-arguments","Test":"TestSub","Elapsed":0}
+{"Action":"run","Package":"command-line-arguments","Test":"TestLoop"}
+not an event
+{"Action":"fail","Package":"command-line-arguments","Elapsed":10}`
+
+func TestParseJUnit(t *testing.T) {
+	tests, err := testreport.Parse(testreport.FormatJUnit, []byte(pytestReport))
+	require.NoError(t, err)
+
+	assert.Equal(t, []testreport.Test{
+		{Name: "test_add", Status: status.StatusPassed, Time: 250 * time.Millisecond},
+		{Name: "test_sub", Status: status.StatusWrongAnswer, Message: "assert -1 == 1", Time: time.Millisecond},
+		{Name: "test_div[1-0]", Status: status.StatusRuntimeError, Message: "ZeroDivisionError: division by zero", Time: time.Millisecond},
+		{Name: "test_mul", Status: status.StatusWrongAnswer, Message: "Test was skipped"},
+		{Name: "test_pow", Status: status.StatusWrongAnswer, Message: "AssertionError"},
+	}, tests)
+
+	_, err = testreport.ParseJUnit([]byte(`<testsuite name="empty"/>`))
+	assert.Error(t, err)
+	_, err = testreport.ParseJUnit([]byte(`<testsuite><testcase`))
+	assert.Error(t, err)
+}
+
+func TestParseGo(t *testing.T) {
+	tests, err := testreport.Parse(testreport.FormatGo, []byte(goReport))
+	require.NoError(t, err)
+
+	assert.Equal(t, []testreport.Test{
+		{Name: "TestAdd", Status: status.StatusPassed, Time: 500 * time.Millisecond},
+		{Name: "TestSub", Status: status.StatusWrongAnswer, Message: "solution_test.go:12: Sub(1, 2) = 1, want -1\nsolution_test.go:14: 1 case failed"},
+		{Name: "TestLoop", Status: status.StatusRuntimeError, Message: "Test did not finish"},
+	}, tests)
+
+	_, err = testreport.ParseGo([]byte("# command-line-arguments\n./solution.go:3:1: syntax error\n"))
+	assert.Error(t, err)
+	_, err = testreport.Parse("tap", nil)
+	assert.Error(t, err)
+}
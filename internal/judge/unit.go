@@ -0,0 +1,186 @@
+package judge
+
+import (
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/judge/testreport"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// unitReport is the file in the working directory of the run the tests write their report to. The working directory
+// is not part of the checkout, so neither the files nor the output of the solution take the place of the report.
+const unitReport = "unit-report"
+
+// unitFormat returns the report format of a framework
+func unitFormat(unit *models.UnitConfig) string {
+	if unit.Framework == models.UnitFrameworkGo {
+		return testreport.FormatGo
+	}
+	return testreport.FormatJUnit
+}
+
+// unitCommand returns the command writing the report of the tests to $UNIT_REPORT. The commands of the frameworks
+// only run the test files of the instructor, tests of the student are neither run nor scored.
+func unitCommand(unit *models.UnitConfig) string {
+	if unit.Command != "" {
+		return unit.Command
+	}
+
+	names := make([]string, 0, len(unit.Files))
+	for _, file := range unit.Files {
+		names = append(names, shellQuote(filepath.Base(file)))
+	}
+	if unit.Framework == models.UnitFrameworkGo {
+		// go test builds the named files as one package, so the test files of the solution are left out
+		return fmt.Sprintf(`set --; for f in *.go; do case "$f" in *_test.go|%s) ;; *) set -- "$@" "$f" ;; esac; done; `+
+			`GOCACHE="${TMPDIR:-/tmp}/gocache" go test -json "$@" %s > "$UNIT_REPORT"`,
+			strings.Join(names, "|"), strings.Join(names, " "))
+	}
+	// The configuration and conftest.py files of the solution could change the collected tests
+	return fmt.Sprintf(`PYTHONDONTWRITEBYTECODE=1 python3 -m pytest -q -p no:cacheprovider --noconftest -c /dev/null --rootdir=. `+
+		`--junitxml="$UNIT_REPORT" %s >&2`, strings.Join(names, " "))
+}
+
+// unitLanguage returns the language of a solution which runs the tests of a unit test task instead of the
+// solution. The test command builds the solution itself, so the language is not compiled.
+func unitLanguage(lang *models.Language, unit *models.UnitConfig) *models.Language {
+	unitLang := *lang
+	unitLang.Compile = ""
+	unitLang.Run = `(export UNIT_REPORT="$PWD/` + unitReport + `"; cd {dir} && ` + unitCommand(unit) + ")"
+	return &unitLang
+}
+
+// copyUnitFiles copies the test files of a unit test task into the directory of the solution. Files of the
+// solution with the same name are replaced.
+func copyUnitFiles(dir string, files []string) error {
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read test file: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(file)), data, 0644); err != nil {
+			return fmt.Errorf("failed to write test file: %v", err)
+		}
+	}
+	return nil
+}
+
+// unitResults splits the result of a unit test task into a result per test. base is the result of the run, it
+// fails every configured test if the tests could not be run or exceeded a limit. Configured tests which are
+// missing in the report fail as well, so the maximum score does not depend on the solution.
+func unitResults(tc models.TestCase, base models.TestCaseResult, execResult *models.ExecutionResult) []models.TestCaseResult {
+	if execResult == nil {
+		return failUnitTests(tc, base)
+	}
+
+	// Failing tests make the test command fail, only the limits apply to the run
+	run := *execResult
+	run.ExitCode = 0
+	if s, message, failed := runVerdict(tc, &run); failed {
+		base.Status, base.Error = s, message
+		return failUnitTests(tc, base)
+	}
+
+	var tests []testreport.Test
+	err := fmt.Errorf("no report was written")
+	if report, written := execResult.Files[unitReport]; written {
+		tests, err = testreport.Parse(unitFormat(tc.Unit), []byte(report))
+	}
+	if err != nil {
+		base.Status, base.Error = status.StatusError, fmt.Sprintf("Tests could not be run: %v", err)
+		if execResult.ExitCode != 0 {
+			// Usually the solution does not compile
+			base.Status = status.StatusCompileError
+		}
+		if !hasHiddenUnitTests(tc.Unit) && execResult.Stderr != "" {
+			base.Stderr = stderrExcerpt(execResult.Stderr)
+		}
+		return failUnitTests(tc, base)
+	}
+
+	results := make([]models.TestCaseResult, 0, len(tests))
+	reported := make(map[string]bool)
+	for _, test := range tests {
+		config, configured := findUnitTest(tc.Unit, test.Name)
+		if !configured && len(tc.Unit.Tests) > 0 {
+			// Only the configured tests are scored, so additional tests do not change the maximum score
+			continue
+		}
+		reported[config.Name] = true
+
+		result := unitResult(base, config, test.Name)
+		// The message is shown in a table cell
+		result.Status, result.Error = test.Status, strings.ReplaceAll(stderrExcerpt(test.Message), "\n", " ")
+		result.ExecutionTime = test.Time
+		results = append(results, result)
+	}
+
+	for _, config := range tc.Unit.Tests {
+		if !reported[config.Name] {
+			result := unitResult(base, config, config.Name)
+			result.Status, result.Error = status.StatusWrongAnswer, "Test was not run"
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// failUnitTests reports the status of base for every configured test. Tasks without configured tests get a
+// single result.
+func failUnitTests(tc models.TestCase, base models.TestCaseResult) []models.TestCaseResult {
+	if len(tc.Unit.Tests) == 0 {
+		base.Points = 1
+		return []models.TestCaseResult{base}
+	}
+
+	results := make([]models.TestCaseResult, 0, len(tc.Unit.Tests))
+	for i, config := range tc.Unit.Tests {
+		result := unitResult(base, config, config.Name)
+		if i > 0 {
+			// The error output of the run is shown once
+			result.Stderr = ""
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// unitResult returns the result of a single test with the scoring and visibility of its configuration
+func unitResult(base models.TestCaseResult, config models.UnitTest, name string) models.TestCaseResult {
+	base.Name = name
+	base.IsHidden = config.Hidden
+	base.Points = casePoints(config.Points, config.Subtask)
+	base.Subtask = config.Subtask
+	base.SubtaskPoints = config.SubtaskPoints
+	return base
+}
+
+// findUnitTest returns the configuration of a test and if it is configured. Parametrized tests like test_add[1-2]
+// match the configuration of test_add. Tests which are not configured are visible and worth one point.
+func findUnitTest(unit *models.UnitConfig, name string) (models.UnitTest, bool) {
+	function, _, _ := strings.Cut(name, "[")
+	for _, config := range unit.Tests {
+		if config.Name == name {
+			return config, true
+		}
+	}
+	for _, config := range unit.Tests {
+		if config.Name == function {
+			return config, true
+		}
+	}
+	return models.UnitTest{Name: name}, false
+}
+
+// hasHiddenUnitTests reports if any test of the task is hidden
+func hasHiddenUnitTests(unit *models.UnitConfig) bool {
+	for _, config := range unit.Tests {
+		if config.Hidden {
+			return true
+		}
+	}
+	return false
+}
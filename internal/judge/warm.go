@@ -0,0 +1,265 @@
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// warmLabel marks the containers of the warm pool, so containers left behind by a previous run can be removed
+const warmLabel = "gitcodejudge.warm"
+
+// warmStartTimeout limits the time a replacement container may take to be created and started
+const warmStartTimeout = 2 * time.Minute
+
+// WarmPoolStats describes how well the warm pool serves the sandboxes
+type WarmPoolStats struct {
+	Size        int     `json:"size"` // Idle containers kept per environment
+	Idle        int     `json:"idle"`
+	Hits        int64   `json:"hits"`
+	Misses      int64   `json:"misses"`
+	HitRate     float64 `json:"hit_rate"`
+	ColdStartMs float64 `json:"cold_start_ms"` // Average time to create and start a container
+	WarmStartMs float64 `json:"warm_start_ms"` // Average time to prepare a warm container
+	SavedMs     float64 `json:"saved_ms"`      // Latency saved by all hits
+}
+
+// warmContainer is a started, idle container
+type warmContainer struct {
+	id     string
+	remove func()
+}
+
+// warmPool keeps started, idle containers per image and security profile. A sandbox takes one, gets its
+// resources and workspaces and removes it after use, so no state is shared between submissions. A replacement
+// is started in the background. Environments are warmed once they were requested the first time.
+type warmPool struct {
+	docker *DockerExecutor
+	size   int
+
+	mu      sync.Mutex
+	idle    map[string][]warmContainer
+	specs   map[string]containerRun // Requested environments, used to start replacements
+	filling map[string]int          // Containers being started per environment
+	closed  bool
+
+	hits, misses         int64
+	coldTotal, warmTotal time.Duration
+	coldCount            int64
+}
+
+func newWarmPool(docker *DockerExecutor, size int) *warmPool {
+	return &warmPool{
+		docker:  docker,
+		size:    size,
+		idle:    make(map[string][]warmContainer),
+		specs:   make(map[string]containerRun),
+		filling: make(map[string]int),
+	}
+}
+
+// warmKey identifies the environment of a container
+func warmKey(image string, security models.Security) string {
+	data, _ := json.Marshal(security)
+	return imageName(image) + "|" + string(data)
+}
+
+// removeStale removes idle containers left behind by a previous run of the judge
+func (w *warmPool) removeStale(ctx context.Context) {
+	containers, err := w.docker.cli.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", warmLabel)),
+	})
+	if err != nil {
+		log.WithError(err).Warn("Failed to list stale warm containers")
+		return
+	}
+
+	for _, c := range containers {
+		err := w.docker.cli.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true, RemoveVolumes: true})
+		if err != nil {
+			log.WithField("ContainerID", c.ID).WithError(err).Warn("Failed to remove stale warm container")
+		}
+	}
+}
+
+// acquire takes an idle container of the environment of spec and prepares it with the resources and workspaces
+// of spec. It returns false if no container was available.
+func (w *warmPool) acquire(ctx context.Context, spec containerRun) (string, func(), bool) {
+	w.mu.Lock()
+	key := w.register(spec.Image, spec.Security)
+	var warm warmContainer
+	found := len(w.idle[key]) > 0
+	if found {
+		warm = w.idle[key][0]
+		w.idle[key] = w.idle[key][1:]
+	}
+	w.mu.Unlock()
+	defer w.refill(key)
+
+	if !found {
+		w.record(false, 0)
+		return "", nil, false
+	}
+
+	start := time.Now()
+	fields := log.Fields{
+		"ContainerID": warm.id,
+	}
+	_, err := w.docker.cli.ContainerUpdate(ctx, warm.id, container.UpdateConfig{
+		Resources: containerResources(spec.Limits),
+	})
+	if err != nil {
+		log.WithFields(fields).WithError(err).Warn("Failed to update resources of warm container")
+		warm.remove()
+		w.record(false, 0)
+		return "", nil, false
+	}
+	if err := w.docker.copyWorkspaces(ctx, warm.id, spec.Workspaces); err != nil {
+		log.WithFields(fields).WithError(err).Warn("Failed to prepare warm container")
+		warm.remove()
+		w.record(false, 0)
+		return "", nil, false
+	}
+
+	w.record(true, time.Since(start))
+	return warm.id, warm.remove, true
+}
+
+// register remembers an environment, so it is kept warm. The caller must hold the lock.
+func (w *warmPool) register(image string, security models.Security) string {
+	key := warmKey(image, security)
+	if _, exists := w.specs[key]; !exists {
+		w.specs[key] = containerRun{
+			Image: image,
+			// Keeps the container alive until it is removed
+			Entrypoint: []string{"tail", "-f", "/dev/null"},
+			WorkingDir: "/",
+			Limits:     w.docker.limits,
+			Security:   security,
+			Labels:     map[string]string{warmLabel: "true"},
+		}
+	}
+	return key
+}
+
+// warmUp starts idle containers of the images with the default security profile
+func (w *warmPool) warmUp(images []string) {
+	for _, image := range images {
+		w.mu.Lock()
+		key := w.register(image, w.docker.EffectiveSecurity(models.Security{}))
+		w.mu.Unlock()
+		w.refill(key)
+	}
+}
+
+// refill starts containers in the background until the environment has enough idle containers
+func (w *warmPool) refill(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+	missing := w.size - len(w.idle[key]) - w.filling[key]
+	for i := 0; i < missing; i++ {
+		w.filling[key]++
+		go w.fill(key, w.specs[key])
+	}
+}
+
+// fill starts a single idle container
+func (w *warmPool) fill(key string, spec containerRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), warmStartTimeout)
+	defer cancel()
+
+	start := time.Now()
+	id, remove, err := w.docker.startContainer(ctx, spec)
+	if err == nil {
+		w.recordCold(time.Since(start))
+	} else {
+		log.WithField("Image", imageName(spec.Image)).WithError(err).Warn("Failed to start warm container")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.filling[key]--
+	if err != nil {
+		return
+	}
+	if w.closed {
+		go remove()
+		return
+	}
+	w.idle[key] = append(w.idle[key], warmContainer{id: id, remove: remove})
+}
+
+// record counts a hit with the time it took to prepare the container or a miss
+func (w *warmPool) record(hit bool, elapsed time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if hit {
+		w.hits++
+		w.warmTotal += elapsed
+	} else {
+		w.misses++
+	}
+}
+
+// recordCold records the time it took to create and start a container
+func (w *warmPool) recordCold(elapsed time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.coldTotal += elapsed
+	w.coldCount++
+}
+
+// stats returns the hit rate of the pool and the latency it saved
+func (w *warmPool) stats() WarmPoolStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := WarmPoolStats{
+		Size:   w.size,
+		Hits:   w.hits,
+		Misses: w.misses,
+	}
+	for _, idle := range w.idle {
+		stats.Idle += len(idle)
+	}
+	if w.hits+w.misses > 0 {
+		stats.HitRate = float64(w.hits) / float64(w.hits+w.misses)
+	}
+	if w.coldCount > 0 {
+		stats.ColdStartMs = float64(w.coldTotal) / float64(time.Millisecond) / float64(w.coldCount)
+	}
+	if w.hits > 0 {
+		stats.WarmStartMs = float64(w.warmTotal) / float64(time.Millisecond) / float64(w.hits)
+	}
+	if stats.ColdStartMs > stats.WarmStartMs {
+		stats.SavedMs = float64(w.hits) * (stats.ColdStartMs - stats.WarmStartMs)
+	}
+	return stats
+}
+
+// close removes the idle containers, containers started afterward are removed once they are ready
+func (w *warmPool) close() {
+	w.mu.Lock()
+	w.closed = true
+	idle := w.idle
+	w.idle = make(map[string][]warmContainer)
+	w.mu.Unlock()
+
+	for _, containers := range idle {
+		for _, warm := range containers {
+			warm.remove()
+		}
+	}
+}
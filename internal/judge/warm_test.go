@@ -0,0 +1,38 @@
+package judge
+
+import (
+	"context"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWarmKey(t *testing.T) {
+	readOnly := true
+	assert.Equal(t, warmKey("python:3", models.Security{User: "65534"}), warmKey("python:3", models.Security{User: "65534"}))
+	assert.NotEqual(t, warmKey("python:3", models.Security{}), warmKey("golang:1", models.Security{}))
+	assert.NotEqual(t, warmKey("python:3", models.Security{}), warmKey("python:3", models.Security{ReadOnlyRoot: &readOnly}))
+}
+
+func TestWarmPoolStats(t *testing.T) {
+	pool := newWarmPool(&DockerExecutor{}, 2)
+	pool.close()
+
+	// A closed pool has no idle containers and does not start new ones
+	_, _, found := pool.acquire(context.Background(), containerRun{Image: "python:3"})
+	assert.False(t, found)
+
+	pool.record(true, 20*time.Millisecond)
+	pool.record(true, 40*time.Millisecond)
+	pool.recordCold(500 * time.Millisecond)
+	pool.recordCold(700 * time.Millisecond)
+
+	stats := pool.stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.InDelta(t, 2.0/3.0, stats.HitRate, 0.001)
+	assert.InDelta(t, 600, stats.ColdStartMs, 0.001)
+	assert.InDelta(t, 30, stats.WarmStartMs, 0.001)
+	assert.InDelta(t, 1140, stats.SavedMs, 0.001)
+}
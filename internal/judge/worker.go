@@ -0,0 +1,242 @@
+package judge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// workerRetryDelay is the time a remote worker waits before it retries a failed request
+const workerRetryDelay = 5 * time.Second
+
+// workerResultAttempts is the number of times a remote worker tries to upload a result
+const workerResultAttempts = 5
+
+// WorkerClient talks to the worker API of a GitCodeJudge server
+type WorkerClient struct {
+	server string
+	token  string
+	name   string
+	client *http.Client
+}
+
+func NewWorkerClient(server string, token string, name string) *WorkerClient {
+	return &WorkerClient{
+		server: strings.TrimSuffix(server, "/"),
+		token:  token,
+		name:   name,
+		// Claims are held open by the server until a job is available
+		client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Claim asks the server for the next job. It returns nil if no job became available while the server waited.
+func (c *WorkerClient) Claim(ctx context.Context, capacity int) (*models.WorkerAssignment, error) {
+	var assignment models.WorkerAssignment
+	code, err := c.do(ctx, "/worker/jobs/claim", models.WorkerClaim{Capacity: capacity}, &assignment)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusNoContent {
+		return nil, nil
+	}
+	return &assignment, nil
+}
+
+// Heartbeat keeps a job alive. It returns ErrJobCancelled if the job should be stopped and ErrJobNotAssigned if
+// the server took the job away.
+func (c *WorkerClient) Heartbeat(ctx context.Context, id string) error {
+	_, err := c.do(ctx, "/worker/jobs/"+id+"/heartbeat", struct{}{}, nil)
+	return err
+}
+
+// Complete uploads the result of a job. execErr is sent instead if the job could not be judged.
+func (c *WorkerClient) Complete(ctx context.Context, id string, result *models.TestResult, execErr error) error {
+	req := models.WorkerResult{Result: result}
+	if execErr != nil {
+		req = models.WorkerResult{Error: execErr.Error()}
+	}
+	_, err := c.do(ctx, "/worker/jobs/"+id+"/result", req, nil)
+	return err
+}
+
+// do posts a request to the worker API and decodes a successful response into out
+func (c *WorkerClient) do(ctx context.Context, path string, body any, out any) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.server+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set(models.WorkerHeader, c.name)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if out == nil {
+			return resp.StatusCode, nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response: %v", err)
+		}
+		return resp.StatusCode, nil
+	case http.StatusNoContent:
+		return resp.StatusCode, nil
+	case http.StatusConflict:
+		return resp.StatusCode, ErrJobNotAssigned
+	case http.StatusGone:
+		return resp.StatusCode, ErrJobCancelled
+	}
+
+	var result struct {
+		Error string `json:"error"`
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(message, &result) == nil && result.Error != "" {
+		return resp.StatusCode, fmt.Errorf("server responded %d: %s", resp.StatusCode, result.Error)
+	}
+	return resp.StatusCode, fmt.Errorf("server responded %d", resp.StatusCode)
+}
+
+// Worker judges the jobs of a remote server with a local executor
+type Worker struct {
+	client      *WorkerClient
+	executor    *Executor
+	concurrency int
+}
+
+func NewWorker(client *WorkerClient, executor *Executor, concurrency int) *Worker {
+	return &Worker{
+		client:      client,
+		executor:    executor,
+		concurrency: max(concurrency, 1),
+	}
+}
+
+// Run claims and judges jobs until ctx is done. Running jobs are finished before it returns.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		assignment, err := w.client.Claim(ctx, w.concurrency)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.WithError(err).Error("Failed to claim job")
+			sleepContext(ctx, workerRetryDelay)
+			continue
+		}
+		if assignment == nil {
+			continue
+		}
+
+		// A claimed job is finished even if the worker is stopped
+		w.process(*assignment)
+	}
+}
+
+// process judges a claimed job while sending heartbeats and uploads its result
+func (w *Worker) process(assignment models.WorkerAssignment) {
+	job := assignment.Job
+	fields := log.Fields{
+		"Job":    job.ID,
+		"Repo":   job.Submission.RepoName,
+		"Commit": job.Submission.CommitID,
+	}
+	log.WithFields(fields).Info("Judging job")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	interval := time.Duration(assignment.HeartbeatInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+	go w.heartbeat(ctx, cancel, job.ID, interval)
+
+	result, execErr := w.executor.Execute(ctx, job.Submission)
+	if cause := context.Cause(ctx); errors.Is(cause, ErrJobNotAssigned) {
+		log.WithFields(fields).Warn("Job was taken away by the server")
+		return
+	} else if cause != nil {
+		execErr = cause
+	}
+	cancel(nil)
+
+	for attempt := 1; ; attempt++ {
+		err := w.client.Complete(context.Background(), job.ID, result, execErr)
+		if err == nil {
+			log.WithFields(fields).Info("Uploaded result")
+			return
+		}
+		if errors.Is(err, ErrJobNotAssigned) || attempt >= workerResultAttempts {
+			log.WithFields(fields).WithError(err).Error("Failed to upload result")
+			return
+		}
+		log.WithFields(fields).WithError(err).Warn("Failed to upload result, retrying")
+		time.Sleep(workerRetryDelay)
+	}
+}
+
+// heartbeat keeps a job alive until ctx is done. The job is cancelled if the server cancelled it or took it away.
+func (w *Worker) heartbeat(ctx context.Context, cancel context.CancelCauseFunc, id string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.client.Heartbeat(ctx, id)
+			switch {
+			case errors.Is(err, ErrJobCancelled), errors.Is(err, ErrJobNotAssigned):
+				cancel(err)
+				return
+			case err != nil && ctx.Err() == nil:
+				// The server requeues the job only after several missed heartbeats
+				log.WithField("Job", id).WithError(err).Warn("Failed to send heartbeat")
+			}
+		}
+	}
+}
+
+// sleepContext waits for the duration or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
@@ -0,0 +1,207 @@
+package judge
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Modes of making the workspaces of a program available to judge containers
+const (
+	WorkspaceMount = "mount" // Bind mount local directories, in Docker they are created in /repos
+	WorkspaceCopy  = "copy"  // Copy the directories into the container and read outputs back
+)
+
+// workspaceVolume is the anonymous volume the workspaces are copied to. Copying is only possible into
+// volumes if the root filesystem of the container is read-only.
+const workspaceVolume = "/workspace"
+
+// workspace is a local directory made available inside a container
+type workspace struct {
+	Dir      string // Local directory
+	Name     string // Name of the directory inside the container, e.g. repo
+	ReadOnly bool
+	Output   bool // Copied back once the container exited
+}
+
+// containerPath returns the path of a workspace inside a container
+func (e *DockerExecutor) containerPath(name string) string {
+	if e.workspace == WorkspaceCopy {
+		return path.Join(workspaceVolume, name)
+	}
+	return "/" + name
+}
+
+// workspaceMounts returns the mounts of the workspaces of a container
+func (e *DockerExecutor) workspaceMounts(workspaces []workspace) []mount.Mount {
+	if e.workspace == WorkspaceCopy {
+		// The volume is removed together with the container. Warm containers get it before their workspaces are known.
+		return []mount.Mount{{Type: mount.TypeVolume, Target: workspaceVolume}}
+	}
+
+	mounts := make([]mount.Mount, 0, len(workspaces))
+	for _, ws := range workspaces {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   getHostPath(ws.Dir),
+			Target:   e.containerPath(ws.Name),
+			ReadOnly: ws.ReadOnly,
+		})
+	}
+	return mounts
+}
+
+// copyWorkspaces copies the workspaces into a created container
+func (e *DockerExecutor) copyWorkspaces(ctx context.Context, containerID string, workspaces []workspace) error {
+	if e.workspace != WorkspaceCopy {
+		return nil
+	}
+
+	for _, ws := range workspaces {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(tarWorkspace(writer, ws))
+		}()
+		err := e.cli.CopyToContainer(ctx, containerID, workspaceVolume, reader, container.CopyToContainerOptions{})
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to copy %s into container: %v", ws.Name, err)
+		}
+	}
+	return nil
+}
+
+// copyOutputs reads the output workspaces back from an exited container
+func (e *DockerExecutor) copyOutputs(ctx context.Context, containerID string, workspaces []workspace) error {
+	if e.workspace != WorkspaceCopy {
+		return nil
+	}
+
+	for _, ws := range workspaces {
+		if !ws.Output {
+			continue
+		}
+		reader, _, err := e.cli.CopyFromContainer(ctx, containerID, e.containerPath(ws.Name))
+		if err != nil {
+			return fmt.Errorf("failed to copy %s from container: %v", ws.Name, err)
+		}
+		err = extractWorkspace(reader, ws.Dir)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("failed to copy %s from container: %v", ws.Name, err)
+		}
+	}
+	return nil
+}
+
+// tarWorkspace writes a workspace as tar archive below its name. The files are owned by root, so the sandbox user
+// can only write to writable workspaces, which are world-writable. Git metadata is not copied.
+func tarWorkspace(w io.Writer, ws workspace) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(ws.Dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ws.Dir, file)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && rel == ".git" {
+			return filepath.SkipDir
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		} else if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(ws.Name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid = 0, 0
+		header.Uname, header.Gname = "", ""
+		if ws.ReadOnly {
+			header.Mode &^= 0022
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// extractWorkspace extracts a directory archived by the daemon into dir. The archive contains the directory
+// itself, its name is stripped. Only directories and regular files are extracted, the content was written by
+// the program and is not trusted.
+func extractWorkspace(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, name, _ := strings.Cut(strings.TrimPrefix(header.Name, "/"), "/")
+		name = strings.TrimSuffix(name, "/")
+		if name == "" {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid path %s in archive", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm()|0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
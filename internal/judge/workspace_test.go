@@ -0,0 +1,64 @@
+package judge
+
+import (
+	"archive/tar"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "workshop1", "task1"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "workshop1", "task1", "solution.py"), []byte("print(42)"), 0666))
+	require.NoError(t, os.WriteFile(filepath.Join(src, ".git", "HEAD"), []byte("ref"), 0644))
+
+	var archive bytes.Buffer
+	require.NoError(t, tarWorkspace(&archive, workspace{Dir: src, Name: "repo", ReadOnly: true}))
+
+	modes := make(map[string]int64)
+	tr := tar.NewReader(bytes.NewReader(archive.Bytes()))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, 0, header.Uid)
+		modes[header.Name] = header.Mode & 0777
+	}
+	assert.NotContains(t, modes, "repo/.git/")
+	assert.Equal(t, int64(0644), modes["repo/workshop1/task1/solution.py"])
+
+	dst := t.TempDir()
+	require.NoError(t, extractWorkspace(bytes.NewReader(archive.Bytes()), dst))
+	content, err := os.ReadFile(filepath.Join(dst, "workshop1", "task1", "solution.py"))
+	require.NoError(t, err)
+	assert.Equal(t, "print(42)", string(content))
+}
+
+func TestExtractWorkspaceUntrusted(t *testing.T) {
+	archive := func(headers ...*tar.Header) io.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, header := range headers {
+			require.NoError(t, tw.WriteHeader(header))
+		}
+		require.NoError(t, tw.Close())
+		return &buf
+	}
+
+	dst := t.TempDir()
+	err := extractWorkspace(archive(&tar.Header{Name: "build/../../evil", Typeflag: tar.TypeReg, Mode: 0644}), dst)
+	assert.Error(t, err)
+
+	err = extractWorkspace(archive(&tar.Header{Name: "build/link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}), dst)
+	require.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(dst, "link"))
+	assert.True(t, os.IsNotExist(err))
+}
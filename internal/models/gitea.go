@@ -1,12 +1,46 @@
 package models
 
 type GiteaPushEvent struct {
-	Ref        string `json:"ref"`    // refs/heads/develop
-	Before     string `json:"before"` // 28e1879d029cb852e4844d9c718537df08844e03
-	After      string `json:"after"`  // bffeb74224043ba2feb48d137756c8a9331c449a
+	Ref        string            `json:"ref"`    // refs/heads/develop
+	Before     string            `json:"before"` // 28e1879d029cb852e4844d9c718537df08844e03
+	After      string            `json:"after"`  // bffeb74224043ba2feb48d137756c8a9331c449a
+	Commits    []GiteaPushCommit `json:"commits"`
 	Repository struct {
 		Name     string `json:"name"`      // webhooks
 		FullName string `json:"full_name"` // gitea/webhooks
 		CloneURL string `json:"clone_url"` // http://localhost:3000/gitea/webhooks.git
 	} `json:"repository"`
 }
+
+// GiteaPushCommit is a commit of a push. Gitea only sends a limited number of commits per push.
+type GiteaPushCommit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// CommitMessages returns the messages of the commits of the push
+func (e GiteaPushEvent) CommitMessages() []string {
+	messages := make([]string, 0, len(e.Commits))
+	for _, commit := range e.Commits {
+		messages = append(messages, commit.Message)
+	}
+	return messages
+}
+
+// ChangedFiles returns the files added or modified by the commits of the push
+func (e GiteaPushEvent) ChangedFiles() []string {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	for _, commit := range e.Commits {
+		for _, file := range append(commit.Added, commit.Modified...) {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
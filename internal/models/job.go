@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type JobState string
+
+const (
+	JobQueued  JobState = "queued"
+	JobRunning JobState = "running"
+)
+
+// JobPriority is the scheduling class of a job. Jobs of a higher class are always started first.
+type JobPriority int
+
+const (
+	PriorityLow    JobPriority = -1 // e.g. rejudges started by an instructor
+	PriorityNormal JobPriority = 0
+	PriorityHigh   JobPriority = 1 // e.g. pushes close to a task deadline
+)
+
+func (p JobPriority) String() string {
+	switch {
+	case p < PriorityNormal:
+		return "low"
+	case p > PriorityNormal:
+		return "high"
+	}
+	return "normal"
+}
+
+// Job is a submission waiting in or being processed by the judge pool. Jobs are persisted
+// so they survive restarts of the server.
+type Job struct {
+	ID         string      `json:"id"`
+	Submission Submission  `json:"submission"`
+	State      JobState    `json:"state"`
+	Priority   JobPriority `json:"priority"`
+	Attempts   int         `json:"attempts"`
+	EnqueuedAt time.Time   `json:"enqueued_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	Worker     string      `json:"worker,omitempty"` // Remote worker processing the job, empty for in-process workers
+}
@@ -0,0 +1,22 @@
+package models
+
+import "strings"
+
+// Language describes how solutions of a programming language are compiled and run
+type Language struct {
+	Name     string   `yaml:"name"`
+	Patterns []string `yaml:"patterns"` // File name patterns of solutions, e.g. solution.py
+	Image    string   `yaml:"image"`    // Docker image, the judge image is used if empty
+	Compile  string   `yaml:"compile"`  // Compile command, interpreted languages have none
+	Run      string   `yaml:"run"`      // Run command, gets the input on stdin
+	Limits   *Limits  `yaml:"limits"`   // Limits of the language, overridden by the limits of the task
+}
+
+// Command fills the placeholders of a compile or run command
+func (l *Language) Command(command string, file string, dir string, build string) string {
+	return strings.NewReplacer(
+		"{file}", file,
+		"{dir}", dir,
+		"{build}", build,
+	).Replace(command)
+}
@@ -0,0 +1,37 @@
+package models
+
+import "errors"
+
+// RejudgeRequest selects the submissions to judge again. Exactly one of Commit, Workshop/Task or All is set.
+type RejudgeRequest struct {
+	Commit   string `json:"commit,omitempty"`
+	Workshop string `json:"workshop,omitempty"`
+	Task     string `json:"task,omitempty"`
+	All      bool   `json:"all,omitempty"` // Latest commit of every repository of Org
+	Org      string `json:"org,omitempty"`
+}
+
+// Validate checks that the request selects exactly one kind of target
+func (r RejudgeRequest) Validate() error {
+	targets := 0
+	if r.Commit != "" {
+		targets++
+	}
+	if r.Workshop != "" || r.Task != "" {
+		if r.Workshop == "" || r.Task == "" {
+			return errors.New("workshop and task are both required")
+		}
+		targets++
+	}
+	if r.All {
+		if r.Org == "" {
+			return errors.New("org is required to rejudge all repositories")
+		}
+		targets++
+	}
+
+	if targets != 1 {
+		return errors.New("exactly one of commit, workshop/task or all must be set")
+	}
+	return nil
+}
@@ -22,14 +22,35 @@ func FormatTestResult(result *TestResult) string {
 		b.WriteString("## ⚠️ Execution Error\n\n")
 	}
 
+	if result.Skipped {
+		b.WriteString("## ⏭️ Judging Skipped\n\n")
+		b.WriteString("The commit message contains `[skip judge]`.\n\n")
+		writeDirectiveHelp(&b, result.Directives)
+		return b.String()
+	}
+
+	if len(result.Scores) > 0 {
+		b.WriteString("### Score\n\n")
+		b.WriteString("| Task | Points |\n")
+		b.WriteString("|------|--------|\n")
+		for _, score := range result.Scores {
+			b.WriteString(fmt.Sprintf("| %s/%s | %g/%g |\n",
+				score.Solution.Workshop,
+				score.Solution.Task,
+				score.Score,
+				score.MaxScore))
+		}
+		b.WriteString("\n")
+	}
+
 	// Write detailed results for each test case
 	b.WriteString("### Test Results\n\n")
-	b.WriteString("| Test # | Task | Status | Time | Details |\n")
-	b.WriteString("|--------|------|--------|------|----------|\n")
+	b.WriteString("| Test # | Task | Status | Verdict | Time | CPU | Memory | Details |\n")
+	b.WriteString("|--------|------|--------|---------|------|-----|--------|----------|\n")
 
 	for _, tc := range result.TestCases {
 		resultStatus := "✅"
-		if tc.Status == status.StatusFailed {
+		if tc.Status.IsFailure() {
 			resultStatus = "❌"
 		} else if tc.Status == status.StatusError {
 			resultStatus = "⚠️"
@@ -44,18 +65,190 @@ func FormatTestResult(result *TestResult) string {
 			details = "_redacted output for hidden test_"
 		}
 
-		b.WriteString(fmt.Sprintf("| %d | %s/%s | %s | %.2fs | %s |\n",
-			tc.TestNumber,
+		// Hidden tests of unit test tasks do not reveal their name
+		test := fmt.Sprintf("%d", tc.TestNumber)
+		if tc.Name != "" && !tc.IsHidden {
+			test += fmt.Sprintf(" `%s`", tc.Name)
+		}
+
+		// Also hides MemoryUnknown
+		memory := "-"
+		if tc.MemoryPeak > 0 {
+			memory = fmt.Sprintf("%.1f MB", float64(tc.MemoryPeak)/1024/1024)
+		}
+
+		b.WriteString(fmt.Sprintf("| %s | %s/%s | %s | %s | %.2fs | %.2fs | %s | %s |\n",
+			test,
 			tc.Solution.Workshop,
 			tc.Solution.Task,
 			resultStatus,
+			tc.Status.Description(),
 			tc.ExecutionTime.Seconds(),
+			tc.CPUTime.Seconds(),
+			memory,
 			details))
 	}
 
+	b.WriteString("\n")
+	writeStderr(&b, result.TestCases)
+	writeTranscripts(&b, result.TestCases)
+	writeResultSets(&b, result.TestCases)
+	writeEnvironment(&b, result.TestCases)
+	writeDirectiveHelp(&b, result.Directives)
+
 	return b.String()
 }
 
+// writeStderr shows the error output of public test cases
+func writeStderr(b *strings.Builder, testCases []TestCaseResult) {
+	header := false
+	for _, tc := range testCases {
+		if tc.IsHidden || tc.Stderr == "" {
+			continue
+		}
+		if !header {
+			b.WriteString("### Error Output\n\n")
+			header = true
+		}
+		b.WriteString(fmt.Sprintf("Test %d (%s/%s):\n\n```\n%s\n```\n\n",
+			tc.TestNumber,
+			tc.Solution.Workshop,
+			tc.Solution.Task,
+			strings.ReplaceAll(tc.Stderr, "```", "` ` `")))
+	}
+}
+
+// writeTranscripts shows the conversation of the solution with the interactor of public interactive cases.
+// Lines of the solution start with > and lines of the interactor with <.
+func writeTranscripts(b *strings.Builder, testCases []TestCaseResult) {
+	header := false
+	for _, tc := range testCases {
+		if tc.IsHidden || tc.Transcript == "" {
+			continue
+		}
+		if !header {
+			b.WriteString("### Transcripts\n\n")
+			header = true
+		}
+		b.WriteString(fmt.Sprintf("Test %d (%s/%s):\n\n```\n%s\n```\n\n",
+			tc.TestNumber,
+			tc.Solution.Workshop,
+			tc.Solution.Task,
+			strings.ReplaceAll(tc.Transcript, "```", "` ` `")))
+	}
+}
+
+// writeResultSets shows the rows returned by the query of failed public SQL cases
+func writeResultSets(b *strings.Builder, testCases []TestCaseResult) {
+	header := false
+	for _, tc := range testCases {
+		if tc.IsHidden || tc.ResultSet == nil {
+			continue
+		}
+		if !header {
+			b.WriteString("### Result Sets\n\n")
+			header = true
+		}
+		b.WriteString(fmt.Sprintf("Test %d (%s/%s):\n\n",
+			tc.TestNumber,
+			tc.Solution.Workshop,
+			tc.Solution.Task))
+		writeTableRow(b, tc.ResultSet.Columns)
+		b.WriteString("|" + strings.Repeat("---|", len(tc.ResultSet.Columns)) + "\n")
+		for _, row := range tc.ResultSet.Rows {
+			writeTableRow(b, row)
+		}
+		if more := tc.ResultSet.Total - len(tc.ResultSet.Rows); more > 0 {
+			b.WriteString(fmt.Sprintf("\n_%d more rows_\n", more))
+		}
+		b.WriteString("\n")
+	}
+}
+
+// writeTableRow writes a row of a markdown table, the values may contain any text
+func writeTableRow(b *strings.Builder, values []string) {
+	b.WriteString("|")
+	for _, value := range values {
+		b.WriteString(" " + strings.ReplaceAll(strings.ReplaceAll(value, "|", "\\|"), "\n", " ") + " |")
+	}
+	b.WriteString("\n")
+}
+
+// writeEnvironment lists the language and image every task was judged with
+func writeEnvironment(b *strings.Builder, testCases []TestCaseResult) {
+	seen := make(map[Solution]bool)
+	rows := make([]string, 0)
+	for _, tc := range testCases {
+		if tc.Image == "" || seen[tc.Solution] {
+			continue
+		}
+		seen[tc.Solution] = true
+		rows = append(rows, fmt.Sprintf("| %s/%s | %s | `%s` | `%s` |\n",
+			tc.Solution.Workshop,
+			tc.Solution.Task,
+			tc.Language,
+			tc.Image,
+			tc.ImageDigest))
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	b.WriteString("### Environment\n\n")
+	b.WriteString("| Task | Language | Image | Digest |\n")
+	b.WriteString("|------|----------|-------|--------|\n")
+	for _, row := range rows {
+		b.WriteString(row)
+	}
+	b.WriteString("\n")
+}
+
+// writeDirectiveHelp documents the commit message directives and the ones applied to this result
+func writeDirectiveHelp(b *strings.Builder, applied string) {
+	b.WriteString("### Commit Message Directives\n\n")
+	if applied != "" {
+		b.WriteString(fmt.Sprintf("Applied: `%s`\n\n", applied))
+	}
+	b.WriteString("By default the tasks changed by a push are judged. Add a line to your commit message to change this:\n\n")
+	b.WriteString("| Directive | Effect |\n")
+	b.WriteString("|-----------|--------|\n")
+	b.WriteString("| `[skip judge]` | The commit is not judged |\n")
+	b.WriteString("| `judge: workshop1/task1` | Also judge the task, even if it was not changed. Separate multiple tasks by commas |\n")
+	b.WriteString("| `judge: all` | Judge every task of the repository |\n")
+}
+
+// Summary returns a short description of the result which fits into a commit status,
+// e.g. "2/4 tests passed (TLE on test 3)"
+func (result *TestResult) Summary() string {
+	if len(result.TestCases) == 0 {
+		return ""
+	}
+
+	passed := 0
+	var firstFailure *TestCaseResult
+	for i, tc := range result.TestCases {
+		if tc.Status == status.StatusPassed {
+			passed++
+		} else if firstFailure == nil {
+			firstFailure = &result.TestCases[i]
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d tests passed", passed, len(result.TestCases))
+	if len(result.Scores) > 0 {
+		var score, maxScore float64
+		for _, s := range result.Scores {
+			score += s.Score
+			maxScore += s.MaxScore
+		}
+		summary += fmt.Sprintf(", %g/%g points", score, maxScore)
+	}
+	if firstFailure != nil {
+		summary += fmt.Sprintf(" (%s on test %d)", firstFailure.Status.Short(), firstFailure.TestNumber)
+	}
+	return summary
+}
+
 func FormatWorkshopStats(workshop string, task string, stats *WorkshopStats) string {
 	var b strings.Builder
 	b.WriteString(fmt.Sprintf("# Statistics for [%s/%s](/pdf?task=%s/%s)\n\n", workshop, task, workshop, task))
@@ -100,23 +293,29 @@ func FormatUserStats(progress *ScoreboardUserProgress) string {
 
 	// Task count summary
 	b.WriteString(fmt.Sprintf("## Overview\n\n"))
-	b.WriteString(fmt.Sprintf("Total Completed Tasks: **%d**\n\n", len(progress.Submissions)))
+	b.WriteString(fmt.Sprintf("Total Points: **%g**\n\n", progress.Points()))
+	b.WriteString(fmt.Sprintf("Total Completed Tasks: **%d**\n\n", progress.CompletedTasks()))
 
 	// Sort submissions by timestamp (most recent first)
 	sort.Slice(progress.Submissions, func(i, j int) bool {
 		return progress.Submissions[i].Submission.Timestamp.After(progress.Submissions[j].Submission.Timestamp)
 	})
 
-	b.WriteString("## Completed Tasks\n\n")
-	b.WriteString("| Workshop | Task | Completion Date | Repository | Commit |\n")
-	b.WriteString("|----------|------|-----------------|------------|--------|\n")
+	b.WriteString("## Best Scores\n\n")
+	b.WriteString("| Workshop | Task | Points | Date | Repository | Commit |\n")
+	b.WriteString("|----------|------|--------|------|------------|--------|\n")
 
 	for _, submission := range progress.Submissions {
-		b.WriteString(fmt.Sprintf("| %s | [%s](/workshop/%s/%s) | %s | [%s](%s) | [`%s`](%s/results/%s) |\n",
+		points := fmt.Sprintf("%g/%g", submission.Score, submission.MaxScore)
+		if submission.Completed() {
+			points += " ✅"
+		}
+		b.WriteString(fmt.Sprintf("| %s | [%s](/workshop/%s/%s) | %s | %s | [%s](%s) | [`%s`](%s/results/%s) |\n",
 			submission.Workshop,
 			submission.Task,
 			submission.Workshop,
 			submission.Task,
+			points,
 			submission.Submission.Timestamp.Format(time.RFC850),
 			submission.Submission.RepoName,
 			submission.Submission.CloneURL,
@@ -124,6 +323,31 @@ func FormatUserStats(progress *ScoreboardUserProgress) string {
 			config.CFG.BaseURL,
 			submission.Submission.CommitID))
 	}
+
+	if len(progress.Attempts) > 0 {
+		sort.Slice(progress.Attempts, func(i, j int) bool {
+			return progress.Attempts[i].Timestamp.After(progress.Attempts[j].Timestamp)
+		})
+
+		b.WriteString("\n## Latest Attempts\n\n")
+		b.WriteString("| Workshop | Task | Verdict | Points | Date | Commit |\n")
+		b.WriteString("|----------|------|---------|--------|------|--------|\n")
+
+		for _, attempt := range progress.Attempts {
+			b.WriteString(fmt.Sprintf("| %s | [%s](/workshop/%s/%s) | %s | %g/%g | %s | [`%s`](%s/results/%s) |\n",
+				attempt.Workshop,
+				attempt.Task,
+				attempt.Workshop,
+				attempt.Task,
+				attempt.Status.Description(),
+				attempt.Score,
+				attempt.MaxScore,
+				attempt.Timestamp.Format(time.RFC850),
+				attempt.CommitID[:8],
+				config.CFG.BaseURL,
+				attempt.CommitID))
+		}
+	}
 	return b.String()
 }
 
@@ -136,14 +360,15 @@ func FormatLeaderboard(leaderboard []Leaderboard) string {
 		b.WriteString(fmt.Sprintf("Showing top %d participants\n\n", len(leaderboard)))
 	}
 
-	b.WriteString("| Rank | User | Completed Tasks | Latest Submission | Latest Repository |\n")
-	b.WriteString("|------|------|-----------------|-------------------|------------------|\n")
+	b.WriteString("| Rank | User | Points | Completed Tasks | Latest Submission | Latest Repository |\n")
+	b.WriteString("|------|------|--------|-----------------|-------------------|------------------|\n")
 
 	for i, entry := range leaderboard {
-		b.WriteString(fmt.Sprintf("| %d | [%s](/user/%s) | %d | %s | %s |\n",
+		b.WriteString(fmt.Sprintf("| %d | [%s](/user/%s) | %g | %d | %s | %s |\n",
 			i+1,
 			entry.Username,
 			entry.Username,
+			entry.Points,
 			entry.CompletedTasks,
 			entry.LastSubmission.Format(time.RFC850),
 			entry.LatestRepoName))
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
 	"time"
 )
 
@@ -10,19 +12,86 @@ type ScoreboardWorkshopTask struct {
 }
 
 type ScoreboardUserSubmission struct {
-	RepoName  string    `json:"repo_name"`
-	CommitID  string    `json:"commit_id"`
-	CloneURL  string    `json:"clone_url"`
-	Timestamp time.Time `json:"timestamp"`
+	RepoName   string    `json:"repo_name"`
+	CommitID   string    `json:"commit_id"`
+	BranchName string    `json:"branch_name"`
+	CloneURL   string    `json:"clone_url"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ScoreboardAttempt is the latest verdict of a user for a workshop/task, whether it passed or not
+type ScoreboardAttempt struct {
+	Workshop   string        `json:"workshop"`
+	Task       string        `json:"task"`
+	Status     status.Status `json:"status"`
+	Score      float64       `json:"score"`
+	MaxScore   float64       `json:"max_score"`
+	CommitID   string        `json:"commit_id"`
+	RepoName   string        `json:"repo_name"`
+	BranchName string        `json:"branch_name"`
+	CloneURL   string        `json:"clone_url"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// ScoreboardUserTask is the best submission of a user for a workshop/task
+type ScoreboardUserTask struct {
+	Workshop   string                   `json:"workshop"`
+	Task       string                   `json:"task"`
+	Score      float64                  `json:"score"`
+	MaxScore   float64                  `json:"max_score"`
+	Submission ScoreboardUserSubmission `json:"submission"`
+}
+
+// UnmarshalJSON reads records written before submissions were scored. Only passed tasks were recorded then, so a
+// record without a maximum score is a completed task worth one point.
+func (t *ScoreboardUserTask) UnmarshalJSON(data []byte) error {
+	type plain ScoreboardUserTask
+	var record struct {
+		plain
+		MaxScore *float64 `json:"max_score"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+
+	*t = ScoreboardUserTask(record.plain)
+	if record.MaxScore == nil {
+		t.Score, t.MaxScore = 1, 1
+	} else {
+		t.MaxScore = *record.MaxScore
+	}
+	return nil
+}
+
+// Completed reports whether the task was solved with full score
+func (t ScoreboardUserTask) Completed() bool {
+	return t.Score >= t.MaxScore
 }
 
 type ScoreboardUserProgress struct {
-	User        string `json:"user"`
-	Submissions []struct {
-		Workshop   string                   `json:"workshop"`
-		Task       string                   `json:"task"`
-		Submission ScoreboardUserSubmission `json:"submission"`
-	} `json:"submissions"`
+	User        string               `json:"user"`
+	Submissions []ScoreboardUserTask `json:"submissions"`
+	Attempts    []ScoreboardAttempt  `json:"attempts"`
+}
+
+// Points returns the sum of the best scores of all tasks
+func (p *ScoreboardUserProgress) Points() float64 {
+	var points float64
+	for _, s := range p.Submissions {
+		points += s.Score
+	}
+	return points
+}
+
+// CompletedTasks returns the number of tasks solved with full score
+func (p *ScoreboardUserProgress) CompletedTasks() int {
+	completed := 0
+	for _, s := range p.Submissions {
+		if s.Completed() {
+			completed++
+		}
+	}
+	return completed
 }
 
 type WorkshopStats struct {
@@ -34,6 +103,7 @@ type WorkshopStats struct {
 
 type Leaderboard struct {
 	Username       string    `json:"username"`
+	Points         float64   `json:"points"`
 	CompletedTasks int       `json:"completedTasks"`
 	LastSubmission time.Time `json:"lastSubmission"`
 	LatestRepoName string    `json:"latestRepoName"`
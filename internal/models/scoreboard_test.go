@@ -0,0 +1,31 @@
+package models_test
+
+import (
+	"encoding/json"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"testing"
+)
+
+func TestScoreboardLegacyRecord(t *testing.T) {
+	// Records written before scores existed only contain passed tasks
+	legacy := `{"user":"alice","submissions":[{"workshop":"w1","task":"t1","submission":{"commit_id":"abc"}}]}`
+	var progress models.ScoreboardUserProgress
+	if err := json.Unmarshal([]byte(legacy), &progress); err != nil {
+		t.Fatalf("Failed to decode legacy record: %v", err)
+	}
+	if progress.Points() != 1 || progress.CompletedTasks() != 1 {
+		t.Errorf("Expected a completed task worth one point, got %g points and %d tasks", progress.Points(), progress.CompletedTasks())
+	}
+	if progress.Submissions[0].Submission.CommitID != "abc" {
+		t.Errorf("Expected the submission of the record, got %+v", progress.Submissions[0])
+	}
+
+	scored := `{"user":"alice","submissions":[{"workshop":"w1","task":"t1","score":2,"max_score":5}]}`
+	progress = models.ScoreboardUserProgress{}
+	if err := json.Unmarshal([]byte(scored), &progress); err != nil {
+		t.Fatalf("Failed to decode record: %v", err)
+	}
+	if progress.Points() != 2 || progress.CompletedTasks() != 0 {
+		t.Errorf("Expected 2 points without completion, got %g points and %d tasks", progress.Points(), progress.CompletedTasks())
+	}
+}
@@ -7,4 +7,78 @@ const (
 	StatusPassed Status = "passed"
 	StatusFailed Status = "failed"
 	StatusError  Status = "error"
+
+	// Verdicts of a single test case. All of them count as failed.
+	StatusWrongAnswer         Status = "wrong_answer"
+	StatusPresentationError   Status = "presentation_error"
+	StatusTimeLimitExceeded   Status = "time_limit_exceeded"
+	StatusMemoryLimitExceeded Status = "memory_limit_exceeded"
+	StatusRuntimeError        Status = "runtime_error"
+	StatusCompileError        Status = "compile_error"
+	StatusOutputLimitExceeded Status = "output_limit_exceeded"
 )
+
+// IsFailure reports whether the status is a failed verdict caused by the submission
+// (as opposed to StatusError which is an error of the judge itself).
+func (s Status) IsFailure() bool {
+	switch s {
+	case StatusFailed, StatusWrongAnswer, StatusPresentationError, StatusTimeLimitExceeded,
+		StatusMemoryLimitExceeded, StatusRuntimeError, StatusCompileError, StatusOutputLimitExceeded:
+		return true
+	}
+	return false
+}
+
+// Short returns the common abbreviation of the verdict, e.g. "TLE"
+func (s Status) Short() string {
+	switch s {
+	case StatusPassed:
+		return "AC"
+	case StatusFailed:
+		return "FAIL"
+	case StatusError:
+		return "JE"
+	case StatusWrongAnswer:
+		return "WA"
+	case StatusPresentationError:
+		return "PE"
+	case StatusTimeLimitExceeded:
+		return "TLE"
+	case StatusMemoryLimitExceeded:
+		return "MLE"
+	case StatusRuntimeError:
+		return "RE"
+	case StatusCompileError:
+		return "CE"
+	case StatusOutputLimitExceeded:
+		return "OLE"
+	}
+	return "-"
+}
+
+// Description returns a human-readable name of the verdict
+func (s Status) Description() string {
+	switch s {
+	case StatusPassed:
+		return "Accepted"
+	case StatusFailed:
+		return "Failed"
+	case StatusError:
+		return "Judge Error"
+	case StatusWrongAnswer:
+		return "Wrong Answer"
+	case StatusPresentationError:
+		return "Presentation Error"
+	case StatusTimeLimitExceeded:
+		return "Time Limit Exceeded"
+	case StatusMemoryLimitExceeded:
+		return "Memory Limit Exceeded"
+	case StatusRuntimeError:
+		return "Runtime Error"
+	case StatusCompileError:
+		return "Compile Error"
+	case StatusOutputLimitExceeded:
+		return "Output Limit Exceeded"
+	}
+	return "None"
+}
@@ -3,41 +3,143 @@ package models
 import (
 	"github.com/gurkengewuerz/GitCodeJudge/internal/gitea"
 	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"strings"
 	"time"
 )
 
+// MemoryUnknown is the peak memory of a program whose memory could not be measured on its own
+const MemoryUnknown int64 = -1
+
 type ExecutionResult struct {
-	Output        string
-	Error         string
-	ExitCode      int64
-	ExecutionTime time.Duration
+	Output         string // Standard output, the only stream compared with the expected output
+	Stderr         string
+	ExceededStream string // Stream which exceeded its size limit, e.g. stdout
+	Error          string
+	ExitCode       int64
+	Signal         int64
+	TimedOut       bool
+	OOMKilled      bool
+	CompileError   bool
+	ExecutionTime  time.Duration     // Wall time of the program
+	CPUTime        time.Duration     // User and system time of the program and its children
+	MemoryPeak     int64             // Peak memory of the program in bytes, MemoryUnknown if it could not be measured
+	Files          map[string]string // Expected output files written by the program, by name
 }
 
 type TestCaseResult struct {
 	TestNumber    int
+	Name          string // Name of the test function of a unit test task
 	Solution      Solution
 	Status        status.Status
 	Error         string
 	ExecutionTime time.Duration
+	CPUTime       time.Duration
+	MemoryPeak    int64 // Peak memory in bytes, MemoryUnknown if it could not be measured
 	IsHidden      bool
+	Points        float64
+	Subtask       string
+	SubtaskPoints float64
+	Stderr        string     // Excerpt of the error output, only kept for public cases
+	Transcript    string     // Excerpt of the conversation with the interactor, only kept for public cases
+	ResultSet     *ResultSet // First rows returned by the query of a failed public SQL case
+	Language      string
+	Image         string // Image the case ran in
+	ImageDigest   string // Digest of the image, identifies the exact environment
+}
+
+// ResultSet is an excerpt of the rows returned by a query
+type ResultSet struct {
+	Columns []string
+	Rows    [][]string
+	Total   int // Number of rows returned, Rows may be shorter
 }
 
 type Solution struct {
-	Workshop string
-	Task     string
+	Workshop string `json:"workshop"`
+	Task     string `json:"task"`
 }
 
 type Submission struct {
-	RepoName   string
-	CommitID   string
-	BranchName string
-	CloneURL   string
-	Solutions  []Solution
-	GitClient  *gitea.GiteaClient
+	RepoName       string             `json:"repo_name"`
+	CommitID       string             `json:"commit_id"`
+	BeforeCommitID string             `json:"before_commit_id"` // Head of the branch before the push, all changes since are judged
+	BranchName     string             `json:"branch_name"`
+	CloneURL       string             `json:"clone_url"`
+	ChangedFiles   []string           `json:"changed_files"`   // Files changed by the pushed commits according to the webhook
+	CommitMessages []string           `json:"commit_messages"` // Messages of the pushed commits according to the webhook
+	Solutions      []Solution         `json:"solutions"`       // Tasks to judge. If empty, the tasks changed by the push are judged
+	Rejudge        bool               `json:"rejudge"`         // Submission was judged before and is judged again
+	GitClient      *gitea.GiteaClient `json:"-"`
+}
+
+// User returns the user a submission belongs to. Repositories are named after their owner, so this is the
+// repository part of the full repository name.
+func (s Submission) User() string {
+	if _, repo, found := strings.Cut(s.RepoName, "/"); found {
+		return repo
+	}
+	return s.RepoName
 }
 
 type TestResult struct {
-	Status    status.Status
-	TestCases []TestCaseResult
-	Markdown  string
+	Status     status.Status
+	TestCases  []TestCaseResult
+	Scores     []TaskScore
+	Skipped    bool   // Judging was skipped by the commit message
+	Directives string // Directives of the commit message which were applied
+	Markdown   string
+}
+
+// TaskScore is the score a submission reached for a single workshop/task
+type TaskScore struct {
+	Solution Solution
+	Score    float64
+	MaxScore float64
+}
+
+// ComputeScores sums up the points of the test case results per workshop/task.
+// Cases of a subtask only score the points of the subtask if all of them passed.
+func ComputeScores(testCases []TestCaseResult) []TaskScore {
+	scores := make([]TaskScore, 0)
+	index := make(map[Solution]int)
+	subtasks := make(map[Solution]map[string]bool)
+
+	for _, tc := range testCases {
+		i, exists := index[tc.Solution]
+		if !exists {
+			i = len(scores)
+			index[tc.Solution] = i
+			scores = append(scores, TaskScore{Solution: tc.Solution})
+			subtasks[tc.Solution] = make(map[string]bool)
+		}
+
+		passed := tc.Status == status.StatusPassed
+		if tc.Subtask == "" {
+			scores[i].MaxScore += tc.Points
+			if passed {
+				scores[i].Score += tc.Points
+			}
+			continue
+		}
+
+		allPassed, seen := subtasks[tc.Solution][tc.Subtask]
+		if !seen {
+			scores[i].MaxScore += tc.SubtaskPoints
+			allPassed = true
+		}
+		subtasks[tc.Solution][tc.Subtask] = allPassed && passed
+	}
+
+	// Award the subtasks after all of their cases are known
+	for _, tc := range testCases {
+		if tc.Subtask == "" {
+			continue
+		}
+		if subtasks[tc.Solution][tc.Subtask] {
+			scores[index[tc.Solution]].Score += tc.SubtaskPoints
+			delete(subtasks[tc.Solution], tc.Subtask)
+		}
+	}
+
+	return scores
 }
@@ -0,0 +1,39 @@
+package models_test
+
+import (
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models"
+	"github.com/gurkengewuerz/GitCodeJudge/internal/models/status"
+	"testing"
+)
+
+func TestComputeScores(t *testing.T) {
+	solution := models.Solution{Workshop: "workshop", Task: "task"}
+	other := models.Solution{Workshop: "workshop", Task: "other"}
+	results := []models.TestCaseResult{
+		{Solution: solution, Status: status.StatusPassed, Points: 10},
+		// small is worth 30 points, one of its cases fails
+		{Solution: solution, Status: status.StatusWrongAnswer, Subtask: "small", SubtaskPoints: 30},
+		{Solution: solution, Status: status.StatusPassed, Subtask: "small", SubtaskPoints: 30},
+		{Solution: solution, Status: status.StatusPassed, Subtask: "large", SubtaskPoints: 2},
+		{Solution: solution, Status: status.StatusPassed, Subtask: "large", SubtaskPoints: 2},
+		{Solution: solution, Status: status.StatusPassed, Points: 1},
+		{Solution: other, Status: status.StatusPassed, Points: 0},
+	}
+
+	scores := models.ComputeScores(results)
+	if len(scores) != 2 {
+		t.Fatalf("Expected 2 task scores, got %d", len(scores))
+	}
+
+	// 10 (case) + 30 (small) + 2 (large) + 1 (case)
+	if scores[0].MaxScore != 43 {
+		t.Errorf("Expected max score 43, got %g", scores[0].MaxScore)
+	}
+	// small failed because one of its cases failed
+	if scores[0].Score != 13 {
+		t.Errorf("Expected score 13, got %g", scores[0].Score)
+	}
+	if scores[1].Solution != other || scores[1].Score != 0 || scores[1].MaxScore != 0 {
+		t.Errorf("Expected a task without points, got %+v", scores[1])
+	}
+}
@@ -6,21 +6,236 @@ type TestCase struct {
 	Input         string
 	Expected      string
 	IsHidden      bool
+	Limits        Limits
+	Checker       CheckerConfig
+	Security      Security // Overrides of the server defaults
+	Languages     []string
+	Isolated      bool // Every case runs in its own container
+	Points        float64
+	Subtask       string
+	SubtaskPoints float64
+	TaskDir       string
 	RepositoryDir string
 	Solution      *Solution
+	Args          []string          // Command-line arguments of the program
+	Env           map[string]string // Environment variables of the program
+	Files         []string          // Absolute paths of the fixtures copied into the working directory
+	ExpectedFiles []ExpectedFile    // Files the program must write, their expected content is resolved
+	Unit          *UnitConfig       // Tests of a unit test task, the paths of their files are resolved
+	Interactor    string            // Absolute path of the interactor of an interactive task
+	SQL           *SQLConfig        // Comparison of the result set of an SQL task, the input holds the dataset
+}
+
+// HasFiles reports if the program of the case needs a working directory of its own
+func (tc TestCase) HasFiles() bool {
+	return len(tc.Files) > 0 || len(tc.ExpectedFiles) > 0
 }
 
 type Case struct {
-	Input    string `yaml:"input"`
-	Expected string `yaml:"expected"`
+	Input    string   `yaml:"input"`
+	Expected string   `yaml:"expected"`
+	Limits   *Limits  `yaml:"limits"`
+	Points   *float64 `yaml:"points"`  // Points for passing this case, defaults to 1. Ignored for cases in a subtask
+	Subtask  string   `yaml:"subtask"` // Name of the subtask the case belongs to
+
+	Args          []string          `yaml:"args"`           // Command-line arguments of the program
+	Env           map[string]string `yaml:"env"`            // Environment variables of the program
+	Files         []string          `yaml:"files"`          // Fixtures relative to the task directory, copied into the working directory
+	ExpectedFiles []ExpectedFile    `yaml:"expected_files"` // Files the program must write to its working directory
+	Dataset       string            `yaml:"dataset"`        // SQL tasks: dataset relative to the task directory, overrides the dataset of the task
+}
+
+// ExpectedFile is a file a program must write to its working directory. It is compared with the checker of the task.
+type ExpectedFile struct {
+	Name     string `yaml:"name"`     // Name of the file in the working directory
+	Expected string `yaml:"expected"` // Expected content
+	File     string `yaml:"file"`     // Expected content read from a file relative to the task directory instead
+}
+
+// TestFile configures the scoring of a case stored as files in the tests directory of a task
+type TestFile struct {
+	Name    string   `yaml:"name"`    // Name of the case, e.g. 01 for 01.in and 01.out
+	Points  *float64 `yaml:"points"`  // Points for passing this case, defaults to 1. Ignored for cases in a subtask
+	Subtask string   `yaml:"subtask"` // Name of the subtask the case belongs to
+}
+
+// Subtask groups cases. The points of a subtask are only awarded if all of its cases pass.
+type Subtask struct {
+	Name   string   `yaml:"name"`
+	Points *float64 `yaml:"points"` // Defaults to one point per case
+}
+
+// Limits describes the resources a solution may use while being judged.
+// Zero values mean "not set" and fall back to the next less specific level
+// (case -> task -> executor defaults).
+type Limits struct {
+	Memory   int64   `yaml:"memory" json:"memory"`       // Memory limit in MB
+	CPUs     float64 `yaml:"cpus" json:"cpus"`           // Number of CPUs, e.g. 0.5
+	Pids     int64   `yaml:"pids" json:"pids"`           // Maximum number of processes
+	WallTime float64 `yaml:"wall_time" json:"wall_time"` // Wall time limit in seconds
+	CPUTime  float64 `yaml:"cpu_time" json:"cpu_time"`   // CPU time limit in seconds
+	Output   int64   `yaml:"output" json:"output"`       // Maximum size of stdout in KB
+	Stderr   int64   `yaml:"stderr" json:"stderr"`       // Maximum size of stderr in KB
+}
+
+// Merge returns a copy of l where every field that is set in override replaces the value in l.
+func (l Limits) Merge(override *Limits) Limits {
+	if override == nil {
+		return l
+	}
+	if override.Memory > 0 {
+		l.Memory = override.Memory
+	}
+	if override.CPUs > 0 {
+		l.CPUs = override.CPUs
+	}
+	if override.Pids > 0 {
+		l.Pids = override.Pids
+	}
+	if override.WallTime > 0 {
+		l.WallTime = override.WallTime
+	}
+	if override.CPUTime > 0 {
+		l.CPUTime = override.CPUTime
+	}
+	if override.Output > 0 {
+		l.Output = override.Output
+	}
+	if override.Stderr > 0 {
+		l.Stderr = override.Stderr
+	}
+	return l
+}
+
+// WallTimeDuration returns the wall time limit as time.Duration.
+func (l Limits) WallTimeDuration() time.Duration {
+	return time.Duration(l.WallTime * float64(time.Second))
+}
+
+// Security describes how judge containers are hardened. Unset fields fall back to the server defaults.
+type Security struct {
+	User            string   `yaml:"user" json:"user"`                           // User programs run as, e.g. 65534:65534
+	ReadOnlyRoot    *bool    `yaml:"read_only_root" json:"read_only_root"`       // Mount the root filesystem read-only
+	ReadOnlyRepo    *bool    `yaml:"read_only_repo" json:"read_only_repo"`       // Mount the repository read-only
+	NoNewPrivileges *bool    `yaml:"no_new_privileges" json:"no_new_privileges"` // Forbid gaining privileges, e.g. through setuid binaries
+	CapDrop         []string `yaml:"cap_drop" json:"cap_drop"`                   // Capabilities to drop, e.g. ALL
+	CapAdd          []string `yaml:"cap_add" json:"cap_add"`                     // Capabilities to add after dropping
+	Seccomp         string   `yaml:"seccomp" json:"seccomp"`                     // default, unconfined or the path of a profile
+	TmpfsSize       int64    `yaml:"tmpfs_size" json:"tmpfs_size"`               // Size of the writable /tmp in MB
+}
+
+// Merge returns a copy of s where every field that is set in override replaces the value in s.
+func (s Security) Merge(override *Security) Security {
+	if override == nil {
+		return s
+	}
+	if override.User != "" {
+		s.User = override.User
+	}
+	if override.ReadOnlyRoot != nil {
+		s.ReadOnlyRoot = override.ReadOnlyRoot
+	}
+	if override.ReadOnlyRepo != nil {
+		s.ReadOnlyRepo = override.ReadOnlyRepo
+	}
+	if override.NoNewPrivileges != nil {
+		s.NoNewPrivileges = override.NoNewPrivileges
+	}
+	if override.CapDrop != nil {
+		s.CapDrop = override.CapDrop
+	}
+	if override.CapAdd != nil {
+		s.CapAdd = override.CapAdd
+	}
+	if override.Seccomp != "" {
+		s.Seccomp = override.Seccomp
+	}
+	if override.TmpfsSize > 0 {
+		s.TmpfsSize = override.TmpfsSize
+	}
+	return s
+}
+
+// Enabled returns the value of an optional flag, unset flags are disabled
+func Enabled(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+// CheckerConfig selects how the output of a solution is compared to the expected output
+type CheckerConfig struct {
+	Type       string  `yaml:"type"`    // exact (default), tokens, float, case_insensitive, unordered, regex or custom
+	AbsEpsilon float64 `yaml:"abs_eps"` // float: allowed absolute difference
+	RelEpsilon float64 `yaml:"rel_eps"` // float: allowed relative difference
+	Pattern    string  `yaml:"pattern"` // regex: pattern the output must match. The expected output is used if empty
+	Program    string  `yaml:"program"` // custom: checker program relative to the task directory
+}
+
+const (
+	// TaskTypeIO compares the output of the solution for every case
+	TaskTypeIO = "io"
+	// TaskTypeUnit runs instructor tests against the solution
+	TaskTypeUnit = "unit"
+	// TaskTypeInteractive connects the solution to an interactor which decides the verdict
+	TaskTypeInteractive = "interactive"
+	// TaskTypeSQL runs the query of the solution against a dataset and compares the result set
+	TaskTypeSQL = "sql"
+)
+
+// Frameworks of unit test tasks
+const (
+	UnitFrameworkPytest = "pytest"
+	UnitFrameworkGo     = "go"
+	UnitFrameworkJUnit  = "junit" // Any framework whose command prints a JUnit XML report
+)
+
+// UnitConfig describes the instructor tests of a unit test task
+type UnitConfig struct {
+	Framework string     `yaml:"framework"` // pytest, go or junit
+	Files     []string   `yaml:"files"`     // Test files relative to the task directory, copied next to the solution
+	Command   string     `yaml:"command"`   // Writes the report to $UNIT_REPORT, overrides the command of the framework
+	Tests     []UnitTest `yaml:"tests"`     // Points, subtasks and visibility of single tests
+}
+
+// UnitTest configures a single test function. Tests which are not configured are visible and worth one point.
+type UnitTest struct {
+	Name          string   `yaml:"name"`   // Name of the test function, parameters of a test are matched as well
+	Points        *float64 `yaml:"points"` // Points for passing this test, defaults to 1. Ignored for tests in a subtask
+	Subtask       string   `yaml:"subtask"`
+	Hidden        bool     `yaml:"hidden"`
+	SubtaskPoints float64  `yaml:"-"` // Points of the subtask, resolved by the loader
+}
+
+// SQLConfig describes the datasets of an SQL task and how its result sets are compared
+type SQLConfig struct {
+	Dataset           string `yaml:"dataset"`             // SQL file relative to the task directory which creates the tables of every case
+	IgnoreOrder       bool   `yaml:"ignore_order"`        // Rows may be returned in any order
+	IgnoreColumnNames bool   `yaml:"ignore_column_names"` // Only the number of columns must match
 }
 
+const (
+	// IsolationSession compiles the solution and runs all cases of a task in one container
+	IsolationSession = "session"
+	// IsolationCase runs every case in a fresh container
+	IsolationCase = "case"
+)
+
 type TestCaseConfig struct {
-	Name        string     `yaml:"name"`
-	Description string     `yaml:"description"`
-	Cases       []Case     `yaml:"cases"`
-	HiddenCases []Case     `yaml:"hidden_cases"`
-	Disabled    bool       `default:"false" yaml:"disabled"`
-	StartDate   *time.Time `yaml:"start_date"`
-	EndDate     *time.Time `yaml:"end_date"`
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Limits      *Limits        `yaml:"limits"`
+	Checker     *CheckerConfig `yaml:"checker"`
+	Security    *Security      `yaml:"security"`
+	Languages   []string       `yaml:"languages"`  // Accepted languages, all if empty
+	Isolation   string         `yaml:"isolation"`  // session (default) or case
+	Type        string         `yaml:"type"`       // io (default), unit, interactive or sql
+	Unit        *UnitConfig    `yaml:"unit"`       // Tests of a unit test task
+	Interactor  string         `yaml:"interactor"` // Interactor of an interactive task relative to the task directory
+	SQL         *SQLConfig     `yaml:"sql"`        // Datasets and comparison of an SQL task
+	Subtasks    []Subtask      `yaml:"subtasks"`
+	Cases       []Case         `yaml:"cases"`
+	HiddenCases []Case         `yaml:"hidden_cases"`
+	TestFiles   []TestFile     `yaml:"test_files"` // Scoring of the cases stored in the tests directory
+	Disabled    bool           `default:"false" yaml:"disabled"`
+	StartDate   *time.Time     `yaml:"start_date"`
+	EndDate     *time.Time     `yaml:"end_date"`
 }
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// WorkerHeader is the header remote workers send their name in
+const WorkerHeader = "X-Worker-Name"
+
+// WorkerClaim is sent by a remote worker asking for a job
+type WorkerClaim struct {
+	Capacity int `json:"capacity"` // Number of jobs the worker processes in parallel
+}
+
+// WorkerAssignment is a job handed to a remote worker
+type WorkerAssignment struct {
+	Job               Job `json:"job"`
+	HeartbeatInterval int `json:"heartbeat_interval"` // Seconds between the heartbeats the server expects
+}
+
+// WorkerResult is the outcome of a job reported by a remote worker. Error is set if the job could not be judged.
+type WorkerResult struct {
+	Result *TestResult `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// WorkerStatus describes an in-process or remote worker of the judge pool
+type WorkerStatus struct {
+	Name      string    `json:"name"`
+	Remote    bool      `json:"remote"`
+	Address   string    `json:"address,omitempty"`
+	Capacity  int       `json:"capacity"`
+	Jobs      []string  `json:"jobs"` // IDs of the running jobs
+	Completed int       `json:"completed"`
+	LastSeen  time.Time `json:"last_seen"`
+	Healthy   bool      `json:"healthy"`
+}